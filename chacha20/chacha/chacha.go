@@ -30,3 +30,54 @@ func (c *Cipher) SetCounter(ctr uint32) {
 	c.state[51] = byte(ctr >> 24)
 	c.off = 0
 }
+
+// HChaCha generates a 32 byte subkey from the key and the 16 byte nonce
+// performing 'rounds' rounds (e.g. HChaCha20 or HChaCha12). HChaCha is used
+// to derive the key of XChaCha, which extends the nonce of ChaCha to 24 byte.
+// The rounds argument must be a positive multiple of 2.
+func HChaCha(out *[32]byte, nonce *[16]byte, key *[32]byte, rounds int) {
+	if rounds <= 0 || rounds%2 != 0 {
+		panic("chacha20/chacha: rounds must be a multiple of 2")
+	}
+	var v [16]uint32
+	for i := 0; i < 4; i++ {
+		v[i] = uint32(constants[4*i]) | uint32(constants[4*i+1])<<8 | uint32(constants[4*i+2])<<16 | uint32(constants[4*i+3])<<24
+		v[12+i] = uint32(nonce[4*i]) | uint32(nonce[4*i+1])<<8 | uint32(nonce[4*i+2])<<16 | uint32(nonce[4*i+3])<<24
+	}
+	for i := 0; i < 8; i++ {
+		v[4+i] = uint32(key[4*i]) | uint32(key[4*i+1])<<8 | uint32(key[4*i+2])<<16 | uint32(key[4*i+3])<<24
+	}
+
+	for i := 0; i < rounds; i += 2 {
+		quarterRound(&v, 0, 4, 8, 12)
+		quarterRound(&v, 1, 5, 9, 13)
+		quarterRound(&v, 2, 6, 10, 14)
+		quarterRound(&v, 3, 7, 11, 15)
+		quarterRound(&v, 0, 5, 10, 15)
+		quarterRound(&v, 1, 6, 11, 12)
+		quarterRound(&v, 2, 7, 8, 13)
+		quarterRound(&v, 3, 4, 9, 14)
+	}
+
+	for i, w := range [8]uint32{v[0], v[1], v[2], v[3], v[12], v[13], v[14], v[15]} {
+		out[4*i] = byte(w)
+		out[4*i+1] = byte(w >> 8)
+		out[4*i+2] = byte(w >> 16)
+		out[4*i+3] = byte(w >> 24)
+	}
+}
+
+func quarterRound(v *[16]uint32, a, b, c, d int) {
+	v[a] += v[b]
+	v[d] ^= v[a]
+	v[d] = v[d]<<16 | v[d]>>16
+	v[c] += v[d]
+	v[b] ^= v[c]
+	v[b] = v[b]<<12 | v[b]>>20
+	v[a] += v[b]
+	v[d] ^= v[a]
+	v[d] = v[d]<<8 | v[d]>>24
+	v[c] += v[d]
+	v[b] ^= v[c]
+	v[b] = v[b]<<7 | v[b]>>25
+}
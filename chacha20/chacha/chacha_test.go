@@ -5,6 +5,7 @@ package chacha
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/hex"
 	"testing"
 )
@@ -98,3 +99,54 @@ func TestXORKeyStreamPanic(t *testing.T) {
 	mustFail2(t, "len(dst) < len(src)", dst[:len(src)-1], src)
 
 }
+
+func TestHChaCha20(t *testing.T) {
+	// Test vector from draft-irtf-cfrg-xchacha-03 Section 2.2.1
+	var key [32]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	var nonce [16]byte
+	n, _ := hex.DecodeString("000000090000004a0000000031415927")
+	copy(nonce[:], n)
+	expected, _ := hex.DecodeString("82413b4227b27bfed30e42508a877d73a0f9e4d58a74a853c12ec41326d3ecdc")
+
+	var out [32]byte
+	HChaCha(&out, &nonce, &key, 20)
+	if !bytes.Equal(out[:], expected) {
+		t.Fatalf("HChaCha20 failed:\nFound   : %s\nExpected: %s", hex.EncodeToString(out[:]), hex.EncodeToString(expected))
+	}
+}
+
+func TestHChaCha(t *testing.T) {
+	var key [32]byte
+	var nonce [16]byte
+	for i := range key {
+		key[i] = byte(i * 7)
+	}
+	for i := range nonce {
+		nonce[i] = byte(255 - i)
+	}
+
+	// HChaCha is the ChaCha core without the final addition
+	// of the input state - the output contains the words 0-3
+	// and 12-15 of the permuted state.
+	for _, rounds := range []int{8, 12, 20} {
+		var state, block [64]byte
+		copy(state[:], constants[:])
+		copy(state[16:], key[:])
+		copy(state[48:], nonce[:])
+		Core(&block, &state, rounds) // increments the counter - the nonce is used below
+
+		var expected, out [32]byte
+		for i := 0; i < 16; i += 4 {
+			binary.LittleEndian.PutUint32(expected[i:], binary.LittleEndian.Uint32(block[i:])-binary.LittleEndian.Uint32(state[i:]))
+			binary.LittleEndian.PutUint32(expected[16+i:], binary.LittleEndian.Uint32(block[48+i:])-binary.LittleEndian.Uint32(nonce[i:]))
+		}
+
+		HChaCha(&out, &nonce, &key, rounds)
+		if out != expected {
+			t.Fatalf("HChaCha%d failed:\nFound   : %s\nExpected: %s", rounds, hex.EncodeToString(out[:]), hex.EncodeToString(expected[:]))
+		}
+	}
+}
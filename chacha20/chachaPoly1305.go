@@ -51,9 +51,6 @@ func (c *aead) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
 	if n := len(nonce); n != NonceSize {
 		panic(crypto.NonceSizeError(n))
 	}
-	if len(dst) < len(plaintext)+c.tagsize {
-		panic("dst buffer to small")
-	}
 	var Nonce [12]byte
 	copy(Nonce[:], nonce)
 
@@ -63,12 +60,14 @@ func (c *aead) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
 
 	// encrypt the plaintext
 	n := len(plaintext)
-	chacha.XORKeyStream(dst, plaintext, &Nonce, &(c.key), 1, 20)
+	ret, out := sliceForAppend(dst, n+c.tagsize)
+	chacha.XORKeyStream(out, plaintext, &Nonce, &(c.key), 1, 20)
 
 	// authenticate the ciphertext
 	var tag [poly1305.TagSize]byte
-	authenticate(&tag, dst[:n], additionalData, &polyKey)
-	return append(dst[:n], tag[:c.tagsize]...)
+	authenticate(&tag, out[:n], additionalData, &polyKey)
+	copy(out[n:], tag[:c.tagsize])
+	return ret
 }
 
 func (c *aead) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
@@ -78,9 +77,6 @@ func (c *aead) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, erro
 	if len(ciphertext) < c.tagsize {
 		return nil, crypto.AuthenticationError{}
 	}
-	if len(dst) < len(ciphertext)-c.tagsize {
-		panic("dst buffer to small")
-	}
 	var Nonce [12]byte
 
 	copy(Nonce[:], nonce)
@@ -100,8 +96,24 @@ func (c *aead) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, erro
 	}
 
 	// decrypt ciphertext
-	chacha.XORKeyStream(dst, ciphertext, &Nonce, &(c.key), 1, 20)
-	return dst[:len(ciphertext)], nil
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	chacha.XORKeyStream(out, ciphertext, &Nonce, &(c.key), 1, 20)
+	return ret, nil
+}
+
+// sliceForAppend takes a slice and a requested number of bytes. It returns a
+// slice with the contents of the given slice followed by that many bytes and a
+// second slice that aliases into it and contains only the extra bytes. If the
+// original slice has sufficient capacity then no allocation is performed.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
 }
 
 // authenticate calculates the poly1305 tag from
@@ -3,7 +3,10 @@
 
 package chacha20
 
-import "testing"
+import (
+	"bytes"
+	"testing"
+)
 
 var recFunc = func(t *testing.T, msg string) {
 	if recover() == nil {
@@ -65,8 +68,6 @@ func TestSeal(t *testing.T) {
 	}
 
 	mustFail("nonce size is invalid", dst[:], nonce[:NonceSize-1], src[:])
-
-	mustFail("dst length invalid", dst[:len(dst)-2], nonce[:], src[:])
 }
 
 func TestOpen(t *testing.T) {
@@ -89,15 +90,8 @@ func TestOpen(t *testing.T) {
 		t.Fatal("Open() accepted invalid ciphertext length")
 	}
 
-	mustFail := func(msg string, dst, nonce, src []byte) {
-		defer recFunc(t, msg)
-		c.Open(dst, nonce, src, nil)
-	}
-
-	mustFail("dst length invalid", dst[:len(src)-TagSize-1], nonce[:], src[:])
-
 	// Check tag verification
-	c.Seal(dst[:], nonce[:], src[:], nil)
+	c.Seal(dst[:0], nonce[:], src[:], nil)
 	dst[len(src)+1] += 1 // modify tag
 
 	_, err = c.Open(src[:], nonce[:], dst[:], nil)
@@ -106,6 +100,59 @@ func TestOpen(t *testing.T) {
 	}
 }
 
+func TestAliasing(t *testing.T) {
+	var key [32]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	c := NewChaCha20Poly1305(&key)
+
+	nonce, msg, data := make([]byte, NonceSize), make([]byte, 100), make([]byte, 20)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	ref := c.Seal(nil, nonce, msg, data)
+
+	// Seal must append to dst - e.g. prepend the nonce
+	out := c.Seal(nonce, nonce, msg, data)
+	if !bytes.Equal(out[:NonceSize], nonce) || !bytes.Equal(out[NonceSize:], ref) {
+		t.Fatal("Seal does not append the ciphertext to dst")
+	}
+
+	// exact overlap of dst and src
+	buf := make([]byte, len(msg), len(msg)+TagSize)
+	copy(buf, msg)
+	ciphertext := c.Seal(buf[:0], nonce, buf, data)
+	if !bytes.Equal(ciphertext, ref) {
+		t.Fatal("in-place Seal produced wrong ciphertext")
+	}
+	if &ciphertext[0] != &buf[0] {
+		t.Fatal("in-place Seal allocated a new slice")
+	}
+	plaintext, err := c.Open(ciphertext[:0], nonce, ciphertext, data)
+	if err != nil {
+		t.Fatalf("in-place Open failed: %s", err)
+	}
+	if !bytes.Equal(plaintext, msg) {
+		t.Fatal("in-place Open produced wrong plaintext")
+	}
+
+	// reuse of dst[:0]
+	dst := make([]byte, 0, len(ref))
+	for i := 0; i < 3; i++ {
+		dst = c.Seal(dst[:0], nonce, msg, data)
+		if !bytes.Equal(dst, ref) {
+			t.Fatalf("Seal with dst[:0] failed in iteration %d", i)
+		}
+		if dst, err = c.Open(dst[:0], nonce, dst, data); err != nil {
+			t.Fatalf("Open with dst[:0] failed in iteration %d: %s", i, err)
+		}
+		if !bytes.Equal(dst, msg) {
+			t.Fatalf("Open with dst[:0] failed in iteration %d", i)
+		}
+	}
+}
+
 func BenchmarkSeal64B(b *testing.B) {
 	var key [32]byte
 	var nonce [12]byte
@@ -117,7 +164,7 @@ func BenchmarkSeal64B(b *testing.B) {
 
 	b.SetBytes(int64(len(msg)))
 	for i := 0; i < b.N; i++ {
-		dst = c.Seal(dst, nonce[:], msg, data)
+		dst = c.Seal(dst[:0], nonce[:], msg, data)
 	}
 }
 
@@ -132,7 +179,7 @@ func BenchmarkSeal1K(b *testing.B) {
 
 	b.SetBytes(int64(len(msg)))
 	for i := 0; i < b.N; i++ {
-		dst = c.Seal(dst, nonce[:], msg, data)
+		dst = c.Seal(dst[:0], nonce[:], msg, data)
 	}
 }
 
@@ -147,7 +194,7 @@ func BenchmarkSeal64K(b *testing.B) {
 
 	b.SetBytes(int64(len(msg)))
 	for i := 0; i < b.N; i++ {
-		dst = c.Seal(dst, nonce[:], msg, data)
+		dst = c.Seal(dst[:0], nonce[:], msg, data)
 	}
 }
 
@@ -160,11 +207,11 @@ func BenchmarkOpen64B(b *testing.B) {
 	dst := make([]byte, len(msg))
 	ciphertext := make([]byte, len(msg)+TagSize)
 	data := make([]byte, 32)
-	ciphertext = c.Seal(ciphertext, nonce[:], msg, data)
+	ciphertext = c.Seal(ciphertext[:0], nonce[:], msg, data)
 
 	b.SetBytes(int64(len(msg)))
 	for i := 0; i < b.N; i++ {
-		dst, _ = c.Open(dst, nonce[:], ciphertext, data)
+		dst, _ = c.Open(dst[:0], nonce[:], ciphertext, data)
 	}
 }
 
@@ -177,11 +224,11 @@ func BenchmarkOpen1K(b *testing.B) {
 	dst := make([]byte, len(msg))
 	ciphertext := make([]byte, len(msg)+TagSize)
 	data := make([]byte, 32)
-	ciphertext = c.Seal(ciphertext, nonce[:], msg, data)
+	ciphertext = c.Seal(ciphertext[:0], nonce[:], msg, data)
 
 	b.SetBytes(int64(len(msg)))
 	for i := 0; i < b.N; i++ {
-		dst, _ = c.Open(dst, nonce[:], ciphertext, data)
+		dst, _ = c.Open(dst[:0], nonce[:], ciphertext, data)
 	}
 }
 
@@ -194,10 +241,10 @@ func BenchmarkOpen64K(b *testing.B) {
 	dst := make([]byte, len(msg))
 	ciphertext := make([]byte, len(msg)+TagSize)
 	data := make([]byte, 32)
-	ciphertext = c.Seal(ciphertext, nonce[:], msg, data)
+	ciphertext = c.Seal(ciphertext[:0], nonce[:], msg, data)
 
 	b.SetBytes(int64(len(msg)))
 	for i := 0; i < b.N; i++ {
-		dst, _ = c.Open(dst, nonce[:], ciphertext, data)
+		dst, _ = c.Open(dst[:0], nonce[:], ciphertext, data)
 	}
 }
@@ -207,14 +207,13 @@ func TestAEADVectors(t *testing.T) {
 			t.Fatalf("Test vector %d: Failed to create AEAD instance: %s", i, err)
 		}
 
-		buf := make([]byte, len(ciphertext))
-		c.Seal(buf, nonce, msg, data)
+		buf := c.Seal(nil, nonce, msg, data)
 
 		if !bytes.Equal(buf, ciphertext) {
 			t.Fatalf("TestVector %d Seal failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(ciphertext))
 		}
 
-		buf, err = c.Open(buf, nonce, buf, data)
+		buf, err = c.Open(buf[:0], nonce, buf, data)
 
 		if err != nil {
 			t.Fatalf("TestVector %d: Open failed - Cause: %s", i, err)
@@ -0,0 +1,272 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/chacha20/chacha"
+	"github.com/enceve/crypto/poly1305"
+)
+
+const (
+	// The size of the Adiantum key in bytes.
+	AdiantumKeySize = 32
+	// The size of the Adiantum tweak in bytes.
+	AdiantumTweakSize = 32
+	// AdiantumBlockSize is the block size of Adiantum in bytes.
+	// Every message must be at least AdiantumBlockSize bytes long.
+	AdiantumBlockSize = 16
+
+	adiantumRounds      = 12   // XChaCha12
+	adiantumNHKeySize   = 1072 // 1024 byte message unit + 3 * 16 byte for the 4 passes
+	adiantumNHUnitSize  = 1024
+	adiantumNHBlockSize = 16
+)
+
+// Adiantum is a tweakable, length-preserving wide-block cipher designed for
+// disk encryption on devices without AES instructions. Every bit of the
+// ciphertext depends on every bit of the plaintext and the tweak.
+type Adiantum interface {
+	// Encrypt encrypts the message in src using the tweak and writes the
+	// ciphertext into dst. The length of src must be at least
+	// AdiantumBlockSize bytes and dst must be at least as large as src.
+	// The tweak must be AdiantumTweakSize bytes long. Dst and src may
+	// overlap entirely or not at all.
+	Encrypt(dst, src, tweak []byte)
+
+	// Decrypt decrypts the message in src using the tweak and writes the
+	// plaintext into dst. The length of src must be at least
+	// AdiantumBlockSize bytes and dst must be at least as large as src.
+	// The tweak must be AdiantumTweakSize bytes long. Dst and src may
+	// overlap entirely or not at all.
+	Decrypt(dst, src, tweak []byte)
+
+	// EncryptSector encrypts the sector in src like Encrypt using the
+	// sector number - encoded as 64 bit little endian integer and zero
+	// padded to AdiantumTweakSize bytes - as tweak.
+	EncryptSector(dst, src []byte, sector uint64)
+
+	// DecryptSector decrypts the sector in src like Decrypt using the
+	// sector number - encoded as 64 bit little endian integer and zero
+	// padded to AdiantumTweakSize bytes - as tweak.
+	DecryptSector(dst, src []byte, sector uint64)
+}
+
+// The Adiantum cipher
+type adiantum struct {
+	key         [32]byte
+	blockCipher cipher.Block
+	tweakKey    [32]byte // the Poly1305 key of the tweak hash (K_T || 0^128)
+	msgKey      [32]byte // the Poly1305 key of the NH hash (K_M || 0^128)
+	nhKey       [adiantumNHKeySize / 4]uint32
+}
+
+// NewAdiantum returns an Adiantum implementing the Adiantum construction
+// (XChaCha12, NH, Poly1305 and a block cipher) specified in "Adiantum:
+// length-preserving encryption for entry-level processors"
+// (https://eprint.iacr.org/2018/720) and used by the Linux kernel.
+// The newCipher function must return a cipher.Block with a block size of 128
+// bit for a 256 bit key (like aes.NewCipher, camellia.NewCipher or
+// serpent.NewCipher) - Adiantum uses AES-256. The key must be
+// AdiantumKeySize bytes long.
+func NewAdiantum(key []byte, newCipher func(key []byte) (cipher.Block, error)) (Adiantum, error) {
+	if k := len(key); k != AdiantumKeySize {
+		return nil, crypto.KeySizeError(k)
+	}
+	if newCipher == nil {
+		return nil, errors.New("the cipher constructor must not be nil")
+	}
+	a := new(adiantum)
+	copy(a.key[:], key)
+
+	// the subkeys are the XChaCha12 key stream for the nonce 1 || 0^191
+	var nonce [24]byte
+	nonce[0] = 1
+	subkeys := make([]byte, 32+16+16+adiantumNHKeySize)
+	a.xchacha(subkeys, subkeys, &nonce)
+
+	c, err := newCipher(subkeys[:32])
+	if err != nil {
+		return nil, err
+	}
+	if c.BlockSize() != AdiantumBlockSize {
+		return nil, errors.New("cipher block size not supported")
+	}
+	a.blockCipher = c
+	copy(a.tweakKey[:16], subkeys[32:48])
+	copy(a.msgKey[:16], subkeys[48:64])
+	for i := range a.nhKey {
+		a.nhKey[i] = binary.LittleEndian.Uint32(subkeys[64+4*i:])
+	}
+	return a, nil
+}
+
+func (a *adiantum) Encrypt(dst, src, tweak []byte) {
+	checkAdiantumArgs(dst, src, tweak)
+	n := len(src) - AdiantumBlockSize
+
+	// P_M = P_R + H(T, P_L) and C_M = E(P_M)
+	var hash, cm [16]byte
+	a.hash(&hash, tweak, src[:n])
+	add128(&cm, src[n:], &hash)
+	a.blockCipher.Encrypt(cm[:], cm[:])
+
+	// C_L = P_L xor XChaCha12(C_M || 1 || 0^63)
+	var nonce [24]byte
+	copy(nonce[:], cm[:])
+	nonce[16] = 1
+	a.xchacha(dst[:n], src[:n], &nonce)
+
+	// C_R = C_M - H(T, C_L)
+	a.hash(&hash, tweak, dst[:n])
+	sub128(&cm, &hash)
+	copy(dst[n:], cm[:])
+}
+
+func (a *adiantum) Decrypt(dst, src, tweak []byte) {
+	checkAdiantumArgs(dst, src, tweak)
+	n := len(src) - AdiantumBlockSize
+
+	// C_M = C_R + H(T, C_L)
+	var hash, cm, pm [16]byte
+	a.hash(&hash, tweak, src[:n])
+	add128(&cm, src[n:], &hash)
+
+	// P_L = C_L xor XChaCha12(C_M || 1 || 0^63)
+	var nonce [24]byte
+	copy(nonce[:], cm[:])
+	nonce[16] = 1
+	a.xchacha(dst[:n], src[:n], &nonce)
+
+	// P_R = D(C_M) - H(T, P_L)
+	a.blockCipher.Decrypt(pm[:], cm[:])
+	a.hash(&hash, tweak, dst[:n])
+	sub128(&pm, &hash)
+	copy(dst[n:], pm[:])
+}
+
+func (a *adiantum) EncryptSector(dst, src []byte, sector uint64) {
+	var tweak [AdiantumTweakSize]byte
+	binary.LittleEndian.PutUint64(tweak[:], sector)
+	a.Encrypt(dst, src, tweak[:])
+}
+
+func (a *adiantum) DecryptSector(dst, src []byte, sector uint64) {
+	var tweak [AdiantumTweakSize]byte
+	binary.LittleEndian.PutUint64(tweak[:], sector)
+	a.Decrypt(dst, src, tweak[:])
+}
+
+// xchacha encrypts src with XChaCha12 using the 24 byte nonce
+// and writes the ciphertext into dst.
+func (a *adiantum) xchacha(dst, src []byte, nonce *[24]byte) {
+	var hNonce [16]byte
+	var subkey [32]byte
+	copy(hNonce[:], nonce[:16])
+	chacha.HChaCha(&subkey, &hNonce, &a.key, adiantumRounds)
+
+	var cNonce [12]byte
+	copy(cNonce[4:], nonce[16:])
+	chacha.XORKeyStream(dst, src, &cNonce, &subkey, 0, adiantumRounds)
+}
+
+// hash computes the Adiantum hash H(T, M) = Poly1305_KT(bin(|M|) || T) +
+// Poly1305_KM(NH(pad(M))) mod 2^128, where Poly1305 is used without the
+// final addition of the encrypted nonce.
+func (a *adiantum) hash(out *[16]byte, tweak, msg []byte) {
+	var header [16]byte
+	binary.LittleEndian.PutUint64(header[:], uint64(len(msg))*8)
+
+	var tweakHash, msgHash [16]byte
+	p := poly1305.New(&a.tweakKey)
+	p.Write(header[:])
+	p.Write(tweak)
+	p.Sum(&tweakHash)
+
+	var nh [32]byte
+	var unit [adiantumNHUnitSize]byte
+	p = poly1305.New(&a.msgKey)
+	for len(msg) > 0 {
+		k := copy(unit[:], msg)
+		msg = msg[k:]
+		if r := k % adiantumNHBlockSize; r != 0 {
+			for i := k; i < k+adiantumNHBlockSize-r; i++ {
+				unit[i] = 0
+			}
+			k += adiantumNHBlockSize - r
+		}
+		nhSum(&nh, unit[:k], &a.nhKey)
+		p.Write(nh[:])
+	}
+	p.Sum(&msgHash)
+
+	add128(out, tweakHash[:], &msgHash)
+}
+
+// nhSum computes the NH hash (4 passes with a stride of 2) of the msg
+// (at most 1024 byte and a multiple of 16 byte) and writes it to out.
+func nhSum(out *[32]byte, msg []byte, key *[adiantumNHKeySize / 4]uint32) {
+	var sums [4]uint64
+	k := key[:]
+	for len(msg) > 0 {
+		m0 := binary.LittleEndian.Uint32(msg[0:])
+		m1 := binary.LittleEndian.Uint32(msg[4:])
+		m2 := binary.LittleEndian.Uint32(msg[8:])
+		m3 := binary.LittleEndian.Uint32(msg[12:])
+		for i := range sums {
+			sums[i] += uint64(m0+k[4*i]) * uint64(m2+k[4*i+2])
+			sums[i] += uint64(m1+k[4*i+1]) * uint64(m3+k[4*i+3])
+		}
+		k = k[4:]
+		msg = msg[adiantumNHBlockSize:]
+	}
+	for i, s := range sums {
+		binary.LittleEndian.PutUint64(out[8*i:], s)
+	}
+}
+
+// add128 sets out = x + y mod 2^128 - x and y
+// are little endian 128 bit integers.
+func add128(out *[16]byte, x []byte, y *[16]byte) {
+	x0, x1 := binary.LittleEndian.Uint64(x), binary.LittleEndian.Uint64(x[8:])
+	y0, y1 := binary.LittleEndian.Uint64(y[:]), binary.LittleEndian.Uint64(y[8:])
+	z0 := x0 + y0
+	z1 := x1 + y1
+	if z0 < x0 {
+		z1++
+	}
+	binary.LittleEndian.PutUint64(out[:], z0)
+	binary.LittleEndian.PutUint64(out[8:], z1)
+}
+
+// sub128 sets x = x - y mod 2^128 - x and y
+// are little endian 128 bit integers.
+func sub128(x, y *[16]byte) {
+	x0, x1 := binary.LittleEndian.Uint64(x[:]), binary.LittleEndian.Uint64(x[8:])
+	y0, y1 := binary.LittleEndian.Uint64(y[:]), binary.LittleEndian.Uint64(y[8:])
+	z0 := x0 - y0
+	z1 := x1 - y1
+	if x0 < y0 {
+		z1--
+	}
+	binary.LittleEndian.PutUint64(x[:], z0)
+	binary.LittleEndian.PutUint64(x[8:], z1)
+}
+
+// checkAdiantumArgs panics if the length of dst, src or tweak is not valid.
+func checkAdiantumArgs(dst, src, tweak []byte) {
+	if len(tweak) != AdiantumTweakSize {
+		panic("tweak length must be 32 bytes")
+	}
+	if len(src) < AdiantumBlockSize {
+		panic("src must be at least one block long")
+	}
+	if len(dst) < len(src) {
+		panic("dst buffer to small")
+	}
+}
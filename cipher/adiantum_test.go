@@ -0,0 +1,197 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/enceve/crypto/camellia"
+	"github.com/enceve/crypto/serpent"
+)
+
+// Adiantum-XChaCha12-AES test vectors from the Linux kernel
+// (crypto/testmgr.h - adiantum_xchacha12_aes_tv_template)
+var adiantumVectors = []struct {
+	key, tweak, plaintext, ciphertext string
+}{
+	{
+		key:        "9EEBB2493C1CF5F46A99C2C4DFB1F4DD752057EA2C4FCDB2A53D7B491EABFD0F",
+		tweak:      "DF63D4ABD249F3D8338137607DFA7308D8496D80E82F6254EB0EA9395B457F8A",
+		plaintext:  "67C9F23084418E43FBF3B33E79367FE8",
+		ciphertext: "6D32861867860F3F967C9D280D53EC9F",
+	},
+	{
+		key:        "362B5797F85DCD995F1A5A441D920F27CC16D72B856399D3BA96A1DBD26068DA",
+		tweak:      "EF5869B12C5E9A4724C1B169E112938F433D6D00DB5ED8D9129AFED9FF2DAAC4",
+		plaintext:  "5EA8681985981223260ACCDB0A04B9DF4DB3487BB0E3C819435A4606942DF2",
+		ciphertext: "C7C6F1738FC4FF4A39BE78BE8D28C8894663E70C7D87E84EC9187BBE186050",
+	},
+}
+
+func TestAdiantumVectors(t *testing.T) {
+	for i, v := range adiantumVectors {
+		tweak, plaintext, ciphertext := fromHex(v.tweak), fromHex(v.plaintext), fromHex(v.ciphertext)
+		a, err := NewAdiantum(fromHex(v.key), aes.NewCipher)
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create Adiantum instance: %s", i, err)
+		}
+
+		buf := make([]byte, len(plaintext))
+		a.Encrypt(buf, plaintext, tweak)
+		if !bytes.Equal(buf, ciphertext) {
+			t.Fatalf("TestVector %d Encrypt failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(ciphertext))
+		}
+		a.Decrypt(buf, buf, tweak)
+		if !bytes.Equal(buf, plaintext) {
+			t.Fatalf("TestVector %d Decrypt failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(plaintext))
+		}
+	}
+}
+
+func testAdiantum(t *testing.T, name string, newCipher func([]byte) (cipher.Block, error)) {
+	key := make([]byte, AdiantumKeySize)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	a, err := NewAdiantum(key, newCipher)
+	if err != nil {
+		t.Fatalf("%s: Failed to create Adiantum instance: %s", name, err)
+	}
+
+	msg := make([]byte, 2*adiantumNHUnitSize+100)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	tweak := make([]byte, AdiantumTweakSize)
+	for i := range tweak {
+		tweak[i] = byte(255 - i)
+	}
+	sizes := []int{16, 17, 31, 32, 33, 100, 512, adiantumNHUnitSize + 15, adiantumNHUnitSize + 16, adiantumNHUnitSize + 17, len(msg)}
+	for _, size := range sizes {
+		ciphertext := make([]byte, size)
+		a.Encrypt(ciphertext, msg[:size], tweak)
+		if bytes.Equal(ciphertext, msg[:size]) {
+			t.Fatalf("%s: Size %d: encryption returned the plaintext", name, size)
+		}
+
+		buf := append([]byte{}, msg[:size]...)
+		a.Encrypt(buf, buf, tweak)
+		if !bytes.Equal(buf, ciphertext) {
+			t.Fatalf("%s: Size %d: in-place encryption failed", name, size)
+		}
+
+		a.Decrypt(buf, buf, tweak)
+		if !bytes.Equal(buf, msg[:size]) {
+			t.Fatalf("%s: Size %d: decryption failed", name, size)
+		}
+	}
+}
+
+func TestAdiantum(t *testing.T) {
+	testAdiantum(t, "AES", aes.NewCipher)
+	testAdiantum(t, "Camellia", camellia.NewCipher)
+	testAdiantum(t, "Serpent", serpent.NewCipher)
+}
+
+func TestAdiantumSector(t *testing.T) {
+	a, err := NewAdiantum(make([]byte, AdiantumKeySize), aes.NewCipher)
+	if err != nil {
+		t.Fatalf("Failed to create Adiantum instance: %s", err)
+	}
+	sector := make([]byte, 4096)
+	for i := range sector {
+		sector[i] = byte(i)
+	}
+
+	const number = 0x0102030405060708
+	tweak := make([]byte, AdiantumTweakSize)
+	binary.LittleEndian.PutUint64(tweak, number)
+
+	expected, ciphertext := make([]byte, len(sector)), make([]byte, len(sector))
+	a.Encrypt(expected, sector, tweak)
+	a.EncryptSector(ciphertext, sector, number)
+	if !bytes.Equal(ciphertext, expected) {
+		t.Fatal("EncryptSector does not use the little endian sector number as tweak")
+	}
+
+	a.EncryptSector(expected, sector, number+1)
+	if bytes.Equal(ciphertext, expected) {
+		t.Fatal("EncryptSector returned the same ciphertext for different sectors")
+	}
+
+	a.DecryptSector(ciphertext, ciphertext, number)
+	if !bytes.Equal(ciphertext, sector) {
+		t.Fatal("DecryptSector failed")
+	}
+}
+
+func TestAdiantumDiffusion(t *testing.T) {
+	a, err := NewAdiantum(make([]byte, AdiantumKeySize), aes.NewCipher)
+	if err != nil {
+		t.Fatalf("Failed to create Adiantum instance: %s", err)
+	}
+	msg, tweak := make([]byte, 100), make([]byte, AdiantumTweakSize)
+	ciphertext := make([]byte, len(msg))
+	a.Encrypt(ciphertext, msg, tweak)
+
+	// modifying any byte of the message or the tweak must change every block
+	modified := make([]byte, len(msg))
+	for i := 0; i < len(msg)+len(tweak); i++ {
+		if i < len(msg) {
+			msg[i] ^= 1
+		} else {
+			tweak[i-len(msg)] ^= 1
+		}
+		a.Encrypt(modified, msg, tweak)
+		for j := 0; j < len(msg); j += 16 {
+			k := j + 16
+			if k > len(msg) {
+				k = len(msg)
+			}
+			if bytes.Equal(modified[j:k], ciphertext[j:k]) {
+				t.Fatalf("Modification of byte %d does not change the ciphertext block %d", i, j/16)
+			}
+		}
+		if i < len(msg) {
+			msg[i] ^= 1
+		} else {
+			tweak[i-len(msg)] ^= 1
+		}
+	}
+}
+
+func TestAdiantumArithmetic(t *testing.T) {
+	var x, y, z [16]byte
+	for i := range x {
+		x[i] = 0xff
+	}
+	y[0] = 2
+
+	// (2^128 - 1) + 2 = 1 mod 2^128
+	add128(&z, x[:], &y)
+	if z != [16]byte{1} {
+		t.Fatalf("add128 failed: %x", z)
+	}
+	// 1 - 2 = 2^128 - 1 mod 2^128
+	sub128(&z, &y)
+	if z != x {
+		t.Fatalf("sub128 failed: %x", z)
+	}
+}
+
+func TestNewAdiantum(t *testing.T) {
+	for _, k := range []int{0, 16, 24, 31, 33} {
+		if _, err := NewAdiantum(make([]byte, k), aes.NewCipher); err == nil {
+			t.Fatalf("NewAdiantum accepted key size %d", k)
+		}
+	}
+	if _, err := NewAdiantum(make([]byte, AdiantumKeySize), nil); err == nil {
+		t.Fatal("NewAdiantum accepted nil cipher constructor")
+	}
+}
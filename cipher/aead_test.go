@@ -0,0 +1,72 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/hex"
+	"testing"
+)
+
+// testAEAD checks the generic cipher.AEAD properties: the ciphertext length,
+// the (in-place) decryption and the rejection of modified nonces, additional
+// data, lengths and ciphertexts.
+func testAEAD(t *testing.T, name string, aead cipher.AEAD) {
+	nonce := make([]byte, aead.NonceSize())
+	for i := range nonce {
+		nonce[i] = byte(i)
+	}
+	data := []byte("additional data")
+	msg := make([]byte, 300)
+	for i := range msg {
+		msg[i] = byte(i * 7)
+	}
+
+	for _, size := range []int{0, 1, 15, 16, 17, 31, 32, 33, 63, 64, 65, 100, 300} {
+		ciphertext := aead.Seal(nil, nonce, msg[:size], data)
+		if n := len(ciphertext); n <= size || n > size+aead.Overhead() {
+			t.Fatalf("%s: Seal returned %d bytes for %d bytes plaintext", name, n, size)
+		}
+
+		plaintext, err := aead.Open(nil, nonce, ciphertext, data)
+		if err != nil {
+			t.Fatalf("%s: Open failed: %s", name, err)
+		}
+		if !bytes.Equal(plaintext, msg[:size]) {
+			t.Fatalf("%s: Open failed:\nFound   : %s\nExpected: %s", name, hex.EncodeToString(plaintext), hex.EncodeToString(msg[:size]))
+		}
+
+		buf := append(make([]byte, 0, len(ciphertext)), msg[:size]...)
+		if buf = aead.Seal(buf[:0], nonce, buf, data); !bytes.Equal(buf, ciphertext) {
+			t.Fatalf("%s: in-place Seal failed:\nFound   : %s\nExpected: %s", name, hex.EncodeToString(buf), hex.EncodeToString(ciphertext))
+		}
+		if buf, err = aead.Open(buf[:0], nonce, buf, data); err != nil || !bytes.Equal(buf, msg[:size]) {
+			t.Fatalf("%s: in-place Open failed: %v", name, err)
+		}
+
+		if _, err = aead.Open(nil, nonce, ciphertext, data[1:]); err == nil {
+			t.Fatalf("%s: Open accepted modified additional data", name)
+		}
+		if _, err = aead.Open(nil, nonce, ciphertext[1:], data); err == nil {
+			t.Fatalf("%s: Open accepted truncated ciphertext", name)
+		}
+		// moving bytes from the additional data to the ciphertext must be detected
+		if _, err = aead.Open(nil, nonce, append(data[len(data)-1:], ciphertext...), data[:len(data)-1]); err == nil {
+			t.Fatalf("%s: Open accepted modified lengths", name)
+		}
+		nonce[0] ^= 1
+		if _, err = aead.Open(nil, nonce, ciphertext, data); err == nil {
+			t.Fatalf("%s: Open accepted modified nonce", name)
+		}
+		nonce[0] ^= 1
+		for i := range ciphertext {
+			ciphertext[i] ^= 1
+			if _, err = aead.Open(nil, nonce, ciphertext, data); err == nil {
+				t.Fatalf("%s: Open accepted modified ciphertext byte %d", name, i)
+			}
+			ciphertext[i] ^= 1
+		}
+	}
+}
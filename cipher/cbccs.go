@@ -0,0 +1,175 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+// The ciphertext stealing variants of the CBC mode
+// specified in the NIST SP 800-38A Addendum.
+const (
+	// CS1 keeps the order of the last two ciphertext blocks.
+	CS1 = 1 + iota
+	// CS2 swaps the last two ciphertext blocks, if the last
+	// plaintext block is incomplete.
+	CS2
+	// CS3 always swaps the last two ciphertext blocks. CS3 is
+	// used by Kerberos (RFC 3962).
+	CS3
+)
+
+// CBCCS is the CBC mode with ciphertext stealing. In contrast to the CBC
+// mode of crypto/cipher, CBCCS can process messages of any length (at least
+// one block), so the ciphertext is exactly as long as the plaintext.
+type CBCCS interface {
+	// BlockSize returns the block size of the cipher.
+	BlockSize() int
+
+	// Encrypt encrypts the message in src using the iv and writes the
+	// ciphertext into dst. The length of src must be at least BlockSize()
+	// bytes and dst must be at least as large as src. The iv must be
+	// BlockSize() bytes long. Dst and src may overlap entirely or not at all.
+	Encrypt(dst, src, iv []byte)
+
+	// Decrypt decrypts the message in src using the iv and writes the
+	// plaintext into dst. The length of src must be at least BlockSize()
+	// bytes and dst must be at least as large as src. The iv must be
+	// BlockSize() bytes long. Dst and src may overlap entirely or not at all.
+	Decrypt(dst, src, iv []byte)
+}
+
+// The CBC mode with ciphertext stealing
+type cbcCS struct {
+	blockCipher cipher.Block
+	variant     int
+}
+
+// NewCBCCS returns a CBCCS implementing the CBC mode with ciphertext stealing
+// specified in the NIST SP 800-38A Addendum wrapping the cipher.Block.
+// The variant argument must be CS1, CS2 or CS3.
+func NewCBCCS(c cipher.Block, variant int) (CBCCS, error) {
+	if c == nil {
+		return nil, errors.New("the cipher.Block must not be nil")
+	}
+	if variant != CS1 && variant != CS2 && variant != CS3 {
+		return nil, errors.New("variant must be CS1, CS2 or CS3")
+	}
+	return &cbcCS{
+		blockCipher: c,
+		variant:     variant,
+	}, nil
+}
+
+func (c *cbcCS) BlockSize() int { return c.blockCipher.BlockSize() }
+
+func (c *cbcCS) Encrypt(dst, src, iv []byte) {
+	c.checkArgs(dst, src, iv)
+	bs := c.blockCipher.BlockSize()
+
+	n := len(src)
+	d := n % bs // the length of the last (incomplete) block
+	if d == 0 {
+		d = bs
+	}
+
+	prev := make([]byte, bs)
+	copy(prev, iv)
+	for i := 0; i < n-d; i += bs {
+		crypto.XOR(dst[i:i+bs], src[i:i+bs], prev)
+		c.blockCipher.Encrypt(dst[i:i+bs], dst[i:i+bs])
+		prev = dst[i : i+bs]
+	}
+
+	// the last block is zero padded
+	last := make([]byte, bs)
+	copy(last, src[n-d:])
+	crypto.XOR(last, last, prev)
+	c.blockCipher.Encrypt(last, last)
+
+	if n == bs {
+		copy(dst, last)
+		return
+	}
+
+	// prev is the second last ciphertext block, which is truncated to d bytes
+	if c.swap(d) {
+		stolen := make([]byte, d)
+		copy(stolen, prev)
+		copy(dst[n-d-bs:], last)
+		copy(dst[n-d:], stolen)
+	} else {
+		copy(dst[n-bs:], last)
+	}
+}
+
+func (c *cbcCS) Decrypt(dst, src, iv []byte) {
+	c.checkArgs(dst, src, iv)
+	bs := c.blockCipher.BlockSize()
+
+	n := len(src)
+	if n == bs {
+		block := make([]byte, bs)
+		c.blockCipher.Decrypt(block, src)
+		crypto.XOR(dst[:bs], block, iv)
+		return
+	}
+
+	d := n % bs // the length of the last (incomplete) block
+	if d == 0 {
+		d = bs
+	}
+
+	// restore the order of CS1: the truncated second last block and the last block
+	last, stolen := make([]byte, bs), make([]byte, bs)
+	if c.swap(d) {
+		copy(last, src[n-d-bs:n-d])
+		copy(stolen, src[n-d:])
+	} else {
+		copy(stolen, src[n-d-bs:n-bs])
+		copy(last, src[n-bs:])
+	}
+
+	prev, block := make([]byte, bs), make([]byte, bs)
+	copy(prev, iv)
+	for i := 0; i < n-d-bs; i += bs {
+		copy(block, src[i:i+bs])
+		c.blockCipher.Decrypt(dst[i:i+bs], block)
+		crypto.XOR(dst[i:i+bs], dst[i:i+bs], prev)
+		prev, block = block, prev
+	}
+
+	// the decrypted last block contains the stolen
+	// bytes of the second last ciphertext block
+	c.blockCipher.Decrypt(last, last)
+	copy(stolen[d:], last[d:])
+	crypto.XOR(last, last, stolen)
+
+	c.blockCipher.Decrypt(stolen, stolen)
+	crypto.XOR(dst[n-d-bs:n-d], stolen, prev)
+	copy(dst[n-d:], last[:d])
+}
+
+// swap returns true if the last two ciphertext blocks are
+// swapped. The argument d is the length of the last block.
+func (c *cbcCS) swap(d int) bool {
+	return c.variant == CS3 || (c.variant == CS2 && d != c.blockCipher.BlockSize())
+}
+
+// checkArgs panics if the length of dst, src or iv is not valid.
+func (c *cbcCS) checkArgs(dst, src, iv []byte) {
+	bs := c.blockCipher.BlockSize()
+	if len(iv) != bs {
+		panic("iv length must equal the block size")
+	}
+	if len(src) < bs {
+		panic("src must be at least one block long")
+	}
+	if len(dst) < len(src) {
+		panic("dst buffer to small")
+	}
+}
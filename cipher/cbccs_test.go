@@ -0,0 +1,205 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"testing"
+
+	"github.com/enceve/crypto/camellia"
+	"github.com/enceve/crypto/serpent"
+)
+
+// The plaintext of the RFC 3962 test vectors
+const cbcCSPlaintext = "I would like the General Gau's Chicken, please, and wonton soup."
+
+// CBC-CS3 (AES-128) test vectors from RFC 3962 (Appendix B)
+// and CBC-CS1 / CBC-CS2 test vectors computed with OpenSSL
+var cbcCSVectors = []struct {
+	variant    int
+	key, iv    string
+	length     int
+	ciphertext string
+}{
+	{
+		variant:    CS3,
+		key:        "636869636b656e207465726979616b69",
+		iv:         "00000000000000000000000000000000",
+		length:     17,
+		ciphertext: "c6353568f2bf8cb4d8a580362da7ff7f97",
+	},
+	{
+		variant:    CS3,
+		key:        "636869636b656e207465726979616b69",
+		iv:         "00000000000000000000000000000000",
+		length:     31,
+		ciphertext: "fc00783e0efdb2c1d445d4c8eff7ed2297687268d6ecccc0c07b25e25ecfe5",
+	},
+	{
+		variant:    CS3,
+		key:        "636869636b656e207465726979616b69",
+		iv:         "00000000000000000000000000000000",
+		length:     32,
+		ciphertext: "39312523a78662d5be7fcbcc98ebf5a897687268d6ecccc0c07b25e25ecfe584",
+	},
+	{
+		variant: CS3,
+		key:     "636869636b656e207465726979616b69",
+		iv:      "00000000000000000000000000000000",
+		length:  47,
+		ciphertext: "97687268d6ecccc0c07b25e25ecfe584b3fffd940c16a18c1b5549d2f838029e" +
+			"39312523a78662d5be7fcbcc98ebf5",
+	},
+	{
+		variant: CS3,
+		key:     "636869636b656e207465726979616b69",
+		iv:      "00000000000000000000000000000000",
+		length:  48,
+		ciphertext: "97687268d6ecccc0c07b25e25ecfe5849dad8bbb96c4cdc03bc103e1a194bbd8" +
+			"39312523a78662d5be7fcbcc98ebf5a8",
+	},
+	{
+		variant: CS3,
+		key:     "636869636b656e207465726979616b69",
+		iv:      "00000000000000000000000000000000",
+		length:  64,
+		ciphertext: "97687268d6ecccc0c07b25e25ecfe58439312523a78662d5be7fcbcc98ebf5a8" +
+			"4807efe836ee89a526730dbc2f7bc8409dad8bbb96c4cdc03bc103e1a194bbd8",
+	},
+	{
+		variant:    CS1,
+		key:        "636869636b656e207465726979616b69",
+		iv:         "000102030405060708090a0b0c0d0e0f",
+		length:     17,
+		ciphertext: "54c255fd16a17eaaad39f5259a80aba22b",
+	},
+	{
+		variant:    CS1,
+		key:        "636869636b656e207465726979616b69",
+		iv:         "000102030405060708090a0b0c0d0e0f",
+		length:     32,
+		ciphertext: "5432a630742dee7beb70f9f1400ee6a0b557cfb581949a4bdf3bb67dedd472b9",
+	},
+	{
+		variant: CS1,
+		key:     "636869636b656e207465726979616b69",
+		iv:      "000102030405060708090a0b0c0d0e0f",
+		length:  47,
+		ciphertext: "5432a630742dee7beb70f9f1400ee6a0b557cfb581949a4bdf3bb67dedd472" +
+			"426da5c54a9990f5ae0b7825f51f0060",
+	},
+	{
+		variant:    CS2,
+		key:        "636869636b656e207465726979616b69",
+		iv:         "000102030405060708090a0b0c0d0e0f",
+		length:     17,
+		ciphertext: "c255fd16a17eaaad39f5259a80aba22b54",
+	},
+	{
+		variant:    CS2,
+		key:        "636869636b656e207465726979616b69",
+		iv:         "000102030405060708090a0b0c0d0e0f",
+		length:     32,
+		ciphertext: "5432a630742dee7beb70f9f1400ee6a0b557cfb581949a4bdf3bb67dedd472b9",
+	},
+	{
+		variant: CS2,
+		key:     "636869636b656e207465726979616b69",
+		iv:      "000102030405060708090a0b0c0d0e0f",
+		length:  47,
+		ciphertext: "5432a630742dee7beb70f9f1400ee6a0426da5c54a9990f5ae0b7825f51f0060" +
+			"b557cfb581949a4bdf3bb67dedd472",
+	},
+}
+
+func TestCBCCSVectors(t *testing.T) {
+	for i, v := range cbcCSVectors {
+		key, iv, ciphertext := fromHex(v.key), fromHex(v.iv), fromHex(v.ciphertext)
+		msg := []byte(cbcCSPlaintext[:v.length])
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create AES instance: %s", i, err)
+		}
+		c, err := NewCBCCS(block, v.variant)
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create CBCCS instance: %s", i, err)
+		}
+
+		buf := make([]byte, len(msg))
+		c.Encrypt(buf, msg, iv)
+		if !bytes.Equal(buf, ciphertext) {
+			t.Fatalf("TestVector %d Encrypt failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(ciphertext))
+		}
+
+		c.Decrypt(buf, buf, iv)
+		if !bytes.Equal(buf, msg) {
+			t.Fatalf("TestVector %d Decrypt failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(msg))
+		}
+	}
+}
+
+func testCBCCS(t *testing.T, name string, block cipher.Block) {
+	bs := block.BlockSize()
+	iv, msg := make([]byte, bs), make([]byte, 10*bs)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	for _, variant := range []int{CS1, CS2, CS3} {
+		c, err := NewCBCCS(block, variant)
+		if err != nil {
+			t.Fatalf("%s: Failed to create CBCCS instance: %s", name, err)
+		}
+		for i := bs; i <= len(msg); i++ {
+			ciphertext := make([]byte, i)
+			c.Encrypt(ciphertext, msg[:i], iv)
+
+			// in-place decryption
+			plaintext := make([]byte, i)
+			copy(plaintext, ciphertext)
+			c.Decrypt(plaintext, plaintext, iv)
+			if !bytes.Equal(plaintext, msg[:i]) {
+				t.Fatalf("%s: CS%d: Decrypt failed:\nFound   : %s\nExpected: %s", name, variant, hex.EncodeToString(plaintext), hex.EncodeToString(msg[:i]))
+			}
+
+			if i%bs == 0 {
+				// CS1 and CS2 are equal to the CBC mode for complete blocks
+				cbc := make([]byte, i)
+				cipher.NewCBCEncrypter(block, iv).CryptBlocks(cbc, msg[:i])
+				if variant != CS3 && !bytes.Equal(cbc, ciphertext) {
+					t.Fatalf("%s: CS%d: ciphertext of complete blocks does not match CBC", name, variant)
+				}
+			}
+		}
+	}
+}
+
+func TestCBCCS(t *testing.T) {
+	c, err := camellia.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create Camellia instance: %s", err)
+	}
+	testCBCCS(t, "Camellia", c)
+
+	s, err := serpent.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create Serpent instance: %s", err)
+	}
+	testCBCCS(t, "Serpent", s)
+}
+
+func TestNewCBCCS(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES instance: %s", err)
+	}
+	for _, variant := range []int{0, 4} {
+		if _, err = NewCBCCS(block, variant); err == nil {
+			t.Fatalf("NewCBCCS accepted variant %d", variant)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"errors"
+	"hash"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/pad"
+)
+
+// The CBC-HMAC cipher
+type cbcHMAC struct {
+	cipher  cipher.Block
+	padding pad.Padding
+	newMac  func() hash.Hash
+	size    int
+}
+
+// NewCBCHMAC returns a cipher.AEAD implementing the composite CBC-HMAC AEAD
+// scheme specified in RFC 7518 (Section 5.2) for any block cipher and MAC.
+// The plaintext is padded with PKCS 7 and encrypted with the cipher.Block in
+// CBC mode - the nonce is the IV and must be as long as the block size. The
+// additional data, the IV and the ciphertext are authenticated followed by
+// the length of the additional data (in bits) encoded as 64 bit big endian
+// integer. The auth. tag is the first tagsize bytes of the MAC.
+// The newMac function must return a MAC (as hash.Hash) keyed with a key
+// independent from the cipher key - e.g. a wrapper around hmac.New or a
+// keyed BLAKE2b. The tagsize must be between 1 and the size of the MAC.
+func NewCBCHMAC(c cipher.Block, newMac func() hash.Hash, tagsize int) (cipher.AEAD, error) {
+	if c == nil {
+		return nil, errors.New("the cipher.Block must not be nil")
+	}
+	if newMac == nil {
+		return nil, errors.New("the MAC constructor must not be nil")
+	}
+	if bs := c.BlockSize(); bs > 255 {
+		return nil, errors.New("cipher block size not supported")
+	}
+	if tagsize < 1 || tagsize > newMac().Size() {
+		return nil, errors.New("tagSize must between 1 and the size of the MAC")
+	}
+	return &cbcHMAC{
+		cipher:  c,
+		padding: pad.NewPKCS7(c.BlockSize()),
+		newMac:  newMac,
+		size:    tagsize,
+	}, nil
+}
+
+// NewAESCBCHMAC returns a cipher.AEAD implementing the AES-CBC-HMAC-SHA2
+// algorithms specified in RFC 7518 (Section 5.2). The key is split into the
+// MAC key (first half) and the AES key (second half). The length of the key
+// must be 32, 48 or 64 byte and selects:
+//   - AES_128_CBC_HMAC_SHA_256 (16 byte tag)
+//   - AES_192_CBC_HMAC_SHA_384 (24 byte tag)
+//   - AES_256_CBC_HMAC_SHA_512 (32 byte tag)
+//
+// The nonce (IV) is 16 bytes long.
+func NewAESCBCHMAC(key []byte) (cipher.AEAD, error) {
+	var h func() hash.Hash
+	switch k := len(key); k {
+	default:
+		return nil, crypto.KeySizeError(k)
+	case 32:
+		h = sha256.New
+	case 48:
+		h = sha512.New384
+	case 64:
+		h = sha512.New
+	}
+	n := len(key) / 2
+
+	c, err := aes.NewCipher(key[n:])
+	if err != nil {
+		return nil, err
+	}
+	macKey := make([]byte, n)
+	copy(macKey, key[:n])
+	newMac := func() hash.Hash { return hmac.New(h, macKey) }
+	return NewCBCHMAC(c, newMac, n)
+}
+
+func (c *cbcHMAC) NonceSize() int { return c.cipher.BlockSize() }
+
+// Overhead returns the max. difference between the lengths of
+// a plaintext and its ciphertext - the block size (padding) plus
+// the tag size.
+func (c *cbcHMAC) Overhead() int { return c.cipher.BlockSize() + c.size }
+
+func (c *cbcHMAC) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if n := len(nonce); n != c.NonceSize() {
+		panic(crypto.NonceSizeError(n))
+	}
+	n := len(plaintext) + c.padding.Overhead(plaintext)
+	ret, out := sliceForAppend(dst, n+c.size)
+
+	copy(out, plaintext)
+	c.padding.Pad(out[:len(plaintext)])
+	cipher.NewCBCEncrypter(c.cipher, nonce).CryptBlocks(out[:n], out[:n])
+
+	tag := c.authenticate(out[:n], nonce, additionalData)
+	copy(out[n:], tag[:c.size])
+	return ret
+}
+
+func (c *cbcHMAC) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if n := len(nonce); n != c.NonceSize() {
+		return nil, crypto.NonceSizeError(n)
+	}
+	bs := c.cipher.BlockSize()
+	n := len(ciphertext) - c.size
+	if n < bs || n%bs != 0 {
+		return nil, crypto.AuthenticationError{}
+	}
+
+	hash := ciphertext[n:]
+	ciphertext = ciphertext[:n]
+
+	// verify the tag before decrypting and unpadding
+	// to prevent padding oracle attacks
+	tag := c.authenticate(ciphertext, nonce, additionalData)
+	if subtle.ConstantTimeCompare(tag[:c.size], hash) != 1 {
+		return nil, crypto.AuthenticationError{}
+	}
+
+	ret, out := sliceForAppend(dst, n)
+	cipher.NewCBCDecrypter(c.cipher, nonce).CryptBlocks(out, ciphertext)
+
+	plaintext, err := c.padding.Unpad(out)
+	if err != nil {
+		return nil, crypto.AuthenticationError{}
+	}
+	return ret[:len(dst)+len(plaintext)], nil
+}
+
+// authenticate computes the MAC of the additional data, the iv and
+// the ciphertext followed by the length of the additional data (in
+// bits) encoded as 64 bit big endian integer.
+func (c *cbcHMAC) authenticate(ciphertext, iv, additionalData []byte) []byte {
+	mac := c.newMac()
+	mac.Write(additionalData)
+	mac.Write(iv)
+	mac.Write(ciphertext)
+
+	var buf [8]byte
+	adLen := uint64(len(additionalData)) * 8
+	for i := range buf {
+		buf[i] = byte(adLen >> uint(56-8*i))
+	}
+	mac.Write(buf[:])
+	return mac.Sum(nil)
+}
@@ -0,0 +1,189 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"testing"
+
+	"github.com/enceve/crypto/blake2/blake2b"
+	"github.com/enceve/crypto/camellia"
+	"github.com/enceve/crypto/serpent"
+)
+
+// Test vectors for AES-CBC-HMAC-SHA2 from RFC 7518 Appendix B.
+// The key is 0x00, 0x01, ...
+var cbcHMACVectors = []struct {
+	keySize                         int
+	iv, plaintext, data, ciphertext string
+}{
+	{
+		keySize: 32,
+		iv:      "1af38c2dc2b96ffdd86694092341bc04",
+		plaintext: "41206369706865722073797374656d206d757374206e6f742062652072657175" +
+			"6972656420746f206265207365637265742c20616e64206974206d7573742062" +
+			"652061626c6520746f2066616c6c20696e746f207468652068616e6473206f66" +
+			"2074686520656e656d7920776974686f757420696e636f6e76656e69656e6365",
+		data: "546865207365636f6e64207072696e6369706c65206f66204175677573746520" +
+			"4b6572636b686f666673",
+		ciphertext: "c80edfa32ddf39d5ef00c0b468834279a2e46a1b8049f792f76bfe54b903a9c9" +
+			"a94ac9b47ad2655c5f10f9aef71427e2fc6f9b3f399a221489f16362c7032336" +
+			"09d45ac69864e3321cf82935ac4096c86e133314c54019e8ca7980dfa4b9cf1b" +
+			"384c486f3a54c51078158ee5d79de59fbd34d848b3d69550a67646344427ade5" +
+			"4b8851ffb598f7f80074b9473c82e2db" +
+			"652c3fa36b0a7c5b3219fab3a30bc1c4",
+	},
+	{
+		keySize: 48,
+		iv:      "1af38c2dc2b96ffdd86694092341bc04",
+		plaintext: "41206369706865722073797374656d206d757374206e6f742062652072657175" +
+			"6972656420746f206265207365637265742c20616e64206974206d7573742062" +
+			"652061626c6520746f2066616c6c20696e746f207468652068616e6473206f66" +
+			"2074686520656e656d7920776974686f757420696e636f6e76656e69656e6365",
+		data: "546865207365636f6e64207072696e6369706c65206f66204175677573746520" +
+			"4b6572636b686f666673",
+		ciphertext: "ea65da6b59e61edb419be62d19712ae5d303eeb50052d0dfd6697f77224c8edb" +
+			"000d279bdc14c1072654bd30944230c657bed4ca0c9f4a8466f22b226d174621" +
+			"4bf8cfc2400add9f5126e479663fc90b3bed787a2f0ffcbf3904be2a641d5c21" +
+			"05bfe591bae23b1d7449e532eef60a9ac8bb6c6b01d35d49787bcd57ef484927" +
+			"f280adc91ac0c4e79c7b11efc60054e3" +
+			"8490ac0e58949bfe51875d733f93ac2075168039ccc733d7",
+	},
+	{
+		keySize: 64,
+		iv:      "1af38c2dc2b96ffdd86694092341bc04",
+		plaintext: "41206369706865722073797374656d206d757374206e6f742062652072657175" +
+			"6972656420746f206265207365637265742c20616e64206974206d7573742062" +
+			"652061626c6520746f2066616c6c20696e746f207468652068616e6473206f66" +
+			"2074686520656e656d7920776974686f757420696e636f6e76656e69656e6365",
+		data: "546865207365636f6e64207072696e6369706c65206f66204175677573746520" +
+			"4b6572636b686f666673",
+		ciphertext: "4affaaadb78c31c5da4b1b590d10ffbd3dd8d5d302423526912da037ecbcc7bd" +
+			"822c301dd67c373bccb584ad3e9279c2e6d12a1374b77f077553df829410446b" +
+			"36ebd97066296ae6427ea75c2e0846a11a09ccf5370dc80bfecbad28c73f09b3" +
+			"a3b75e662a2594410ae496b2e2e6609e31e6e02cc837f053d21f37ff4f51950b" +
+			"be2638d09dd7a4930930806d0703b1f6" +
+			"4dd3b4c088a7f45c216839645b2012bf2e6269a8c56a816dbc1b267761955bc5",
+	},
+}
+
+func TestCBCHMACVectors(t *testing.T) {
+	for i, v := range cbcHMACVectors {
+		key := make([]byte, v.keySize)
+		for j := range key {
+			key[j] = byte(j)
+		}
+		iv, plaintext, data := fromHex(v.iv), fromHex(v.plaintext), fromHex(v.data)
+		ciphertext := fromHex(v.ciphertext)
+
+		aead, err := NewAESCBCHMAC(key)
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create AES-CBC-HMAC instance: %s", i, err)
+		}
+
+		buf := aead.Seal(nil, iv, plaintext, data)
+		if !bytes.Equal(buf, ciphertext) {
+			t.Fatalf("TestVector %d Seal failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(ciphertext))
+		}
+
+		buf, err = aead.Open(buf[:0], iv, buf, data)
+		if err != nil {
+			t.Fatalf("TestVector %d: Open failed: %s", i, err)
+		}
+		if !bytes.Equal(buf, plaintext) {
+			t.Fatalf("TestVector %d Open failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(plaintext))
+		}
+
+		ciphertext[len(ciphertext)-1] ^= 1
+		if _, err = aead.Open(nil, iv, ciphertext, data); err == nil {
+			t.Fatalf("TestVector %d: Open accepted modified tag", i)
+		}
+	}
+}
+
+func TestCBCHMAC(t *testing.T) {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+
+	c, err := camellia.NewCipher(key[16:])
+	if err != nil {
+		t.Fatalf("Failed to create Camellia instance: %s", err)
+	}
+	aead, err := NewCBCHMAC(c, func() hash.Hash { return hmac.New(sha256.New, key[:16]) }, 16)
+	if err != nil {
+		t.Fatalf("Failed to create Camellia-CBC-HMAC-SHA256 instance: %s", err)
+	}
+	testAEAD(t, "Camellia-CBC-HMAC-SHA256", aead)
+
+	c, err = serpent.NewCipher(key[16:])
+	if err != nil {
+		t.Fatalf("Failed to create Serpent instance: %s", err)
+	}
+	newMac := func() hash.Hash {
+		h, err := blake2b.New(32, &blake2b.Config{Key: key[:16]})
+		if err != nil {
+			panic(err)
+		}
+		return h
+	}
+	aead, err = NewCBCHMAC(c, newMac, 32)
+	if err != nil {
+		t.Fatalf("Failed to create Serpent-CBC-BLAKE2b instance: %s", err)
+	}
+	testAEAD(t, "Serpent-CBC-BLAKE2b", aead)
+}
+
+func TestCBCHMACBadPadding(t *testing.T) {
+	key := make([]byte, 32)
+	aead, err := NewAESCBCHMAC(key)
+	if err != nil {
+		t.Fatalf("Failed to create AES-CBC-HMAC instance: %s", err)
+	}
+	c := aead.(*cbcHMAC)
+	nonce := make([]byte, aead.NonceSize())
+
+	// a ciphertext with a valid tag but an invalid
+	// padding - the last plaintext byte is 0
+	ciphertext := make([]byte, 16)
+	cipher.NewCBCEncrypter(c.cipher, nonce).CryptBlocks(ciphertext, ciphertext)
+	tag := c.authenticate(ciphertext, nonce, nil)
+	ciphertext = append(ciphertext, tag[:c.size]...)
+	if _, err = aead.Open(nil, nonce, ciphertext, nil); err == nil {
+		t.Fatal("Open accepted invalid padding")
+	}
+}
+
+func TestNewCBCHMAC(t *testing.T) {
+	badKeySizes := []int{0, 16, 31, 33, 47, 63, 65}
+	for i, v := range badKeySizes {
+		if _, err := NewAESCBCHMAC(make([]byte, v)); err == nil {
+			t.Fatalf("Test %d: NewAESCBCHMAC accepted key size %d", i, v)
+		}
+	}
+
+	c, err := camellia.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create Camellia instance: %s", err)
+	}
+	newMac := func() hash.Hash { return hmac.New(sha256.New, make([]byte, 16)) }
+	if _, err = NewCBCHMAC(nil, newMac, 16); err == nil {
+		t.Fatal("NewCBCHMAC accepted nil cipher.Block")
+	}
+	if _, err = NewCBCHMAC(c, nil, 16); err == nil {
+		t.Fatal("NewCBCHMAC accepted nil MAC constructor")
+	}
+	badTagSizes := []int{0, 33}
+	for i, v := range badTagSizes {
+		if _, err = NewCBCHMAC(c, newMac, v); err == nil {
+			t.Fatalf("Test %d: NewCBCHMAC accepted tag size %d", i, v)
+		}
+	}
+}
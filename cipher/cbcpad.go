@@ -0,0 +1,188 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"errors"
+	"io"
+
+	"github.com/enceve/crypto/pad"
+)
+
+// ErrCBCDecryption is returned by the padded CBC decrypter, if the ciphertext
+// is not valid - either because its length is not a multiple of the block size
+// or because the padding of the last block is not valid. Both cases return the
+// same error to not reveal, which check failed.
+var ErrCBCDecryption = errors.New("padded CBC decryption failed")
+
+// The size of the internal buffer of the padded CBC encrypter and decrypter.
+const cbcPadBufSize = 4096
+
+var errCBCClosed = errors.New("the padded CBC encrypter is closed")
+
+func newPaddedCBC(c cipher.Block, iv []byte, padding pad.Padding) error {
+	if c == nil {
+		return errors.New("the cipher.Block must not be nil")
+	}
+	if padding == nil {
+		return errors.New("the padding must not be nil")
+	}
+	if padding.BlockSize() != c.BlockSize() {
+		return errors.New("the block size of the padding and the cipher must be equal")
+	}
+	if len(iv) != c.BlockSize() {
+		return errors.New("iv length must equal the block size")
+	}
+	return nil
+}
+
+// NewPaddedCBCEncrypter returns an io.WriteCloser encrypting all data written
+// to it with the CBC mode and writing the ciphertext to w. Close pads the last
+// (incomplete) block with the padding - e.g. pad.NewPKCS7, pad.NewX923 or
+// pad.NewISO10126 - writes the last ciphertext block to w - but does not close
+// w - and must be called after all data is written.
+// The block size of the padding must be equal to the block size of the cipher
+// and the iv must be as long as the block size.
+func NewPaddedCBCEncrypter(c cipher.Block, iv []byte, padding pad.Padding, w io.Writer) (io.WriteCloser, error) {
+	if err := newPaddedCBC(c, iv, padding); err != nil {
+		return nil, err
+	}
+	return &cbcPadEncrypter{
+		mode:    cipher.NewCBCEncrypter(c, iv),
+		padding: padding,
+		w:       w,
+		buf:     make([]byte, 0, cbcPadBufSize+c.BlockSize()),
+	}, nil
+}
+
+type cbcPadEncrypter struct {
+	mode    cipher.BlockMode
+	padding pad.Padding
+	w       io.Writer
+	buf     []byte // the plaintext, which is not encrypted yet
+	closed  bool
+	err     error
+}
+
+func (e *cbcPadEncrypter) Write(p []byte) (n int, err error) {
+	if e.closed {
+		return 0, errCBCClosed
+	}
+	if e.err != nil {
+		return 0, e.err
+	}
+	bs := e.mode.BlockSize()
+	for len(p) > 0 {
+		k := copy(e.buf[len(e.buf):cap(e.buf)], p)
+		e.buf = e.buf[:len(e.buf)+k]
+		n += k
+		p = p[k:]
+
+		if k = len(e.buf) - len(e.buf)%bs; k > 0 {
+			e.mode.CryptBlocks(e.buf[:k], e.buf[:k])
+			if _, e.err = e.w.Write(e.buf[:k]); e.err != nil {
+				return n, e.err
+			}
+			e.buf = e.buf[:copy(e.buf, e.buf[k:])]
+		}
+	}
+	return n, nil
+}
+
+func (e *cbcPadEncrypter) Close() error {
+	if e.closed {
+		return errCBCClosed
+	}
+	e.closed = true
+	if e.err != nil {
+		return e.err
+	}
+	last := e.padding.Pad(e.buf)
+	e.mode.CryptBlocks(last, last)
+	_, err := e.w.Write(last)
+	return err
+}
+
+// NewPaddedCBCDecrypter returns an io.Reader decrypting the ciphertext read
+// from r with the CBC mode and removing the padding of the last block.
+// The padding and the iv must be the same as the ones used for encryption.
+// The decrypter holds back the last block until r returns io.EOF and removes
+// its padding. If the ciphertext is not valid, Read returns ErrCBCDecryption.
+// Notice that the CBC mode does not authenticate the ciphertext - so the
+// plaintext may be modified even if the padding is valid.
+func NewPaddedCBCDecrypter(c cipher.Block, iv []byte, padding pad.Padding, r io.Reader) (io.Reader, error) {
+	if err := newPaddedCBC(c, iv, padding); err != nil {
+		return nil, err
+	}
+	return &cbcPadDecrypter{
+		mode:      cipher.NewCBCDecrypter(c, iv),
+		padding:   padding,
+		r:         r,
+		buf:       make([]byte, 0, cbcPadBufSize+c.BlockSize()),
+		plaintext: make([]byte, 0, cbcPadBufSize+c.BlockSize()),
+	}, nil
+}
+
+type cbcPadDecrypter struct {
+	mode      cipher.BlockMode
+	padding   pad.Padding
+	r         io.Reader
+	buf       []byte // the ciphertext, which is not decrypted yet
+	plaintext []byte // the unread plaintext
+	off       int    // the read offset of plaintext
+	err       error
+}
+
+func (d *cbcPadDecrypter) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for d.off == len(d.plaintext) {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.fill()
+	}
+	n := copy(p, d.plaintext[d.off:])
+	d.off += n
+	return n, nil
+}
+
+// fill reads ciphertext from r and decrypts all complete blocks - except the
+// last one, which is decrypted and unpadded when r returns io.EOF.
+func (d *cbcPadDecrypter) fill() {
+	bs := d.mode.BlockSize()
+
+	n, err := d.r.Read(d.buf[len(d.buf):cap(d.buf)])
+	d.buf = d.buf[:len(d.buf)+n]
+	if err != nil && err != io.EOF {
+		d.err = err
+		return
+	}
+
+	if err == io.EOF {
+		d.err = io.EOF
+		if len(d.buf) == 0 || len(d.buf)%bs != 0 {
+			d.err = ErrCBCDecryption
+			return
+		}
+		d.mode.CryptBlocks(d.buf, d.buf)
+		plaintext, err := d.padding.Unpad(d.buf)
+		if err != nil {
+			d.err = ErrCBCDecryption
+			return
+		}
+		d.plaintext, d.off = append(d.plaintext[:0], plaintext...), 0
+		d.buf = d.buf[:0]
+		return
+	}
+
+	// hold back the last complete block - it may be the padded block
+	if k := (len(d.buf) - 1) / bs * bs; k > 0 {
+		d.plaintext, d.off = d.plaintext[:k], 0
+		d.mode.CryptBlocks(d.plaintext, d.buf[:k])
+		d.buf = d.buf[:copy(d.buf, d.buf[k:])]
+	}
+}
@@ -0,0 +1,127 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"io/ioutil"
+	"testing"
+	"testing/iotest"
+
+	"github.com/enceve/crypto/pad"
+)
+
+func TestPaddedCBC(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES instance: %s", err)
+	}
+	iv := make([]byte, block.BlockSize())
+	paddings := map[string]pad.Padding{
+		"PKCS7":    pad.NewPKCS7(block.BlockSize()),
+		"X923":     pad.NewX923(block.BlockSize()),
+		"ISO10126": pad.NewISO10126(block.BlockSize(), nil),
+	}
+
+	msg := make([]byte, 2*cbcPadBufSize+100)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	for name, padding := range paddings {
+		for _, size := range []int{0, 1, 15, 16, 17, 100, cbcPadBufSize, len(msg)} {
+			for _, chunk := range []int{1, 16, 1000} {
+				var buf bytes.Buffer
+				w, err := NewPaddedCBCEncrypter(block, iv, padding, &buf)
+				if err != nil {
+					t.Fatalf("%s: Failed to create padded CBC encrypter: %s", name, err)
+				}
+				for i := 0; i < size; i += chunk {
+					j := i + chunk
+					if j > size {
+						j = size
+					}
+					if _, err = w.Write(msg[i:j]); err != nil {
+						t.Fatalf("%s: Write failed: %s", name, err)
+					}
+				}
+				if err = w.Close(); err != nil {
+					t.Fatalf("%s: Close failed: %s", name, err)
+				}
+				ciphertext := buf.Bytes()
+
+				// compare with the manual padding and CBC encryption
+				if name != "ISO10126" {
+					expected := padding.Pad(append([]byte{}, msg[:size]...))
+					cipher.NewCBCEncrypter(block, iv).CryptBlocks(expected, expected)
+					if !bytes.Equal(ciphertext, expected) {
+						t.Fatalf("%s: Size %d - chunk %d: ciphertext does not match the CBC mode", name, size, chunk)
+					}
+				}
+
+				r, err := NewPaddedCBCDecrypter(block, iv, padding, iotest.OneByteReader(bytes.NewReader(ciphertext)))
+				if err != nil {
+					t.Fatalf("%s: Failed to create padded CBC decrypter: %s", name, err)
+				}
+				plaintext, err := ioutil.ReadAll(r)
+				if err != nil {
+					t.Fatalf("%s: Size %d - chunk %d: decryption failed: %s", name, size, chunk, err)
+				}
+				if !bytes.Equal(plaintext, msg[:size]) {
+					t.Fatalf("%s: Size %d - chunk %d: decryption returned wrong plaintext", name, size, chunk)
+				}
+			}
+		}
+	}
+}
+
+func TestPaddedCBCDecrypterError(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES instance: %s", err)
+	}
+	iv, padding := make([]byte, block.BlockSize()), pad.NewPKCS7(block.BlockSize())
+
+	decrypt := func(ciphertext []byte) error {
+		r, err := NewPaddedCBCDecrypter(block, iv, padding, bytes.NewReader(ciphertext))
+		if err != nil {
+			t.Fatalf("Failed to create padded CBC decrypter: %s", err)
+		}
+		_, err = ioutil.ReadAll(r)
+		return err
+	}
+
+	// invalid padding - the last plaintext byte is 0
+	ciphertext := make([]byte, 3*block.BlockSize())
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, ciphertext)
+	if err = decrypt(ciphertext); err != ErrCBCDecryption {
+		t.Fatalf("Decrypter accepted an invalid padding - error: %v", err)
+	}
+
+	// invalid length
+	for _, n := range []int{0, 1, 17, 47} {
+		if err = decrypt(make([]byte, n)); err != ErrCBCDecryption {
+			t.Fatalf("Decrypter accepted a ciphertext of %d bytes - error: %v", n, err)
+		}
+	}
+}
+
+func TestNewPaddedCBC(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES instance: %s", err)
+	}
+	iv := make([]byte, block.BlockSize())
+
+	if _, err = NewPaddedCBCEncrypter(block, iv, pad.NewPKCS7(8), ioutil.Discard); err == nil {
+		t.Fatal("NewPaddedCBCEncrypter accepted a padding with a different block size")
+	}
+	if _, err = NewPaddedCBCEncrypter(block, iv[1:], pad.NewPKCS7(16), ioutil.Discard); err == nil {
+		t.Fatal("NewPaddedCBCEncrypter accepted an invalid iv")
+	}
+	if _, err = NewPaddedCBCDecrypter(block, iv, nil, nil); err == nil {
+		t.Fatal("NewPaddedCBCDecrypter accepted a nil padding")
+	}
+}
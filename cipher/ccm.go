@@ -0,0 +1,177 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+// The CCM cipher
+type ccmCipher struct {
+	blockCipher   cipher.Block
+	size, lenSize int
+}
+
+// NewCCM returns a cipher.AEAD implementing the CCM mode specified in
+// RFC 3610 and NIST SP 800-38C wrapping the cipher.Block. CCM is a
+// two pass-scheme AEAD cipher combining CBC-MAC and the CTR mode.
+// The block size of the cipher must be 128 bit (like AES or Camellia).
+// The tagsize argument specifies the number of bytes of the auth. tag
+// and must be 4, 6, 8, 10, 12, 14 or 16. The lensize argument (L) specifies
+// the number of bytes used to encode the length of the plaintext and must
+// be between 2 and 8. The nonce size is 15 - lensize bytes.
+func NewCCM(c cipher.Block, tagsize, lensize int) (cipher.AEAD, error) {
+	if c == nil {
+		return nil, errors.New("the cipher.Block must not be nil")
+	}
+	if c.BlockSize() != 16 {
+		return nil, errors.New("cipher block size not supported")
+	}
+	if tagsize < 4 || tagsize > 16 || tagsize%2 != 0 {
+		return nil, errors.New("tagSize must be 4, 6, 8, 10, 12, 14 or 16")
+	}
+	if lensize < 2 || lensize > 8 {
+		return nil, errors.New("lensize must between 2 and 8")
+	}
+	return &ccmCipher{
+		blockCipher: c,
+		size:        tagsize,
+		lenSize:     lensize,
+	}, nil
+}
+
+func (c *ccmCipher) NonceSize() int { return 15 - c.lenSize }
+
+func (c *ccmCipher) Overhead() int { return c.size }
+
+func (c *ccmCipher) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if n := len(nonce); n != c.NonceSize() {
+		panic(crypto.NonceSizeError(n))
+	}
+	if !c.validLength(len(plaintext)) {
+		panic("plaintext too large for the lensize")
+	}
+	ret, out := sliceForAppend(dst, len(plaintext)+c.size)
+
+	tag := c.cbcMac(nonce, plaintext, additionalData)
+	s0 := c.ctrCrypt(out, plaintext, nonce)
+	crypto.XOR(out[len(plaintext):], tag[:c.size], s0)
+	return ret
+}
+
+func (c *ccmCipher) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if n := len(nonce); n != c.NonceSize() {
+		return nil, crypto.NonceSizeError(n)
+	}
+	if len(ciphertext) < c.size || !c.validLength(len(ciphertext)-c.size) {
+		return nil, crypto.AuthenticationError{}
+	}
+
+	hash := make([]byte, c.size)
+	copy(hash, ciphertext[len(ciphertext)-c.size:])
+	ciphertext = ciphertext[:len(ciphertext)-c.size]
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+
+	s0 := c.ctrCrypt(out, ciphertext, nonce)
+	crypto.XOR(hash, hash, s0)
+
+	tag := c.cbcMac(nonce, out, additionalData)
+	if subtle.ConstantTimeCompare(tag[:c.size], hash) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, crypto.AuthenticationError{}
+	}
+	return ret, nil
+}
+
+// validLength returns true if n can be encoded with lenSize bytes.
+func (c *ccmCipher) validLength(n int) bool {
+	return c.lenSize >= 8 || uint64(n) < 1<<uint(8*c.lenSize)
+}
+
+// cbcMac computes the (untruncated) CBC-MAC of the formatted
+// nonce, additional data and plaintext.
+func (c *ccmCipher) cbcMac(nonce, plaintext, additionalData []byte) []byte {
+	mac := make([]byte, 16)
+
+	// B_0
+	mac[0] = byte(((c.size-2)/2)<<3 | (c.lenSize - 1))
+	if len(additionalData) > 0 {
+		mac[0] |= 1 << 6
+	}
+	copy(mac[1:], nonce)
+	for i, n := 15, uint64(len(plaintext)); i > 15-c.lenSize; i-- {
+		mac[i] = byte(n)
+		n >>= 8
+	}
+	c.blockCipher.Encrypt(mac, mac)
+
+	if n := uint64(len(additionalData)); n > 0 {
+		var block [16]byte
+		var off int
+		switch {
+		case n < 1<<16-1<<8:
+			block[0], block[1] = byte(n>>8), byte(n)
+			off = 2
+		case n < 1<<32:
+			block[0], block[1] = 0xff, 0xfe
+			for i := 0; i < 4; i++ {
+				block[2+i] = byte(n >> uint(24-8*i))
+			}
+			off = 6
+		default:
+			block[0], block[1] = 0xff, 0xff
+			for i := 0; i < 8; i++ {
+				block[2+i] = byte(n >> uint(56-8*i))
+			}
+			off = 10
+		}
+		k := copy(block[off:], additionalData)
+		crypto.XOR(mac, mac, block[:])
+		c.blockCipher.Encrypt(mac, mac)
+		c.cbcUpdate(mac, additionalData[k:])
+	}
+	c.cbcUpdate(mac, plaintext)
+	return mac
+}
+
+// cbcUpdate processes the (zero padded) msg with CBC-MAC.
+func (c *ccmCipher) cbcUpdate(mac, msg []byte) {
+	for len(msg) > 0 {
+		n := crypto.XOR(mac, mac, msg)
+		msg = msg[n:]
+		c.blockCipher.Encrypt(mac, mac)
+	}
+}
+
+// ctrCrypt encrypts the bytes in src with the CTR mode and writes the
+// ciphertext into dst. The first key stream block (S_0), which is used
+// to encrypt the tag, is returned.
+func (c *ccmCipher) ctrCrypt(dst, src, nonce []byte) []byte {
+	var ctr, block [16]byte
+	ctr[0] = byte(c.lenSize - 1)
+	copy(ctr[1:], nonce)
+
+	s0 := make([]byte, 16)
+	c.blockCipher.Encrypt(s0, ctr[:])
+
+	for len(src) > 0 {
+		for k := 15; k > 15-c.lenSize; k-- {
+			ctr[k]++
+			if ctr[k] != 0 {
+				break
+			}
+		}
+		c.blockCipher.Encrypt(block[:], ctr[:])
+		n := crypto.XOR(dst, src, block[:])
+		dst, src = dst[n:], src[n:]
+	}
+	return s0
+}
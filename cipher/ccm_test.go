@@ -0,0 +1,373 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"testing"
+
+	"github.com/enceve/crypto/camellia"
+)
+
+// CCM-AES test vectors from RFC 3610 (Packet Vector #1 - #12) and
+// NIST SP 800-38C (Appendix C, Example 1 - 3)
+var ccmVectors = []struct {
+	key, nonce, data, msg string
+	ciphertext            string
+	tagSize               int
+}{
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "00000003020100A0A1A2A3A4A5",
+		data:       "0001020304050607",
+		msg:        "08090A0B0C0D0E0F101112131415161718191A1B1C1D1E",
+		ciphertext: "588C979A61C663D2F066D0C2C0F989806D5F6B61DAC38417E8D12CFDF926E0",
+		tagSize:    8,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "00000004030201A0A1A2A3A4A5",
+		data:       "0001020304050607",
+		msg:        "08090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		ciphertext: "72C91A36E135F8CF291CA894085C87E3CC15C439C9E43A3BA091D56E10400916",
+		tagSize:    8,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "00000005040302A0A1A2A3A4A5",
+		data:       "0001020304050607",
+		msg:        "08090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F20",
+		ciphertext: "51B1E5F44A197D1DA46B0F8E2D282AE871E838BB64DA8596574ADAA76FBD9FB0C5",
+		tagSize:    8,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "00000006050403A0A1A2A3A4A5",
+		data:       "000102030405060708090A0B",
+		msg:        "0C0D0E0F101112131415161718191A1B1C1D1E",
+		ciphertext: "A28C6865939A9A79FAAA5C4C2A9D4A91CDAC8C96C861B9C9E61EF1",
+		tagSize:    8,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "00000007060504A0A1A2A3A4A5",
+		data:       "000102030405060708090A0B",
+		msg:        "0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		ciphertext: "DCF1FB7B5D9E23FB9D4E131253658AD86EBDCA3E51E83F077D9C2D93",
+		tagSize:    8,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "00000008070605A0A1A2A3A4A5",
+		data:       "000102030405060708090A0B",
+		msg:        "0C0D0E0F101112131415161718191A1B1C1D1E1F20",
+		ciphertext: "6FC1B011F006568B5171A42D953D469B2570A4BD87405A0443AC91CB94",
+		tagSize:    8,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "00000009080706A0A1A2A3A4A5",
+		data:       "0001020304050607",
+		msg:        "08090A0B0C0D0E0F101112131415161718191A1B1C1D1E",
+		ciphertext: "0135D1B2C95F41D5D1D4FEC185D166B8094E999DFED96C048C56602C97ACBB7490",
+		tagSize:    10,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "0000000A090807A0A1A2A3A4A5",
+		data:       "0001020304050607",
+		msg:        "08090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		ciphertext: "7B75399AC0831DD2F0BBD75879A2FD8F6CAE6B6CD9B7DB24C17B4433F434963F34B4",
+		tagSize:    10,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "0000000B0A0908A0A1A2A3A4A5",
+		data:       "0001020304050607",
+		msg:        "08090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F20",
+		ciphertext: "82531A60CC24945A4B8279181AB5C84DF21CE7F9B73F42E197EA9C07E56B5EB17E5F4E",
+		tagSize:    10,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "0000000C0B0A09A0A1A2A3A4A5",
+		data:       "000102030405060708090A0B",
+		msg:        "0C0D0E0F101112131415161718191A1B1C1D1E",
+		ciphertext: "07342594157785152B074098330ABB141B947B566AA9406B4D999988DD",
+		tagSize:    10,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "0000000D0C0B0AA0A1A2A3A4A5",
+		data:       "000102030405060708090A0B",
+		msg:        "0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		ciphertext: "676BB20380B0E301E8AB79590A396DA78B834934F53AA2E9107A8B6C022C",
+		tagSize:    10,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "0000000E0D0C0BA0A1A2A3A4A5",
+		data:       "000102030405060708090A0B",
+		msg:        "0C0D0E0F101112131415161718191A1B1C1D1E1F20",
+		ciphertext: "C0FFA0D6F05BDB67F24D43A4338D2AA4BED7B20E43CD1AA31662E7AD65D6DB",
+		tagSize:    10,
+	},
+	{
+		key:        "404142434445464748494A4B4C4D4E4F",
+		nonce:      "10111213141516",
+		data:       "0001020304050607",
+		msg:        "20212223",
+		ciphertext: "7162015B4DAC255D",
+		tagSize:    4,
+	},
+	{
+		key:        "404142434445464748494A4B4C4D4E4F",
+		nonce:      "1011121314151617",
+		data:       "000102030405060708090A0B0C0D0E0F",
+		msg:        "202122232425262728292A2B2C2D2E2F",
+		ciphertext: "D2A1F0E051EA5F62081A7792073D593D1FC64FBFACCD",
+		tagSize:    6,
+	},
+	{
+		key:        "404142434445464748494A4B4C4D4E4F",
+		nonce:      "101112131415161718191A1B",
+		data:       "000102030405060708090A0B0C0D0E0F10111213",
+		msg:        "202122232425262728292A2B2C2D2E2F3031323334353637",
+		ciphertext: "E3B201A9F5B71A7A9B1CEAECCD97E70B6176AAD9A4428AA5484392FBC1B09951",
+		tagSize:    8,
+	},
+}
+
+func TestCCMVectors(t *testing.T) {
+	for i, v := range ccmVectors {
+		key, nonce := fromHex(v.key), fromHex(v.nonce)
+		data, msg := fromHex(v.data), fromHex(v.msg)
+		ciphertext := fromHex(v.ciphertext)
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create AES instance: %s", i, err)
+		}
+		ccm, err := NewCCM(block, v.tagSize, 15-len(nonce))
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create CCM instance: %s", i, err)
+		}
+
+		buf := ccm.Seal(nil, nonce, msg, data)
+		if !bytes.Equal(buf, ciphertext) {
+			t.Fatalf("TestVector %d Seal failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(ciphertext))
+		}
+
+		buf, err = ccm.Open(buf[:0], nonce, buf, data)
+		if err != nil {
+			t.Fatalf("TestVector %d: Open failed: %s", i, err)
+		}
+		if !bytes.Equal(buf, msg) {
+			t.Fatalf("TestVector %d Open failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(msg))
+		}
+	}
+}
+
+// CCM-Camellia test vectors from RFC 5528 (Packet Vector #1 - #12).
+// The ciphertexts of #10 and #12 were computed from the RFC inputs with
+// the CCM mode of libgcrypt 1.10.1, which reproduces all other vectors.
+var ccmCamelliaVectors = []struct {
+	key, nonce, data, msg string
+	ciphertext            string
+	tagSize               int
+}{
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "00000003020100A0A1A2A3A4A5",
+		data:       "0001020304050607",
+		msg:        "08090A0B0C0D0E0F101112131415161718191A1B1C1D1E",
+		ciphertext: "BA737185E719310492F38A5F1251DA55FAFBC949848A0DFCAECE746B3DB9AD",
+		tagSize:    8,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "00000004030201A0A1A2A3A4A5",
+		data:       "0001020304050607",
+		msg:        "08090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		ciphertext: "5D2564BF8EAFE1D99526EC016D1BF0424CFBD2CD62848F3360B2295DF24283E8",
+		tagSize:    8,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "00000005040302A0A1A2A3A4A5",
+		data:       "0001020304050607",
+		msg:        "08090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F20",
+		ciphertext: "81F663D6C7787817F9203608B982AD15DC2BBD87D756F79204F551D6682F23AA46",
+		tagSize:    8,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "00000006050403A0A1A2A3A4A5",
+		data:       "000102030405060708090A0B",
+		msg:        "0C0D0E0F101112131415161718191A1B1C1D1E",
+		ciphertext: "CAEF1E827211B08F7BD90F08C77288C070A4A08B3A933A63E497A0",
+		tagSize:    8,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "00000007060504A0A1A2A3A4A5",
+		data:       "000102030405060708090A0B",
+		msg:        "0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		ciphertext: "2AD3BAD94FC52E92BE438E827C1023B96A8A77258FA17BA7F331DB09",
+		tagSize:    8,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "00000008070605A0A1A2A3A4A5",
+		data:       "000102030405060708090A0B",
+		msg:        "0C0D0E0F101112131415161718191A1B1C1D1E1F20",
+		ciphertext: "FEA5480BA53FA8D3C34422AACE4DE67FFA3BB73BABAB36A1EE4FE0FE28",
+		tagSize:    8,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "00000009080706A0A1A2A3A4A5",
+		data:       "0001020304050607",
+		msg:        "08090A0B0C0D0E0F101112131415161718191A1B1C1D1E",
+		ciphertext: "54532026E54C119A8D36D9EC6E1ED97416C8708C4B5C2CACAFA3BCCF7A4EBF9573",
+		tagSize:    10,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "0000000A090807A0A1A2A3A4A5",
+		data:       "0001020304050607",
+		msg:        "08090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		ciphertext: "8AD19B001A87D148F4D92BEF34525CCCE3A63C6512A6F5757388E4913EF14701F441",
+		tagSize:    10,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "0000000B0A0908A0A1A2A3A4A5",
+		data:       "0001020304050607",
+		msg:        "08090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F20",
+		ciphertext: "5DB08D62407E6E31D60F9CA2C60474219AC0BE50C0D4A5778794D6E230CD25C9FEBF87",
+		tagSize:    10,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "0000000C0B0A09A0A1A2A3A4A5",
+		data:       "000102030405060708090A0B",
+		msg:        "0C0D0E0F101112131415161718191A1B1C1D1E",
+		ciphertext: "DB118CCEC1B8761C877CD8963A67D6F3BBBC5CD09299EB11F312F23237",
+		tagSize:    10,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "0000000D0C0B0AA0A1A2A3A4A5",
+		data:       "000102030405060708090A0B",
+		msg:        "0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		ciphertext: "7CC83D8DC49103525B483DC5CA7EA9AB812B7056079DAFFADA16CCCF2C4E",
+		tagSize:    10,
+	},
+	{
+		key:        "C0C1C2C3C4C5C6C7C8C9CACBCCCDCECF",
+		nonce:      "0000000E0D0C0BA0A1A2A3A4A5",
+		data:       "000102030405060708090A0B",
+		msg:        "0C0D0E0F101112131415161718191A1B1C1D1E1F20",
+		ciphertext: "2CD35B8820D23E7AA351B0E92FC79367238B2CC748CBB94C2947793D64AF75",
+		tagSize:    10,
+	},
+}
+
+func TestCCMCamellia(t *testing.T) {
+	for i, v := range ccmCamelliaVectors {
+		key, nonce := fromHex(v.key), fromHex(v.nonce)
+		data, msg := fromHex(v.data), fromHex(v.msg)
+		ciphertext := fromHex(v.ciphertext)
+
+		block, err := camellia.NewCipher(key)
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create Camellia instance: %s", i, err)
+		}
+		ccm, err := NewCCM(block, v.tagSize, 15-len(nonce))
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create CCM instance: %s", i, err)
+		}
+
+		buf := ccm.Seal(nil, nonce, msg, data)
+		if !bytes.Equal(buf, ciphertext) {
+			t.Fatalf("TestVector %d Seal failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(ciphertext))
+		}
+
+		buf, err = ccm.Open(buf[:0], nonce, buf, data)
+		if err != nil {
+			t.Fatalf("TestVector %d: Open failed: %s", i, err)
+		}
+		if !bytes.Equal(buf, msg) {
+			t.Fatalf("TestVector %d Open failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(msg))
+		}
+	}
+}
+
+func testCCM(t *testing.T, name string, block cipher.Block) {
+	msg, data := make([]byte, 100), make([]byte, 300)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	for lensize := 2; lensize <= 8; lensize++ {
+		for _, tagsize := range []int{4, 8, 12, 16} {
+			ccm, err := NewCCM(block, tagsize, lensize)
+			if err != nil {
+				t.Fatalf("%s: Failed to create CCM instance: %s", name, err)
+			}
+			nonce := make([]byte, ccm.NonceSize())
+			nonce[0] = byte(tagsize)
+			for i := range msg {
+				ciphertext := ccm.Seal(nil, nonce, msg[:i], data[:3*i])
+				plaintext, err := ccm.Open(nil, nonce, ciphertext, data[:3*i])
+				if err != nil {
+					t.Fatalf("%s: Open failed: %s", name, err)
+				}
+				if !bytes.Equal(plaintext, msg[:i]) {
+					t.Fatalf("%s: Open failed:\nFound   : %s\nExpected: %s", name, hex.EncodeToString(plaintext), hex.EncodeToString(msg[:i]))
+				}
+
+				if tagsize < 8 {
+					continue // forgeries are likely for short tags
+				}
+				ciphertext[i/2] ^= 0x40
+				if _, err = ccm.Open(nil, nonce, ciphertext, data[:3*i]); err == nil {
+					t.Fatalf("%s: Open accepted modified ciphertext", name)
+				}
+			}
+		}
+	}
+}
+
+func TestCCM(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES instance: %s", err)
+	}
+	testCCM(t, "AES", block)
+
+	c, err := camellia.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create Camellia instance: %s", err)
+	}
+	testCCM(t, "Camellia", c)
+}
+
+func TestNewCCM(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES instance: %s", err)
+	}
+	badSizes := []struct{ tagSize, lenSize int }{
+		{2, 2}, {5, 2}, {18, 2}, {16, 1}, {16, 9},
+	}
+	for i, v := range badSizes {
+		if _, err = NewCCM(block, v.tagSize, v.lenSize); err == nil {
+			t.Fatalf("Test %d: NewCCM accepted tag size %d and length size %d", i, v.tagSize, v.lenSize)
+		}
+	}
+}
@@ -22,12 +22,26 @@ const (
 	cTag = 0x2 // The ciphertext tag constant
 )
 
+// EAX is a cipher.AEAD implementing the EAX mode. EAX is defined
+// for nonces of any length, so beside the cipher.AEAD methods,
+// which only accept nonces of NonceSize() bytes, EAX can process
+// nonces of any length.
+type EAX interface {
+	cipher.AEAD
+
+	// SealAnyNonce is like Seal, but accepts nonces of any length.
+	SealAnyNonce(dst, nonce, plaintext, additionalData []byte) []byte
+
+	// OpenAnyNonce is like Open, but accepts nonces of any length.
+	OpenAnyNonce(dst, nonce, ciphertext, additionalData []byte) ([]byte, error)
+}
+
 // The EAX cipher
 type eaxCipher struct {
-	blockCipher cipher.Block
-	ctr, block  []byte
-	mac         hash.Hash
-	size        int
+	blockCipher     cipher.Block
+	ctr, block      []byte
+	mac             hash.Hash
+	nonceSize, size int
 }
 
 // NewEAX returns a cipher.AEAD wrapping the cipher.Block.
@@ -35,13 +49,30 @@ type eaxCipher struct {
 // For authentication EAX uses CMac (OMAC1).
 // The tagsize argument specifies the number of bytes of the auth. tag
 // and must be between 1 and the block size of the cipher.
+// The nonce size is the block size of the cipher. The returned
+// cipher.AEAD also implements the EAX interface.
 // This function returns a non-nil error if the given block cipher
 // is not supported by CMac (see crypto/cmac for details)
 func NewEAX(c cipher.Block, tagsize int) (cipher.AEAD, error) {
+	if c == nil {
+		return nil, errors.New("the cipher.Block must not be nil")
+	}
+	return NewEAXWithNonceSize(c, c.BlockSize(), tagsize)
+}
+
+// NewEAXWithNonceSize returns a EAX wrapping the cipher.Block.
+// It is like NewEAX, but the noncesize argument specifies the
+// length of the nonce (in bytes) accepted by Seal and Open.
+// The noncesize must be greater than 0 - e.g. 12 for protocols
+// using 96 bit nonces.
+func NewEAXWithNonceSize(c cipher.Block, noncesize, tagsize int) (EAX, error) {
 	m, err := cmac.New(c)
 	if err != nil {
 		return nil, err
 	}
+	if noncesize < 1 {
+		return nil, errors.New("noncesize must be greater than 0")
+	}
 	if tagsize < 1 || tagsize > c.BlockSize() {
 		return nil, errors.New("tagSize must between 1 and BlockSize() of the given cipher")
 	}
@@ -50,21 +81,31 @@ func NewEAX(c cipher.Block, tagsize int) (cipher.AEAD, error) {
 		mac:         m,
 		ctr:         make([]byte, c.BlockSize()),
 		block:       make([]byte, c.BlockSize()),
+		nonceSize:   noncesize,
 		size:        tagsize,
 	}, nil
 }
 
-func (c *eaxCipher) NonceSize() int { return c.blockCipher.BlockSize() }
+func (c *eaxCipher) NonceSize() int { return c.nonceSize }
 
 func (c *eaxCipher) Overhead() int { return c.size }
 
 func (c *eaxCipher) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
-	if n := len(nonce); n != c.blockCipher.BlockSize() {
+	if n := len(nonce); n != c.nonceSize {
 		panic(crypto.NonceSizeError(n))
 	}
-	if len(dst) < len(plaintext) {
-		panic("dst buffer to small")
+	return c.SealAnyNonce(dst, nonce, plaintext, additionalData)
+}
+
+func (c *eaxCipher) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if n := len(nonce); n != c.nonceSize {
+		return nil, crypto.NonceSizeError(n)
 	}
+	return c.OpenAnyNonce(dst, nonce, ciphertext, additionalData)
+}
+
+func (c *eaxCipher) SealAnyNonce(dst, nonce, plaintext, additionalData []byte) []byte {
+	ret, out := sliceForAppend(dst, len(plaintext)+c.size)
 
 	tag := make([]byte, c.mac.BlockSize())
 
@@ -85,32 +126,26 @@ func (c *eaxCipher) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
 	// encrypt
 	n := len(plaintext)
 	copy(c.ctr, authNonce) // set the ctr-mode nonce
-	c.ctrCrypt(dst, plaintext)
+	c.ctrCrypt(out[:n], plaintext)
 
 	// process ciphertext
 	tag[len(tag)-1] = cTag
 	c.mac.Write(tag)
-	c.mac.Write(dst[:n])
+	c.mac.Write(out[:n])
 	tag = c.mac.Sum(tag[:0])
 	c.mac.Reset()
 
 	for i := range tag {
 		tag[i] ^= authData[i] ^ authNonce[i]
 	}
-	return append(dst[:n], tag[:c.size]...)
+	copy(out[n:], tag[:c.size])
+	return ret
 }
 
-func (c *eaxCipher) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
-	if n := len(nonce); n != c.blockCipher.BlockSize() {
-		return nil, crypto.NonceSizeError(n)
-	}
+func (c *eaxCipher) OpenAnyNonce(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
 	if len(ciphertext) < c.size {
 		return nil, crypto.AuthenticationError{}
 	}
-	if len(dst) < len(ciphertext)-c.mac.Size() {
-		panic("dst buffer to small")
-	}
-
 	hash := ciphertext[len(ciphertext)-c.size:]
 	ciphertext = ciphertext[:len(ciphertext)-c.size]
 
@@ -146,11 +181,11 @@ func (c *eaxCipher) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte,
 	}
 
 	// decrypt
-	n := len(ciphertext)
+	ret, out := sliceForAppend(dst, len(ciphertext))
 	copy(c.ctr, authNonce) // set the ctr-mode nonce
-	c.ctrCrypt(dst, ciphertext)
+	c.ctrCrypt(out, ciphertext)
 
-	return dst[:n], nil
+	return ret, nil
 }
 
 // ctrCrypt encrypts the bytes in src with the CTR mode and writes
@@ -158,7 +193,7 @@ func (c *eaxCipher) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte,
 func (c *eaxCipher) ctrCrypt(dst, src []byte) {
 	length := len(src)
 	bs := c.blockCipher.BlockSize()
-	n := length & (^(length - bs))
+	n := length - length%bs
 
 	for i := 0; i < n; i += bs {
 		j := i + bs
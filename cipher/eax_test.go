@@ -4,10 +4,196 @@
 package cipher
 
 import (
+	"bytes"
 	"crypto/aes"
+	"encoding/hex"
 	"testing"
 )
 
+// EAX-AES test vectors with nonces of different lengths.
+// The first vector is taken from http://web.cs.ucdavis.edu/~rogaway/papers/eax.pdf
+// the others were computed with the EAX construction on top of
+// the AES-CMAC and AES-CTR implementations of OpenSSL.
+var eaxNonceVectors = []testVector{
+	testVector{
+		msg:        "CA40D7446E545FFAED3BD12A740A659FFBBB3CEAB7",
+		key:        "8395FCF1E95BEBD697BD010BC766AAC3",
+		nonce:      "22E7ADD93CFC6393C57EC0B3C17D6B44",
+		data:       "126735FCC320D25A",
+		ciphertext: "CB8920F87A6C75CFF39627B56E3ED197C552D295A7CFC46AFC253B4652B1AF3795B124AB6E",
+		macSize:    16,
+	},
+	testVector{
+		msg:        "CA40D7446E545FFAED3BD12A740A659FFBBB3CEAB7",
+		key:        "8395FCF1E95BEBD697BD010BC766AAC3",
+		nonce:      "22E7ADD93CFC6393C57EC0B3",
+		data:       "126735FCC320D25A",
+		ciphertext: "6AD98D5CC056DC6DEF154660D9A902EA250FF5067AD6C7EBFB5D2CF113354F96CA388FCE01",
+		macSize:    16,
+	},
+	testVector{
+		msg:        "CA40D7446E545FFAED3BD12A740A659FFBBB3CEAB7",
+		key:        "8395FCF1E95BEBD697BD010BC766AAC3",
+		nonce:      "22E7ADD93CFC6393C57EC0B3",
+		data:       "",
+		ciphertext: "6AD98D5CC056DC6DEF154660D9A902EA250FF5067A1E9C932A8069B7AA",
+		macSize:    8,
+	},
+	testVector{
+		msg:        "CA40D7446E545FFAED3BD12A740A659FFBBB3CEAB7",
+		key:        "8395FCF1E95BEBD697BD010BC766AAC3",
+		nonce:      "22",
+		data:       "126735FCC320D25A",
+		ciphertext: "9303A2616DC85DACE4F03CDEFEF5338949B22ACBC1C2F5AD3A9D2DC8C0D5DFBC701654E975",
+		macSize:    16,
+	},
+	testVector{
+		msg:        "CA40D7446E545FFAED3BD12A740A659FFBBB3CEAB7",
+		key:        "8395FCF1E95BEBD697BD010BC766AAC3",
+		nonce:      "22E7ADD93CFC6393C57EC0B3C17D6B4422E7ADD93CFC6393C57EC0B3C17D6B44",
+		data:       "126735FCC320D25A",
+		ciphertext: "A2B53DE7BD6026B4643F3037D449EE217DDB95B6A4128F206354F411185858EB37",
+		macSize:    12,
+	},
+	testVector{
+		msg: "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F202122232425262728292A2B2C2D2E2F3031" +
+			"32333435363738393A3B3C3D3E3F404142434445464748494A4B4C4D4E4F505152535455565758595A5B5C5D5E5F60616263",
+		key:   "000102030405060708090A0B0C0D0E0F",
+		nonce: "000102030405060708090A0B",
+		data:  "0001020304",
+		ciphertext: "8CCC8D3C76B7208195A92E1ED771572BF007859EE419F1D25F920D47AC170FD6300F64BBA99B4755B5E1BA8A949B8B39152AD9D1DD55B4099FEA" +
+			"381D30F6FF2EA6554C0D8713B9DDA3CD07AE290C8C92F419A47D7408372B2B0460321D84838383DEB59E5260E34361DF8F9253BFF5C77E0FB114",
+		macSize: 16,
+	},
+}
+
+func TestEAXNonceSize(t *testing.T) {
+	for i, v := range eaxNonceVectors {
+		key, nonce := fromHex(v.key), fromHex(v.nonce)
+		msg, data := fromHex(v.msg), fromHex(v.data)
+		ciphertext := fromHex(v.ciphertext)
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create AES instance: %s", i, err)
+		}
+		eax, err := NewEAXWithNonceSize(block, len(nonce), v.macSize)
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create EAX instance: %s", i, err)
+		}
+		if n := eax.NonceSize(); n != len(nonce) {
+			t.Fatalf("TestVector %d: NonceSize() returned %d - expected %d", i, n, len(nonce))
+		}
+
+		buf := eax.Seal(nil, nonce, msg, data)
+		if !bytes.Equal(buf, ciphertext) {
+			t.Fatalf("TestVector %d Seal failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(ciphertext))
+		}
+		buf, err = eax.Open(buf[:0], nonce, buf, data)
+		if err != nil {
+			t.Fatalf("TestVector %d: Open failed: %s", i, err)
+		}
+		if !bytes.Equal(buf, msg) {
+			t.Fatalf("TestVector %d Open failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(msg))
+		}
+	}
+}
+
+func TestEAXAnyNonce(t *testing.T) {
+	block, err := aes.NewCipher(fromHex(eaxNonceVectors[0].key))
+	if err != nil {
+		t.Fatalf("Failed to create AES instance: %s", err)
+	}
+	c, err := NewEAX(block, 16)
+	if err != nil {
+		t.Fatalf("Failed to create EAX instance: %s", err)
+	}
+	eax, ok := c.(EAX)
+	if !ok {
+		t.Fatal("NewEAX does not return a EAX")
+	}
+
+	for i, v := range eaxNonceVectors {
+		if v.macSize != 16 || v.key != eaxNonceVectors[0].key {
+			continue
+		}
+		nonce, msg, data := fromHex(v.nonce), fromHex(v.msg), fromHex(v.data)
+		ciphertext := fromHex(v.ciphertext)
+
+		buf := eax.SealAnyNonce(nil, nonce, msg, data)
+		if !bytes.Equal(buf, ciphertext) {
+			t.Fatalf("TestVector %d SealAnyNonce failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(ciphertext))
+		}
+		buf, err = eax.OpenAnyNonce(buf[:0], nonce, buf, data)
+		if err != nil {
+			t.Fatalf("TestVector %d: OpenAnyNonce failed: %s", i, err)
+		}
+		if !bytes.Equal(buf, msg) {
+			t.Fatalf("TestVector %d OpenAnyNonce failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(msg))
+		}
+	}
+
+	if _, err = NewEAXWithNonceSize(block, 0, 16); err == nil {
+		t.Fatal("NewEAXWithNonceSize accepted a nonce size of 0")
+	}
+}
+
+func TestEAXAliasing(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES instance: %s", err)
+	}
+	c, err := NewEAXWithNonceSize(block, 12, 16)
+	if err != nil {
+		t.Fatalf("Failed to create EAX instance: %s", err)
+	}
+
+	nonce, msg, data := make([]byte, 12), make([]byte, 100), make([]byte, 20)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	ref := c.Seal(nil, nonce, msg, data)
+
+	// Seal must append to dst - e.g. prepend the nonce
+	out := c.Seal(nonce, nonce, msg, data)
+	if !bytes.Equal(out[:len(nonce)], nonce) || !bytes.Equal(out[len(nonce):], ref) {
+		t.Fatal("Seal does not append the ciphertext to dst")
+	}
+
+	// exact overlap of dst and src
+	buf := make([]byte, len(msg), len(msg)+c.Overhead())
+	copy(buf, msg)
+	ciphertext := c.Seal(buf[:0], nonce, buf, data)
+	if !bytes.Equal(ciphertext, ref) {
+		t.Fatal("in-place Seal produced wrong ciphertext")
+	}
+	if &ciphertext[0] != &buf[0] {
+		t.Fatal("in-place Seal allocated a new slice")
+	}
+	plaintext, err := c.Open(ciphertext[:0], nonce, ciphertext, data)
+	if err != nil {
+		t.Fatalf("in-place Open failed: %s", err)
+	}
+	if !bytes.Equal(plaintext, msg) {
+		t.Fatal("in-place Open produced wrong plaintext")
+	}
+
+	// reuse of dst[:0]
+	dst := make([]byte, 0, len(ref))
+	for i := 0; i < 3; i++ {
+		dst = c.Seal(dst[:0], nonce, msg, data)
+		if !bytes.Equal(dst, ref) {
+			t.Fatalf("Seal with dst[:0] failed in iteration %d", i)
+		}
+		if dst, err = c.Open(dst[:0], nonce, dst, data); err != nil {
+			t.Fatalf("Open with dst[:0] failed in iteration %d: %s", i, err)
+		}
+		if !bytes.Equal(dst, msg) {
+			t.Fatalf("Open with dst[:0] failed in iteration %d", i)
+		}
+	}
+}
+
 // Benchmarks
 
 func BenchmarkSeal_64B(b *testing.B) {
@@ -25,7 +211,7 @@ func BenchmarkSeal_64B(b *testing.B) {
 	data := make([]byte, 8)
 	b.SetBytes(64)
 	for i := 0; i < b.N; i++ {
-		dst = c.Seal(dst, nonce, msg, data)
+		dst = c.Seal(dst[:0], nonce, msg, data)
 	}
 }
 
@@ -44,7 +230,7 @@ func BenchmarkSeal_1K(b *testing.B) {
 	data := make([]byte, 8)
 	b.SetBytes(1024)
 	for i := 0; i < b.N; i++ {
-		dst = c.Seal(dst, nonce, msg, data)
+		dst = c.Seal(dst[:0], nonce, msg, data)
 	}
 }
 
@@ -62,10 +248,10 @@ func BenchmarkOpen_64B(b *testing.B) {
 	dst := make([]byte, len(msg))
 	ciphertext := make([]byte, len(msg)+aes.BlockSize)
 	data := make([]byte, 8)
-	ciphertext = c.Seal(ciphertext, nonce, msg, data)
+	ciphertext = c.Seal(ciphertext[:0], nonce, msg, data)
 	b.SetBytes(64)
 	for i := 0; i < b.N; i++ {
-		dst, _ = c.Open(dst, nonce, ciphertext, data)
+		dst, _ = c.Open(dst[:0], nonce, ciphertext, data)
 	}
 }
 
@@ -83,9 +269,9 @@ func BenchmarkOpen_1K(b *testing.B) {
 	dst := make([]byte, len(msg))
 	ciphertext := make([]byte, len(msg)+aes.BlockSize)
 	data := make([]byte, 8)
-	ciphertext = c.Seal(ciphertext, nonce, msg, data)
+	ciphertext = c.Seal(ciphertext[:0], nonce, msg, data)
 	b.SetBytes(1024)
 	for i := 0; i < b.N; i++ {
-		dst, _ = c.Open(dst, nonce, ciphertext, data)
+		dst, _ = c.Open(dst[:0], nonce, ciphertext, data)
 	}
 }
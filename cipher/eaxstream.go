@@ -0,0 +1,250 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"hash"
+	"io"
+	"io/ioutil"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/cmac"
+)
+
+// The size of the internal buffer of the EAX encrypter and decrypter.
+const eaxStreamBufSize = 4096
+
+var errEAXClosed = errors.New("the EAX encrypter is closed")
+
+// eaxStream holds the incremental state of the
+// CTR mode and the CMac of the ciphertext.
+type eaxStream struct {
+	ctr     cipher.Stream
+	mac     hash.Hash
+	tagMask []byte // the xor of the nonce and additional data MAC
+	size    int
+}
+
+func newEAXStream(c cipher.Block, tagsize int, nonce, additionalData []byte) (*eaxStream, error) {
+	m, err := cmac.New(c)
+	if err != nil {
+		return nil, err
+	}
+	if tagsize < 1 || tagsize > c.BlockSize() {
+		return nil, errors.New("tagSize must between 1 and BlockSize() of the given cipher")
+	}
+
+	tag := make([]byte, c.BlockSize())
+
+	// process nonce
+	tag[len(tag)-1] = nTag
+	m.Write(tag)
+	m.Write(nonce)
+	authNonce := m.Sum(nil)
+	m.Reset()
+
+	// process additional data
+	tag[len(tag)-1] = hTag
+	m.Write(tag)
+	m.Write(additionalData)
+	tagMask := m.Sum(nil)
+	m.Reset()
+	crypto.XOR(tagMask, tagMask, authNonce)
+
+	// the ciphertext is processed incrementally
+	tag[len(tag)-1] = cTag
+	m.Write(tag)
+
+	return &eaxStream{
+		ctr:     cipher.NewCTR(c, authNonce),
+		mac:     m,
+		tagMask: tagMask,
+		size:    tagsize,
+	}, nil
+}
+
+// tag returns the auth. tag of the processed ciphertext.
+func (s *eaxStream) tag() []byte {
+	tag := s.mac.Sum(nil)
+	crypto.XOR(tag, tag, s.tagMask)
+	return tag[:s.size]
+}
+
+// NewEAXEncrypter returns an io.WriteCloser encrypting and authenticating
+// all data written to it with EAX and writing the ciphertext to w. EAX is
+// an online scheme, so the length of the plaintext need not be known in advance.
+// Close writes the auth. tag to w - but does not close w - and must be called
+// after all data is written. The ciphertext is compatible to EAX's Seal.
+// The tagsize argument specifies the number of bytes of the auth. tag
+// and must be between 1 and the block size of the cipher.
+// This function returns a non-nil error if the given block cipher
+// is not supported by CMac (see crypto/cmac for details)
+func NewEAXEncrypter(c cipher.Block, tagsize int, nonce, additionalData []byte, w io.Writer) (io.WriteCloser, error) {
+	s, err := newEAXStream(c, tagsize, nonce, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	return &eaxEncrypter{
+		eaxStream: s,
+		w:         w,
+		buf:       make([]byte, eaxStreamBufSize),
+	}, nil
+}
+
+type eaxEncrypter struct {
+	*eaxStream
+	w      io.Writer
+	buf    []byte
+	closed bool
+	err    error
+}
+
+// Write encrypts p and writes the ciphertext to the underlying writer.
+// After the underlying writer returned an error the ciphertext is
+// incomplete, so the error is returned by all subsequent calls of
+// Write and Close.
+func (e *eaxEncrypter) Write(p []byte) (n int, err error) {
+	if e.closed {
+		return 0, errEAXClosed
+	}
+	if e.err != nil {
+		return 0, e.err
+	}
+	for len(p) > 0 {
+		k := len(p)
+		if k > len(e.buf) {
+			k = len(e.buf)
+		}
+		e.ctr.XORKeyStream(e.buf[:k], p[:k])
+		e.mac.Write(e.buf[:k])
+		if _, err = e.w.Write(e.buf[:k]); err != nil {
+			e.err = err
+			return
+		}
+		n += k
+		p = p[k:]
+	}
+	return
+}
+
+func (e *eaxEncrypter) Close() error {
+	if e.closed {
+		return errEAXClosed
+	}
+	e.closed = true
+	if e.err != nil {
+		return e.err
+	}
+	_, err := e.w.Write(e.tag())
+	return err
+}
+
+// NewEAXDecrypter returns an io.Reader decrypting and verifying the ciphertext
+// read from r. The ciphertext must be produced by EAX's Seal or an EAX
+// encrypter (see NewEAXEncrypter) with the same parameters.
+// The decrypter releases plaintext only after the auth. tag is verified.
+// Therefore the first call of Read reads the entire ciphertext from r and
+// keeps the plaintext in memory. If the verification fails, Read returns
+// a crypto.AuthenticationError. For large streams see NewUnsafeEAXDecrypter.
+func NewEAXDecrypter(c cipher.Block, tagsize int, nonce, additionalData []byte, r io.Reader) (io.Reader, error) {
+	u, err := NewUnsafeEAXDecrypter(c, tagsize, nonce, additionalData, r)
+	if err != nil {
+		return nil, err
+	}
+	return &eaxDecrypter{unsafe: u}, nil
+}
+
+type eaxDecrypter struct {
+	unsafe    io.Reader
+	plaintext *bytes.Reader
+	err       error
+}
+
+func (d *eaxDecrypter) Read(p []byte) (int, error) {
+	if d.plaintext == nil && d.err == nil {
+		plaintext, err := ioutil.ReadAll(d.unsafe)
+		if err != nil {
+			for i := range plaintext {
+				plaintext[i] = 0
+			}
+			d.err = err
+		} else {
+			d.plaintext = bytes.NewReader(plaintext)
+		}
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.plaintext.Read(p)
+}
+
+// NewUnsafeEAXDecrypter returns an io.Reader decrypting and verifying the
+// ciphertext read from r. In contrast to NewEAXDecrypter the unsafe decrypter
+// releases plaintext before the auth. tag is verified - the last Read returns
+// a crypto.AuthenticationError instead of io.EOF, if the verification fails.
+// The caller must not use (and should discard) any plaintext until the
+// unsafe decrypter returns io.EOF.
+func NewUnsafeEAXDecrypter(c cipher.Block, tagsize int, nonce, additionalData []byte, r io.Reader) (io.Reader, error) {
+	s, err := newEAXStream(c, tagsize, nonce, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	return &unsafeEAXDecrypter{
+		eaxStream: s,
+		r:         r,
+		buf:       make([]byte, 0, eaxStreamBufSize+tagsize),
+	}, nil
+}
+
+type unsafeEAXDecrypter struct {
+	*eaxStream
+	r   io.Reader
+	buf []byte // the pending ciphertext - the last size bytes may be the tag
+	eof bool
+	err error
+}
+
+func (d *unsafeEAXDecrypter) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for {
+		if n := len(d.buf) - d.size; n > 0 {
+			if n > len(p) {
+				n = len(p)
+			}
+			d.mac.Write(d.buf[:n])
+			d.ctr.XORKeyStream(p[:n], d.buf[:n])
+			d.buf = d.buf[:copy(d.buf, d.buf[n:])]
+			return n, nil
+		}
+		if d.eof && d.err == nil {
+			d.err = d.verify()
+		}
+		if d.err != nil {
+			return 0, d.err
+		}
+
+		n, err := d.r.Read(d.buf[len(d.buf):cap(d.buf)])
+		d.buf = d.buf[:len(d.buf)+n]
+		if err == io.EOF {
+			d.eof = true
+		} else if err != nil {
+			d.err = err
+		}
+	}
+}
+
+// verify checks the auth. tag at the end of the ciphertext and
+// returns io.EOF on success.
+func (d *unsafeEAXDecrypter) verify() error {
+	if len(d.buf) != d.size || subtle.ConstantTimeCompare(d.tag(), d.buf) != 1 {
+		return crypto.AuthenticationError{}
+	}
+	return io.EOF
+}
@@ -0,0 +1,172 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+	"testing/iotest"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/camellia"
+)
+
+func TestEAXEncrypter(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES instance: %s", err)
+	}
+	nonce, data := make([]byte, 12), []byte("additional data")
+	eax, err := NewEAXWithNonceSize(block, len(nonce), 12)
+	if err != nil {
+		t.Fatalf("Failed to create EAX instance: %s", err)
+	}
+
+	msg := make([]byte, 3*eaxStreamBufSize+100)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	for _, size := range []int{0, 1, 15, 16, 17, 100, eaxStreamBufSize, len(msg)} {
+		for _, chunk := range []int{1, 7, 16, 1000, eaxStreamBufSize + 1} {
+			var buf bytes.Buffer
+			w, err := NewEAXEncrypter(block, 12, nonce, data, &buf)
+			if err != nil {
+				t.Fatalf("Failed to create EAX encrypter: %s", err)
+			}
+			for i := 0; i < size; i += chunk {
+				j := i + chunk
+				if j > size {
+					j = size
+				}
+				if _, err = w.Write(msg[i:j]); err != nil {
+					t.Fatalf("Write failed: %s", err)
+				}
+			}
+			if err = w.Close(); err != nil {
+				t.Fatalf("Close failed: %s", err)
+			}
+			if _, err = w.Write(msg[:1]); err == nil {
+				t.Fatal("Write succeeded after Close")
+			}
+
+			ciphertext := eax.Seal(nil, nonce, msg[:size], data)
+			if !bytes.Equal(buf.Bytes(), ciphertext) {
+				t.Fatalf("Size %d - chunk %d: ciphertext does not match Seal:\nFound   : %s\nExpected: %s", size, chunk, hex.EncodeToString(buf.Bytes()), hex.EncodeToString(ciphertext))
+			}
+		}
+	}
+}
+
+// errWriter fails once n bytes have been written.
+type errWriter struct{ n int }
+
+var errWrite = errors.New("write failed")
+
+func (w *errWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		w.n = 0
+		return 0, errWrite
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestEAXEncrypterWriteError(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES instance: %s", err)
+	}
+	w, err := NewEAXEncrypter(block, 16, make([]byte, 16), nil, &errWriter{n: 10})
+	if err != nil {
+		t.Fatalf("Failed to create EAX encrypter: %s", err)
+	}
+	if _, err = w.Write(make([]byte, 20)); err != errWrite {
+		t.Fatalf("Write returned %v - expected %v", err, errWrite)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err = w.Write(make([]byte, 1)); err != errWrite {
+			t.Fatalf("Write %d after failed Write returned %v - expected %v", i, err, errWrite)
+		}
+	}
+	if err = w.Close(); err != errWrite {
+		t.Fatalf("Close after failed Write returned %v - expected %v", err, errWrite)
+	}
+}
+
+func TestEAXDecrypter(t *testing.T) {
+	block, err := camellia.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create Camellia instance: %s", err)
+	}
+	nonce, data := make([]byte, 16), []byte("additional data")
+	eax, err := NewEAX(block, 16)
+	if err != nil {
+		t.Fatalf("Failed to create EAX instance: %s", err)
+	}
+
+	msg := make([]byte, 2*eaxStreamBufSize+100)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	for _, size := range []int{0, 1, 16, 100, eaxStreamBufSize, len(msg)} {
+		ciphertext := eax.Seal(nil, nonce, msg[:size], data)
+
+		newDecrypters := map[string]func(io.Reader) (io.Reader, error){
+			"safe": func(r io.Reader) (io.Reader, error) {
+				return NewEAXDecrypter(block, 16, nonce, data, r)
+			},
+			"unsafe": func(r io.Reader) (io.Reader, error) {
+				return NewUnsafeEAXDecrypter(block, 16, nonce, data, r)
+			},
+		}
+		for name, newDecrypter := range newDecrypters {
+			for _, oneByte := range []bool{false, true} {
+				var src io.Reader = bytes.NewReader(ciphertext)
+				if oneByte {
+					src = iotest.OneByteReader(src)
+				}
+				r, err := newDecrypter(src)
+				if err != nil {
+					t.Fatalf("Failed to create %s EAX decrypter: %s", name, err)
+				}
+				plaintext, err := ioutil.ReadAll(r)
+				if err != nil {
+					t.Fatalf("Size %d: %s decrypter failed: %s", size, name, err)
+				}
+				if !bytes.Equal(plaintext, msg[:size]) {
+					t.Fatalf("Size %d: %s decrypter returned wrong plaintext", size, name)
+				}
+			}
+
+			// modify the tag
+			ciphertext[len(ciphertext)-1] ^= 1
+			r, err := newDecrypter(bytes.NewReader(ciphertext))
+			if err != nil {
+				t.Fatalf("Failed to create %s EAX decrypter: %s", name, err)
+			}
+			plaintext, err := ioutil.ReadAll(r)
+			if err != (crypto.AuthenticationError{}) {
+				t.Fatalf("Size %d: %s decrypter accepted a modified ciphertext - error: %v", size, name, err)
+			}
+			if name == "safe" && len(plaintext) != 0 {
+				t.Fatalf("Size %d: safe decrypter released unauthenticated plaintext", size)
+			}
+			ciphertext[len(ciphertext)-1] ^= 1
+
+			// truncate the ciphertext
+			r, err = newDecrypter(bytes.NewReader(ciphertext[:len(ciphertext)-1]))
+			if err != nil {
+				t.Fatalf("Failed to create %s EAX decrypter: %s", name, err)
+			}
+			if _, err = ioutil.ReadAll(r); err != (crypto.AuthenticationError{}) {
+				t.Fatalf("Size %d: %s decrypter accepted a truncated ciphertext - error: %v", size, name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/enceve/crypto"
+)
+
+const (
+	ff1Rounds    = 10
+	ff1MaxLength = 1 << 32 // max. length of the numeral string
+)
+
+// The FF1 format-preserving encryption scheme
+type ff1 struct {
+	blockCipher cipher.Block
+	radix       int
+	maxTweakLen int
+	minLength   int
+}
+
+// NewFF1 returns a FPE implementing the FF1 format-preserving encryption
+// scheme specified in NIST SP 800-38G wrapping the cipher.Block. The block
+// size of the cipher must be 128 bit - e.g. AES, Camellia or Serpent.
+// The radix must be between 2 and 2^16 and maxTweakLen is the max. length
+// of the tweak in bytes. The numeral strings must be at least 2 numerals
+// long and the radix^length must be at least 1,000,000.
+func NewFF1(c cipher.Block, radix, maxTweakLen int) (FPE, error) {
+	if c == nil {
+		return nil, errors.New("the cipher.Block must not be nil")
+	}
+	if c.BlockSize() != 16 {
+		return nil, errors.New("cipher block size not supported")
+	}
+	if radix < 2 || radix > 1<<16 {
+		return nil, errors.New("the radix must be between 2 and 2^16")
+	}
+	if maxTweakLen < 0 {
+		return nil, errors.New("the max. tweak length must not be negative")
+	}
+	return &ff1{
+		blockCipher: c,
+		radix:       radix,
+		maxTweakLen: maxTweakLen,
+		minLength:   minFPELength(radix),
+	}, nil
+}
+
+func (f *ff1) Radix() int { return f.radix }
+
+func (f *ff1) Encrypt(dst, src []uint16, tweak []byte) error {
+	return f.crypt(dst, src, tweak, false)
+}
+
+func (f *ff1) Decrypt(dst, src []uint16, tweak []byte) error {
+	return f.crypt(dst, src, tweak, true)
+}
+
+func (f *ff1) crypt(dst, src []uint16, tweak []byte, decrypt bool) error {
+	n := len(src)
+	if n < f.minLength || uint64(n) > ff1MaxLength {
+		return errFPELength
+	}
+	if len(tweak) > f.maxTweakLen {
+		return errors.New("tweak is too long")
+	}
+	if len(dst) < n {
+		panic("dst buffer to small")
+	}
+	if err := checkNumerals(src, f.radix); err != nil {
+		return err
+	}
+
+	u := n / 2
+	v := n - u
+
+	radix := big.NewInt(int64(f.radix))
+	modU := new(big.Int).Exp(radix, big.NewInt(int64(u)), nil)
+	modV := new(big.Int).Exp(radix, big.NewInt(int64(v)), nil)
+
+	// b is the byte length of the largest number represented by v numerals
+	b := (new(big.Int).Sub(modV, big.NewInt(1)).BitLen() + 7) / 8
+	d := 4*((b+3)/4) + 4
+
+	// buf contains P || Q where P = [1]^1 || [2]^1 || [1]^1 || [radix]^3 ||
+	// [10]^1 || [u mod 256]^1 || [n]^4 || [t]^4 and Q = T || [0]^pad || [i]^1 || [NUM(B)]^b
+	t := len(tweak)
+	qLen := t + b + 1
+	qLen += (16 - qLen%16) % 16
+	buf := make([]byte, 16+qLen)
+	buf[0], buf[1], buf[2] = 1, 2, 1
+	buf[3], buf[4], buf[5] = byte(f.radix>>16), byte(f.radix>>8), byte(f.radix)
+	buf[6], buf[7] = ff1Rounds, byte(u)
+	binary.BigEndian.PutUint32(buf[8:], uint32(n))
+	binary.BigEndian.PutUint32(buf[12:], uint32(t))
+	copy(buf[16:], tweak)
+	numB := buf[len(buf)-b:]
+
+	s := make([]byte, ((d+15)/16)*16)
+	r, block := s[:16], make([]byte, 16)
+
+	a, bb := make([]uint16, u, n), make([]uint16, v, n)
+	copy(a, src[:u])
+	copy(bb, src[u:])
+	y := new(big.Int)
+	for j := 0; j < ff1Rounds; j++ {
+		i := j
+		if decrypt {
+			i = ff1Rounds - 1 - j
+		}
+		m, mod := u, modU
+		if i%2 == 1 {
+			m, mod = v, modV
+		}
+
+		// decryption swaps the roles of A and B
+		x, z := bb, a
+		if decrypt {
+			x, z = a, bb
+		}
+
+		buf[len(buf)-b-1] = byte(i)
+		numBytes(numB, x, radix)
+
+		// R = PRF(P || Q) - the CBC-MAC with a zero IV
+		for k := range r {
+			r[k] = 0
+		}
+		for k := 0; k < len(buf); k += 16 {
+			crypto.XOR(r, r, buf[k:k+16])
+			f.blockCipher.Encrypt(r, r)
+		}
+		for k := 1; k*16 < d; k++ {
+			copy(block, r)
+			block[15] ^= byte(k)
+			block[14] ^= byte(k >> 8)
+			block[13] ^= byte(k >> 16)
+			block[12] ^= byte(k >> 24)
+			f.blockCipher.Encrypt(s[16*k:16*(k+1)], block)
+		}
+		y.SetBytes(s[:d])
+
+		c := num(z, radix)
+		if decrypt {
+			c.Sub(c, y)
+		} else {
+			c.Add(c, y)
+		}
+		c.Mod(c, mod)
+
+		z = z[:m]
+		str(z, c, radix)
+		if decrypt {
+			a, bb = z, a
+		} else {
+			a, bb = bb, z
+		}
+	}
+	copy(dst[:u], a)
+	copy(dst[u:n], bb)
+	return nil
+}
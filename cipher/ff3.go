@@ -0,0 +1,176 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"errors"
+	"math/big"
+)
+
+const (
+	// The size of the FF3-1 tweak in bytes (56 bit).
+	FF31TweakSize = 7
+
+	ff3Rounds = 8
+)
+
+// The FF3-1 format-preserving encryption scheme
+type ff3 struct {
+	blockCipher cipher.Block
+	radix       int
+	minLength   int
+	maxLength   int
+}
+
+// NewFF31 returns a FPE implementing the FF3-1 format-preserving encryption
+// scheme specified in NIST SP 800-38G Revision 1. FF3-1 encrypts with the
+// byte-reversed key, so NewFF31 takes the key and the newCipher function,
+// which must return a cipher.Block (like aes.NewCipher, camellia.NewCipher
+// or serpent.NewCipher) with a block size of 128 bit.
+// The radix must be between 2 and 2^16 and the tweak must be FF31TweakSize
+// bytes long. The numeral strings must be at least 2 and at most
+// 2*floor(log_radix(2^96)) numerals long and the radix^length must be
+// at least 1,000,000.
+func NewFF31(key []byte, newCipher func(key []byte) (cipher.Block, error), radix int) (FPE, error) {
+	if newCipher == nil {
+		return nil, errors.New("the cipher constructor must not be nil")
+	}
+	if radix < 2 || radix > 1<<16 {
+		return nil, errors.New("the radix must be between 2 and 2^16")
+	}
+	revKey := make([]byte, len(key))
+	for i, v := range key {
+		revKey[len(key)-1-i] = v
+	}
+	c, err := newCipher(revKey)
+	if err != nil {
+		return nil, err
+	}
+	if c.BlockSize() != 16 {
+		return nil, errors.New("cipher block size not supported")
+	}
+
+	// the max. length is 2*k, where k is the greatest number with radix^k <= 2^96
+	maxLength, d, max := 0, big.NewInt(int64(radix)), new(big.Int).Lsh(big.NewInt(1), 96)
+	for d.Cmp(max) <= 0 {
+		d.Mul(d, big.NewInt(int64(radix)))
+		maxLength += 2
+	}
+	return &ff3{
+		blockCipher: c,
+		radix:       radix,
+		minLength:   minFPELength(radix),
+		maxLength:   maxLength,
+	}, nil
+}
+
+func (f *ff3) Radix() int { return f.radix }
+
+func (f *ff3) Encrypt(dst, src []uint16, tweak []byte) error {
+	if len(tweak) != FF31TweakSize {
+		return errors.New("tweak length must be 7 bytes")
+	}
+	tl, tr := ff31Tweak(tweak)
+	return f.crypt(dst, src, &tl, &tr, false)
+}
+
+func (f *ff3) Decrypt(dst, src []uint16, tweak []byte) error {
+	if len(tweak) != FF31TweakSize {
+		return errors.New("tweak length must be 7 bytes")
+	}
+	tl, tr := ff31Tweak(tweak)
+	return f.crypt(dst, src, &tl, &tr, true)
+}
+
+// ff31Tweak splits the 56 bit FF3-1 tweak into the 32 bit
+// tweaks TL and TR of the (original) FF3 scheme.
+func ff31Tweak(tweak []byte) (tl, tr [4]byte) {
+	tl[0], tl[1], tl[2], tl[3] = tweak[0], tweak[1], tweak[2], tweak[3]&0xf0
+	tr[0], tr[1], tr[2], tr[3] = tweak[4], tweak[5], tweak[6], tweak[3]<<4
+	return
+}
+
+func (f *ff3) crypt(dst, src []uint16, tl, tr *[4]byte, decrypt bool) error {
+	n := len(src)
+	if n < f.minLength || n > f.maxLength {
+		return errFPELength
+	}
+	if len(dst) < n {
+		panic("dst buffer to small")
+	}
+	if err := checkNumerals(src, f.radix); err != nil {
+		return err
+	}
+
+	u := (n + 1) / 2
+	v := n - u
+
+	radix := big.NewInt(int64(f.radix))
+	modU := new(big.Int).Exp(radix, big.NewInt(int64(u)), nil)
+	modV := new(big.Int).Exp(radix, big.NewInt(int64(v)), nil)
+
+	// FF3 interprets the numeral strings in reversed order - so A and
+	// B are stored reversed (the first numeral is the least significant)
+	a, bb := make([]uint16, u, n), make([]uint16, v, n)
+	copy(a, src[:u])
+	copy(bb, src[u:])
+	rev(a)
+	rev(bb)
+
+	var p [16]byte
+	y := new(big.Int)
+	for j := 0; j < ff3Rounds; j++ {
+		i := j
+		if decrypt {
+			i = ff3Rounds - 1 - j
+		}
+		m, mod, w := u, modU, tr
+		if i%2 == 1 {
+			m, mod, w = v, modV, tl
+		}
+
+		// decryption swaps the roles of A and B
+		x, z := bb, a
+		if decrypt {
+			x, z = a, bb
+		}
+
+		// P = W xor [i]^4 || [NUM(REV(B))]^12 - the cipher
+		// encrypts REVB(P) and the result S is reversed.
+		// Since x is stored reversed, NUM(x) = NUM(REV(B)).
+		copy(p[:4], w[:])
+		p[3] ^= byte(i)
+		numBytes(p[4:], x, radix)
+		for k := 0; k < 8; k++ {
+			p[k], p[15-k] = p[15-k], p[k]
+		}
+		f.blockCipher.Encrypt(p[:], p[:])
+		for k := 0; k < 8; k++ {
+			p[k], p[15-k] = p[15-k], p[k]
+		}
+		y.SetBytes(p[:])
+
+		c := num(z, radix)
+		if decrypt {
+			c.Sub(c, y)
+		} else {
+			c.Add(c, y)
+		}
+		c.Mod(c, mod)
+
+		z = z[:m]
+		str(z, c, radix)
+		if decrypt {
+			a, bb = z, a
+		} else {
+			a, bb = bb, z
+		}
+	}
+	rev(a)
+	rev(bb)
+	copy(dst[:u], a)
+	copy(dst[u:n], bb)
+	return nil
+}
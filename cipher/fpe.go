@@ -0,0 +1,177 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"errors"
+	"math/big"
+	"unicode/utf8"
+)
+
+// FPE is a format-preserving encryption scheme. FPE encrypts numeral strings -
+// a sequence of numerals, which are smaller than the radix - to numeral strings
+// of the same length. An Alphabet can be used to convert between strings and
+// numeral strings.
+type FPE interface {
+	// Radix returns the radix (the base) of the numerals.
+	Radix() int
+
+	// Encrypt encrypts the numeral string src using the tweak and writes the
+	// result into dst. Dst must be at least as long as src and dst and src may
+	// overlap entirely or not at all. Encrypt returns a non-nil error if the
+	// length of src or tweak is not valid or src contains invalid numerals.
+	Encrypt(dst, src []uint16, tweak []byte) error
+
+	// Decrypt decrypts the numeral string src using the tweak and writes the
+	// result into dst. Dst must be at least as long as src and dst and src may
+	// overlap entirely or not at all. Decrypt returns a non-nil error if the
+	// length of src or tweak is not valid or src contains invalid numerals.
+	Decrypt(dst, src []uint16, tweak []byte) error
+}
+
+// The minimal domain size (radix^length) of FF1 and FF3-1
+// as specified in NIST SP 800-38G Revision 1.
+const fpeMinDomainSize = 1000000
+
+var errFPELength = errors.New("the length of the numeral string is not valid")
+var errFPENumeral = errors.New("the numeral string contains a numeral greater than or equal to the radix")
+
+// Some common alphabets.
+var (
+	// Digits contains the decimal digits 0-9.
+	Digits = mustAlphabet("0123456789")
+	// Alphanumerics contains the decimal digits 0-9 and the
+	// lowercase letters a-z (in this order).
+	Alphanumerics = mustAlphabet("0123456789abcdefghijklmnopqrstuvwxyz")
+)
+
+// An Alphabet converts strings to numeral strings and vice versa.
+// Every character of the alphabet represents the numeral of its position.
+type Alphabet struct {
+	chars []rune
+	index map[rune]uint16
+}
+
+// NewAlphabet returns an Alphabet consisting of the characters of chars.
+// The characters must be distinct and there must be at least 2 and at
+// most 2^16 characters.
+func NewAlphabet(chars string) (*Alphabet, error) {
+	a := &Alphabet{
+		chars: []rune(chars),
+		index: make(map[rune]uint16),
+	}
+	if n := len(a.chars); n < 2 || n > 1<<16 {
+		return nil, errors.New("an alphabet must consist of 2 to 2^16 characters")
+	}
+	for i, r := range a.chars {
+		if _, ok := a.index[r]; ok || r == utf8.RuneError {
+			return nil, errors.New("the characters of an alphabet must be distinct and valid UTF-8")
+		}
+		a.index[r] = uint16(i)
+	}
+	return a, nil
+}
+
+func mustAlphabet(chars string) *Alphabet {
+	a, err := NewAlphabet(chars)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// Radix returns the number of characters of the alphabet.
+func (a *Alphabet) Radix() int { return len(a.chars) }
+
+// Numerals converts the string s into a numeral string. It returns
+// a non-nil error if s contains characters not in the alphabet.
+func (a *Alphabet) Numerals(s string) ([]uint16, error) {
+	numerals := make([]uint16, 0, len(s))
+	for _, r := range s {
+		n, ok := a.index[r]
+		if !ok {
+			return nil, errors.New("the string contains a character, which is not in the alphabet")
+		}
+		numerals = append(numerals, n)
+	}
+	return numerals, nil
+}
+
+// String converts the numeral string into a string. It returns
+// a non-nil error if a numeral is not smaller than the radix.
+func (a *Alphabet) String(numerals []uint16) (string, error) {
+	s := make([]rune, len(numerals))
+	for i, n := range numerals {
+		if int(n) >= len(a.chars) {
+			return "", errFPENumeral
+		}
+		s[i] = a.chars[n]
+	}
+	return string(s), nil
+}
+
+// minFPELength returns the min. length of a numeral
+// string, so that radix^length >= fpeMinDomainSize.
+// The domain size is computed with 64 bit integers, so
+// radix^length does not overflow for a radix up to 2^16
+// on 32 bit platforms.
+func minFPELength(radix int) int {
+	n, d := 1, uint64(radix)
+	for d < fpeMinDomainSize {
+		d *= uint64(radix)
+		n++
+	}
+	return n
+}
+
+// checkNumerals returns a non-nil error if a numeral is not smaller than the radix.
+func checkNumerals(src []uint16, radix int) error {
+	for _, n := range src {
+		if int(n) >= radix {
+			return errFPENumeral
+		}
+	}
+	return nil
+}
+
+// num returns the number represented by the numeral string
+// (the first numeral is the most significant one).
+func num(x []uint16, radix *big.Int) *big.Int {
+	n, d := new(big.Int), new(big.Int)
+	for _, v := range x {
+		n.Mul(n, radix)
+		n.Add(n, d.SetUint64(uint64(v)))
+	}
+	return n
+}
+
+// numBytes writes the number represented by the numeral string
+// to dst as big endian integer (left padded with zeros).
+// The number must fit into len(dst) bytes.
+func numBytes(dst []byte, x []uint16, radix *big.Int) {
+	b := num(x, radix).Bytes()
+	n := len(dst) - len(b)
+	for i := range dst[:n] {
+		dst[i] = 0
+	}
+	copy(dst[n:], b)
+}
+
+// str writes the numeral string representing n to dst
+// (the first numeral is the most significant one).
+// The number n must be smaller than radix^len(dst).
+func str(dst []uint16, n, radix *big.Int) {
+	n, r := new(big.Int).Set(n), new(big.Int)
+	for i := len(dst) - 1; i >= 0; i-- {
+		n.QuoRem(n, radix, r)
+		dst[i] = uint16(r.Uint64())
+	}
+}
+
+// rev reverses the numeral string in place.
+func rev(x []uint16) {
+	for i, j := 0, len(x)-1; i < j; i, j = i+1, j-1 {
+		x[i], x[j] = x[j], x[i]
+	}
+}
@@ -0,0 +1,312 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"testing"
+
+	"github.com/enceve/crypto/camellia"
+	"github.com/enceve/crypto/serpent"
+)
+
+// FF1-AES test vectors from the NIST SP 800-38G samples
+// https://csrc.nist.gov/CSRC/media/Projects/Cryptographic-Standards-and-Guidelines/documents/examples/FF1samples.pdf
+var ff1Vectors = []struct {
+	key, tweak          string
+	radix               int
+	plaintext, expected string
+}{
+	{"2B7E151628AED2A6ABF7158809CF4F3C", "", 10, "0123456789", "2433477484"},
+	{"2B7E151628AED2A6ABF7158809CF4F3C", "39383736353433323130", 10, "0123456789", "6124200773"},
+	{"2B7E151628AED2A6ABF7158809CF4F3C", "3737373770717273373737", 36, "0123456789abcdefghi", "a9tv40mll9kdu509eum"},
+	{"2B7E151628AED2A6ABF7158809CF4F3CEF4359D8D580AA4F", "", 10, "0123456789", "2830668132"},
+	{"2B7E151628AED2A6ABF7158809CF4F3CEF4359D8D580AA4F", "39383736353433323130", 10, "0123456789", "2496655549"},
+	{"2B7E151628AED2A6ABF7158809CF4F3CEF4359D8D580AA4F", "3737373770717273373737", 36, "0123456789abcdefghi", "xbj3kv35jrawxv32ysr"},
+	{"2B7E151628AED2A6ABF7158809CF4F3CEF4359D8D580AA4F7F036D6F04FC6A94", "", 10, "0123456789", "6657667009"},
+	{"2B7E151628AED2A6ABF7158809CF4F3CEF4359D8D580AA4F7F036D6F04FC6A94", "39383736353433323130", 10, "0123456789", "1001623463"},
+	{"2B7E151628AED2A6ABF7158809CF4F3CEF4359D8D580AA4F7F036D6F04FC6A94", "3737373770717273373737", 36, "0123456789abcdefghi", "xs8a0azh2avyalyzuwd"},
+}
+
+// FF3-AES test vectors from the NIST SP 800-38G samples of the original FF3
+// scheme with a 64 bit tweak. FF3-1 only differs in the derivation of the two
+// 32 bit tweak halves, so these vectors are used to test the FF3 rounds.
+// https://csrc.nist.gov/CSRC/media/Projects/Cryptographic-Standards-and-Guidelines/documents/examples/FF3samples.pdf
+var ff3Vectors = []struct {
+	key, tweak          string
+	radix               int
+	plaintext, expected string
+}{
+	{"EF4359D8D580AA4F7F036D6F04FC6A94", "D8E7920AFA330A73", 10, "890121234567890000", "750918814058654607"},
+	{"EF4359D8D580AA4F7F036D6F04FC6A94", "9A768A92F60E12D8", 10, "890121234567890000", "018989839189395384"},
+	{"EF4359D8D580AA4F7F036D6F04FC6A94", "D8E7920AFA330A73", 10, "89012123456789000000789000000", "48598367162252569629397416226"},
+	{"EF4359D8D580AA4F7F036D6F04FC6A94", "0000000000000000", 10, "89012123456789000000789000000", "34695224821734535122613701434"},
+	{"EF4359D8D580AA4F7F036D6F04FC6A94", "9A768A92F60E12D8", 26, "0123456789abcdefghi", "g2pk40i992fn20cjakb"},
+	{"EF4359D8D580AA4F7F036D6F04FC6A942B7E151628AED2A6", "D8E7920AFA330A73", 10, "890121234567890000", "646965393875028755"},
+	{"EF4359D8D580AA4F7F036D6F04FC6A942B7E151628AED2A6ABF7158809CF4F3C", "D8E7920AFA330A73", 10, "890121234567890000", "922011205562777495"},
+}
+
+// FF3-1-AES test vectors with a 56 bit tweak from the NIST ACVP
+// FF3-1 sample vectors and the NIST SP 800-38G Rev. 1 FF3-1 sample.
+var ff31Vectors = []struct {
+	key, tweak          string
+	radix               int
+	plaintext, expected string
+}{
+	{"EF4359D8D580AA4F7F036D6F04FC6A94", "D8E7920AFA330A", 10, "890121234567890000", "477064185124354662"},
+	{"AD41EC5D2356DEAE53AE76F50B4BA6D2", "CF29DA1E18D970", 10, "6520935496", "4716569208"},
+	{"2DE79D232DF5585D68CE47882AE256D6", "CBD09280979564", 10, "3992520240", "8901801106"},
+	{"01C63017111438F7FC8E24EB16C71AB5", "C4E822DCD09F27", 10,
+		"60761757463116869318437658042297305934914824457484538562",
+		"35637144092473838892796702739628394376915177448290847293"},
+}
+
+func TestFF1Vectors(t *testing.T) {
+	for i, v := range ff1Vectors {
+		block, err := aes.NewCipher(fromHex(v.key))
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create AES instance: %s", i, err)
+		}
+		f, err := NewFF1(block, v.radix, 16)
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create FF1 instance: %s", i, err)
+		}
+		testFPEVector(t, i, f, fromHex(v.tweak), v.plaintext, v.expected)
+	}
+}
+
+func TestFF3Vectors(t *testing.T) {
+	for i, v := range ff3Vectors {
+		f, err := NewFF31(fromHex(v.key), aes.NewCipher, v.radix)
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create FF3-1 instance: %s", i, err)
+		}
+		a, _ := NewAlphabet(string(Alphanumerics.chars[:v.radix]))
+		plaintext, err := a.Numerals(v.plaintext)
+		if err != nil {
+			t.Fatalf("TestVector %d: %s", i, err)
+		}
+
+		var tl, tr [4]byte
+		tweak := fromHex(v.tweak)
+		copy(tl[:], tweak[:4])
+		copy(tr[:], tweak[4:])
+
+		ciphertext := make([]uint16, len(plaintext))
+		if err = f.(*ff3).crypt(ciphertext, plaintext, &tl, &tr, false); err != nil {
+			t.Fatalf("TestVector %d: Encryption failed: %s", i, err)
+		}
+		if s, _ := a.String(ciphertext); s != v.expected {
+			t.Fatalf("TestVector %d Encrypt failed:\nFound   : %s\nExpected: %s", i, s, v.expected)
+		}
+		if err = f.(*ff3).crypt(ciphertext, ciphertext, &tl, &tr, true); err != nil {
+			t.Fatalf("TestVector %d: Decryption failed: %s", i, err)
+		}
+		if s, _ := a.String(ciphertext); s != v.plaintext {
+			t.Fatalf("TestVector %d Decrypt failed:\nFound   : %s\nExpected: %s", i, s, v.plaintext)
+		}
+	}
+}
+
+func TestFF31Vectors(t *testing.T) {
+	for i, v := range ff31Vectors {
+		f, err := NewFF31(fromHex(v.key), aes.NewCipher, v.radix)
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create FF3-1 instance: %s", i, err)
+		}
+		testFPEVector(t, i, f, fromHex(v.tweak), v.plaintext, v.expected)
+	}
+}
+
+func testFPEVector(t *testing.T, i int, f FPE, tweak []byte, plaintext, expected string) {
+	a, err := NewAlphabet(string(Alphanumerics.chars[:f.Radix()]))
+	if err != nil {
+		t.Fatalf("TestVector %d: Failed to create alphabet: %s", i, err)
+	}
+	numerals, err := a.Numerals(plaintext)
+	if err != nil {
+		t.Fatalf("TestVector %d: %s", i, err)
+	}
+
+	ciphertext := make([]uint16, len(numerals))
+	if err = f.Encrypt(ciphertext, numerals, tweak); err != nil {
+		t.Fatalf("TestVector %d: Encryption failed: %s", i, err)
+	}
+	if s, _ := a.String(ciphertext); s != expected {
+		t.Fatalf("TestVector %d Encrypt failed:\nFound   : %s\nExpected: %s", i, s, expected)
+	}
+	if err = f.Decrypt(ciphertext, ciphertext, tweak); err != nil {
+		t.Fatalf("TestVector %d: Decryption failed: %s", i, err)
+	}
+	if s, _ := a.String(ciphertext); s != plaintext {
+		t.Fatalf("TestVector %d Decrypt failed:\nFound   : %s\nExpected: %s", i, s, plaintext)
+	}
+}
+
+func TestMinFPELength(t *testing.T) {
+	lengths := []struct{ radix, length int }{
+		{2, 20}, {10, 6}, {36, 4}, {999, 3}, {1000, 2}, {1 << 16, 2},
+	}
+	for i, v := range lengths {
+		if n := minFPELength(v.radix); n != v.length {
+			t.Fatalf("Test %d: minFPELength(%d) = %d - expected %d", i, v.radix, n, v.length)
+		}
+	}
+}
+
+func testFPE(t *testing.T, name string, f FPE, tweak []byte, maxLength int) {
+	radix := f.Radix()
+	for n := minFPELength(radix); n <= maxLength; n++ {
+		plaintext := make([]uint16, n)
+		for i := range plaintext {
+			plaintext[i] = uint16((i * 7919) % radix)
+		}
+		ciphertext := make([]uint16, n)
+		if err := f.Encrypt(ciphertext, plaintext, tweak); err != nil {
+			t.Fatalf("%s: Radix %d - length %d: encryption failed: %s", name, radix, n, err)
+		}
+		if err := checkNumerals(ciphertext, radix); err != nil {
+			t.Fatalf("%s: Radix %d - length %d: %s", name, radix, n, err)
+		}
+		if err := f.Decrypt(ciphertext, ciphertext, tweak); err != nil {
+			t.Fatalf("%s: Radix %d - length %d: decryption failed: %s", name, radix, n, err)
+		}
+		for i := range plaintext {
+			if plaintext[i] != ciphertext[i] {
+				t.Fatalf("%s: Radix %d - length %d: decryption returned wrong plaintext", name, radix, n)
+			}
+		}
+	}
+}
+
+func TestFF1(t *testing.T) {
+	key := make([]byte, 16)
+	ciphers := map[string]func([]byte) (cipher.Block, error){
+		"AES":      aes.NewCipher,
+		"Camellia": camellia.NewCipher,
+		"Serpent":  serpent.NewCipher,
+	}
+	for name, newCipher := range ciphers {
+		block, err := newCipher(key)
+		if err != nil {
+			t.Fatalf("%s: Failed to create cipher instance: %s", name, err)
+		}
+		for _, radix := range []int{2, 10, 36, 256, 1 << 16} {
+			f, err := NewFF1(block, radix, 32)
+			if err != nil {
+				t.Fatalf("%s: Failed to create FF1 instance: %s", name, err)
+			}
+			testFPE(t, name, f, []byte("tweak"), 64)
+		}
+	}
+}
+
+func TestFF31(t *testing.T) {
+	key := make([]byte, 16)
+	ciphers := map[string]func([]byte) (cipher.Block, error){
+		"AES":      aes.NewCipher,
+		"Camellia": camellia.NewCipher,
+		"Serpent":  serpent.NewCipher,
+	}
+	tweak := make([]byte, FF31TweakSize)
+	for name, newCipher := range ciphers {
+		for _, radix := range []int{2, 10, 36, 256, 1 << 16} {
+			f, err := NewFF31(key, newCipher, radix)
+			if err != nil {
+				t.Fatalf("%s: Failed to create FF3-1 instance: %s", name, err)
+			}
+			testFPE(t, name, f, tweak, f.(*ff3).maxLength)
+		}
+	}
+}
+
+func TestFF31Tweak(t *testing.T) {
+	tl, tr := ff31Tweak(fromHex("0102033A040506"))
+	if tl != [4]byte{0x01, 0x02, 0x03, 0x30} || tr != [4]byte{0x04, 0x05, 0x06, 0xA0} {
+		t.Fatalf("FF3-1 tweak split failed: TL: %x - TR: %x", tl, tr)
+	}
+}
+
+func TestAlphabet(t *testing.T) {
+	s := "4111111111111111"
+	numerals, err := Digits.Numerals(s)
+	if err != nil {
+		t.Fatalf("Failed to convert %s: %s", s, err)
+	}
+	if r, _ := Digits.String(numerals); r != s {
+		t.Fatalf("Alphabet conversion failed: Found %s - expected %s", r, s)
+	}
+	if _, err = Digits.Numerals("4111-1111"); err == nil {
+		t.Fatal("Digits accepted a string with invalid characters")
+	}
+	if _, err = Digits.String([]uint16{10}); err == nil {
+		t.Fatal("Digits accepted an invalid numeral")
+	}
+	if Alphanumerics.Radix() != 36 {
+		t.Fatalf("Alphanumerics has radix %d - expected 36", Alphanumerics.Radix())
+	}
+
+	a, err := NewAlphabet("αβγ")
+	if err != nil {
+		t.Fatalf("Failed to create alphabet: %s", err)
+	}
+	if numerals, err = a.Numerals("γαβ"); err != nil || numerals[0] != 2 || numerals[1] != 0 || numerals[2] != 1 {
+		t.Fatalf("Alphabet conversion of multi-byte characters failed: %v - %v", numerals, err)
+	}
+	if _, err = NewAlphabet("abca"); err == nil {
+		t.Fatal("NewAlphabet accepted duplicate characters")
+	}
+	if _, err = NewAlphabet("a"); err == nil {
+		t.Fatal("NewAlphabet accepted a single character")
+	}
+}
+
+func TestNewFPE(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES instance: %s", err)
+	}
+	if _, err = NewFF1(block, 1, 0); err == nil {
+		t.Fatal("NewFF1 accepted a radix of 1")
+	}
+	if _, err = NewFF1(block, 1<<16+1, 0); err == nil {
+		t.Fatal("NewFF1 accepted a radix of 2^16 + 1")
+	}
+	if _, err = NewFF1(nil, 10, 0); err == nil {
+		t.Fatal("NewFF1 accepted a nil cipher.Block")
+	}
+	if _, err = NewFF31(make([]byte, 15), aes.NewCipher, 10); err == nil {
+		t.Fatal("NewFF31 accepted an invalid key")
+	}
+	if _, err = NewFF31(make([]byte, 16), nil, 10); err == nil {
+		t.Fatal("NewFF31 accepted a nil cipher constructor")
+	}
+
+	f, err := NewFF1(block, 10, 8)
+	if err != nil {
+		t.Fatalf("Failed to create FF1 instance: %s", err)
+	}
+	dst := make([]uint16, 64)
+	if err = f.Encrypt(dst, make([]uint16, 5), nil); err == nil {
+		t.Fatal("FF1 accepted a numeral string with a domain smaller than 1,000,000")
+	}
+	if err = f.Encrypt(dst, make([]uint16, 6), make([]byte, 9)); err == nil {
+		t.Fatal("FF1 accepted a too long tweak")
+	}
+	if err = f.Encrypt(dst, []uint16{0, 1, 2, 3, 4, 10}, nil); err == nil {
+		t.Fatal("FF1 accepted an invalid numeral")
+	}
+
+	f, err = NewFF31(make([]byte, 16), aes.NewCipher, 10)
+	if err != nil {
+		t.Fatalf("Failed to create FF3-1 instance: %s", err)
+	}
+	if err = f.Encrypt(dst, make([]uint16, 6), make([]byte, 8)); err == nil {
+		t.Fatal("FF3-1 accepted a 64 bit tweak")
+	}
+	if err = f.Encrypt(dst, make([]uint16, 57), make([]byte, FF31TweakSize)); err == nil {
+		t.Fatal("FF3-1 accepted a too long numeral string")
+	}
+}
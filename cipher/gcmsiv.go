@@ -0,0 +1,248 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+const (
+	// The nonce size of the GCM-SIV AEAD cipher in bytes.
+	GCMSIVNonceSize = 12
+	// The size of the GCM-SIV auth. tag in bytes.
+	GCMSIVTagSize = 16
+
+	gcmSIVMaxSize = 1 << 36 // max. length of the plaintext and additional data
+)
+
+// The GCM-SIV cipher
+type gcmSIV struct {
+	keyGen    cipher.Block
+	newCipher func(key []byte) (cipher.Block, error)
+	keySize   int
+}
+
+// NewGCMSIV returns a cipher.AEAD implementing the (nonce-misuse-resistant)
+// GCM-SIV mode specified in RFC 8452. GCM-SIV derives a fresh
+// authentication and encryption key from the key (key-generating key)
+// and the nonce for every message. The newCipher function must return
+// a cipher.Block (like aes.NewCipher, camellia.NewCipher or serpent.NewCipher)
+// with a block size of 128 bit for the key and the derived encryption keys,
+// which have the same length as the key.
+// The length of the key must be 16 or 32 byte (RFC 8452 does not define
+// a 24 byte key size).
+func NewGCMSIV(key []byte, newCipher func(key []byte) (cipher.Block, error)) (cipher.AEAD, error) {
+	if k := len(key); k != 16 && k != 32 {
+		return nil, crypto.KeySizeError(k)
+	}
+	if newCipher == nil {
+		return nil, errors.New("the cipher constructor must not be nil")
+	}
+	c, err := newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if c.BlockSize() != 16 {
+		return nil, errors.New("cipher block size not supported")
+	}
+	return &gcmSIV{
+		keyGen:    c,
+		newCipher: newCipher,
+		keySize:   len(key),
+	}, nil
+}
+
+func (c *gcmSIV) NonceSize() int { return GCMSIVNonceSize }
+
+func (c *gcmSIV) Overhead() int { return GCMSIVTagSize }
+
+func (c *gcmSIV) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if n := len(nonce); n != GCMSIVNonceSize {
+		panic(crypto.NonceSizeError(n))
+	}
+	if uint64(len(plaintext)) > gcmSIVMaxSize || uint64(len(additionalData)) > gcmSIVMaxSize {
+		panic("plaintext or additional data too large")
+	}
+	authKey, block := c.deriveKeys(nonce)
+
+	ret, out := sliceForAppend(dst, len(plaintext)+GCMSIVTagSize)
+
+	var tag [GCMSIVTagSize]byte
+	gcmSIVTag(&tag, authKey, block, nonce, plaintext, additionalData)
+
+	gcmSIVCtrCrypt(out, plaintext, &tag, block)
+	copy(out[len(plaintext):], tag[:])
+	return ret
+}
+
+func (c *gcmSIV) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if n := len(nonce); n != GCMSIVNonceSize {
+		return nil, crypto.NonceSizeError(n)
+	}
+	if len(ciphertext) < GCMSIVTagSize {
+		return nil, crypto.AuthenticationError{}
+	}
+	if uint64(len(ciphertext)) > gcmSIVMaxSize+GCMSIVTagSize || uint64(len(additionalData)) > gcmSIVMaxSize {
+		return nil, crypto.AuthenticationError{}
+	}
+	authKey, block := c.deriveKeys(nonce)
+
+	var hash [GCMSIVTagSize]byte
+	copy(hash[:], ciphertext[len(ciphertext)-GCMSIVTagSize:])
+	ciphertext = ciphertext[:len(ciphertext)-GCMSIVTagSize]
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	gcmSIVCtrCrypt(out, ciphertext, &hash, block)
+
+	var tag [GCMSIVTagSize]byte
+	gcmSIVTag(&tag, authKey, block, nonce, out, additionalData)
+	if subtle.ConstantTimeCompare(tag[:], hash[:]) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, crypto.AuthenticationError{}
+	}
+	return ret, nil
+}
+
+// deriveKeys derives the message authentication key and
+// the message encryption key from the nonce.
+func (c *gcmSIV) deriveKeys(nonce []byte) (*[16]byte, cipher.Block) {
+	var in, out [16]byte
+	copy(in[4:], nonce)
+
+	key := make([]byte, 16+c.keySize)
+	for i := 0; i < len(key); i += 8 {
+		in[0] = byte(i / 8)
+		c.keyGen.Encrypt(out[:], in[:])
+		copy(key[i:], out[:8])
+	}
+
+	var authKey [16]byte
+	copy(authKey[:], key[:16])
+
+	block, err := c.newCipher(key[16:])
+	if err != nil {
+		panic(err) // the key size is valid for the key-generating key
+	}
+	return &authKey, block
+}
+
+// gcmSIVTag computes the auth. tag from the plaintext and
+// additional data using POLYVAL and the block cipher.
+func gcmSIVTag(tag *[16]byte, authKey *[16]byte, c cipher.Block, nonce, plaintext, additionalData []byte) {
+	var p polyval
+	p.init(authKey)
+	p.update(additionalData)
+	p.update(plaintext)
+
+	var lengths [16]byte
+	adLen, ptLen := uint64(len(additionalData))*8, uint64(len(plaintext))*8
+	for i := 0; i < 8; i++ {
+		lengths[i] = byte(adLen >> uint(8*i))
+		lengths[i+8] = byte(ptLen >> uint(8*i))
+	}
+	p.update(lengths[:])
+	p.sum(tag)
+
+	crypto.XOR(tag[:], tag[:], nonce)
+	tag[15] &= 0x7f
+	c.Encrypt(tag[:], tag[:])
+}
+
+// gcmSIVCtrCrypt encrypts the bytes in src with the CTR mode
+// of GCM-SIV - the counter block is the tag with the most significant
+// bit set and the first 32 bit are a little endian counter - and
+// writes the ciphertext into dst.
+func gcmSIVCtrCrypt(dst, src []byte, tag *[16]byte, c cipher.Block) {
+	var ctr, block [16]byte
+	ctr = *tag
+	ctr[15] |= 0x80
+
+	n := uint32(ctr[0]) | uint32(ctr[1])<<8 | uint32(ctr[2])<<16 | uint32(ctr[3])<<24
+	for len(src) > 0 {
+		c.Encrypt(block[:], ctr[:])
+		k := crypto.XOR(dst, src, block[:])
+		dst, src = dst[k:], src[k:]
+
+		n++
+		ctr[0], ctr[1], ctr[2], ctr[3] = byte(n), byte(n>>8), byte(n>>16), byte(n>>24)
+	}
+}
+
+// polyval implements the POLYVAL universal hash function
+// specified in RFC 8452. POLYVAL is computed using GHASH
+// multiplication in the "byte reversed" domain (RFC 8452 Appendix A).
+type polyval struct {
+	h, s [2]uint64 // GHASH representation: [0] holds the 64 most significant bits
+}
+
+// init sets the POLYVAL key and resets the state.
+func (p *polyval) init(key *[16]byte) {
+	h := polyvalToGHASH(key[:])
+
+	// mulX_GHASH
+	lsb := h[1] & 1
+	h[1] = h[1]>>1 | h[0]<<63
+	h[0] = h[0]>>1 ^ (0xe100000000000000 & -lsb)
+
+	p.h = h
+	p.s = [2]uint64{}
+}
+
+// update processes the (zero padded) msg.
+func (p *polyval) update(msg []byte) {
+	var block [16]byte
+	for len(msg) > 0 {
+		n := copy(block[:], msg)
+		for i := n; i < len(block); i++ {
+			block[i] = 0
+		}
+		msg = msg[n:]
+
+		x := polyvalToGHASH(block[:])
+		p.s[0] ^= x[0]
+		p.s[1] ^= x[1]
+		p.s = gfMul(p.s, p.h)
+	}
+}
+
+// sum writes the POLYVAL value to out.
+func (p *polyval) sum(out *[16]byte) {
+	for i := 0; i < 8; i++ {
+		out[i] = byte(p.s[1] >> uint(8*i))
+		out[i+8] = byte(p.s[0] >> uint(8*i))
+	}
+}
+
+// polyvalToGHASH converts the 16 byte POLYVAL field element
+// into the (byte reversed) GHASH representation.
+func polyvalToGHASH(b []byte) (x [2]uint64) {
+	for i := 7; i >= 0; i-- {
+		x[1] = x[1]<<8 | uint64(b[i])
+		x[0] = x[0]<<8 | uint64(b[i+8])
+	}
+	return
+}
+
+// gfMul computes x * y in GF(2^128) using the GHASH
+// representation (NIST SP 800-38D). The multiplication
+// is constant time.
+func gfMul(x, y [2]uint64) (z [2]uint64) {
+	v := y
+	for i := uint(0); i < 128; i++ {
+		bit := (x[i/64] >> (63 - i%64)) & 1
+		z[0] ^= v[0] & -bit
+		z[1] ^= v[1] & -bit
+
+		lsb := v[1] & 1
+		v[1] = v[1]>>1 | v[0]<<63
+		v[0] = v[0]>>1 ^ (0xe100000000000000 & -lsb)
+	}
+	return
+}
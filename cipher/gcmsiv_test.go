@@ -0,0 +1,179 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"math/rand"
+	"testing"
+
+	"github.com/enceve/crypto/camellia"
+	"github.com/enceve/crypto/serpent"
+)
+
+func TestPolyval(t *testing.T) {
+	// POLYVAL example from RFC 8452 (Appendix A)
+	key := fromHex("25629347589242761d31f826ba4b757b")
+	msg := fromHex("4f4f95668c83dfb6401762bb2d01a262d1a24ddd2721d006bbe45f20d3c9f362")
+	ref := fromHex("f7a3b47b846119fae5b7866cf5e5b77e")
+
+	var k, sum [16]byte
+	copy(k[:], key)
+
+	var p polyval
+	p.init(&k)
+	p.update(msg)
+	p.sum(&sum)
+	if !bytes.Equal(sum[:], ref) {
+		t.Fatalf("POLYVAL failed:\nFound   : %s\nExpected: %s", hex.EncodeToString(sum[:]), hex.EncodeToString(ref))
+	}
+}
+
+// AES-GCM-SIV test vectors from RFC 8452 (Appendix C)
+var gcmSIVVectors = []struct {
+	key, nonce, data, msg string
+	ciphertext            string
+}{
+	{
+		key:        "01000000000000000000000000000000",
+		nonce:      "030000000000000000000000",
+		data:       "",
+		msg:        "",
+		ciphertext: "dc20e2d83f25705bb49e439eca56de25",
+	},
+	{
+		key:        "01000000000000000000000000000000",
+		nonce:      "030000000000000000000000",
+		data:       "",
+		msg:        "0100000000000000",
+		ciphertext: "b5d839330ac7b786578782fff6013b815b287c22493a364c",
+	},
+	{
+		key:        "01000000000000000000000000000000",
+		nonce:      "030000000000000000000000",
+		data:       "",
+		msg:        "010000000000000000000000",
+		ciphertext: "7323ea61d05932260047d942a4978db357391a0bc4fdec8b0d106639",
+	},
+	{
+		key:        "01000000000000000000000000000000",
+		nonce:      "030000000000000000000000",
+		data:       "",
+		msg:        "01000000000000000000000000000000",
+		ciphertext: "743f7c8077ab25f8624e2e948579cf77303aaf90f6fe21199c6068577437a0c4",
+	},
+	{
+		key:   "01000000000000000000000000000000",
+		nonce: "030000000000000000000000",
+		data:  "",
+		msg:   "0100000000000000000000000000000002000000000000000000000000000000",
+		ciphertext: "84e07e62ba83a6585417245d7ec413a9fe427d6315c09b57ce45f2e3936a9445" +
+			"1a8e45dcd4578c667cd86847bf6155ff",
+	},
+	{
+		key:        "01000000000000000000000000000000",
+		nonce:      "030000000000000000000000",
+		data:       "01",
+		msg:        "0200000000000000",
+		ciphertext: "1e6daba35669f4273b0a1a2560969cdf790d99759abd1508",
+	},
+	{
+		key:        "0100000000000000000000000000000000000000000000000000000000000000",
+		nonce:      "030000000000000000000000",
+		data:       "",
+		msg:        "",
+		ciphertext: "07f5f4169bbf55a8400cd47ea6fd400f",
+	},
+	{
+		key:   "0000000000000000000000000000000000000000000000000000000000000000",
+		nonce: "000000000000000000000000",
+		data:  "",
+		msg:   "000000000000000000000000000000004db923dc793ee6497c76dcc03a98e108",
+		ciphertext: "f3f80f2cf0cb2dd9c5984fcda908456cc537703b5ba70324a6793a7bf218d3ea" +
+			"ffffffff000000000000000000000000",
+	},
+}
+
+func TestGCMSIVVectors(t *testing.T) {
+	for i, v := range gcmSIVVectors {
+		key, nonce := fromHex(v.key), fromHex(v.nonce)
+		data, msg := fromHex(v.data), fromHex(v.msg)
+		ciphertext := fromHex(v.ciphertext)
+
+		c, err := NewGCMSIV(key, aes.NewCipher)
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create GCM-SIV instance: %s", i, err)
+		}
+
+		buf := c.Seal(nil, nonce, msg, data)
+		if !bytes.Equal(buf, ciphertext) {
+			t.Fatalf("TestVector %d Seal failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(ciphertext))
+		}
+
+		buf, err = c.Open(buf[:0], nonce, buf, data)
+		if err != nil {
+			t.Fatalf("TestVector %d: Open failed: %s", i, err)
+		}
+		if !bytes.Equal(buf, msg) {
+			t.Fatalf("TestVector %d Open failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(msg))
+		}
+	}
+}
+
+// TestGCMSIV cross-checks Seal and Open with random
+// keys, nonces, messages and additional data.
+func TestGCMSIV(t *testing.T) {
+	ciphers := []struct {
+		name      string
+		newCipher func([]byte) (cipher.Block, error)
+	}{
+		{"AES", aes.NewCipher},
+		{"Camellia", camellia.NewCipher},
+		{"Serpent", serpent.NewCipher},
+	}
+	rng := rand.New(rand.NewSource(0))
+	for _, v := range ciphers {
+		for i := 0; i < 64; i++ {
+			key := make([]byte, 16+16*rng.Intn(2))
+			nonce := make([]byte, GCMSIVNonceSize)
+			msg, data := make([]byte, rng.Intn(300)), make([]byte, rng.Intn(50))
+			rng.Read(key)
+			rng.Read(nonce)
+			rng.Read(msg)
+			rng.Read(data)
+
+			c, err := NewGCMSIV(key, v.newCipher)
+			if err != nil {
+				t.Fatalf("%s: Failed to create GCM-SIV instance: %s", v.name, err)
+			}
+			ciphertext := c.Seal(nil, nonce, msg, data)
+			plaintext, err := c.Open(nil, nonce, ciphertext, data)
+			if err != nil {
+				t.Fatalf("%s: Open failed: %s", v.name, err)
+			}
+			if !bytes.Equal(plaintext, msg) {
+				t.Fatalf("%s: Open failed:\nFound   : %s\nExpected: %s", v.name, hex.EncodeToString(plaintext), hex.EncodeToString(msg))
+			}
+
+			ciphertext[rng.Intn(len(ciphertext))] ^= byte(1 + rng.Intn(255))
+			if _, err = c.Open(nil, nonce, ciphertext, data); err == nil {
+				t.Fatalf("%s: Open accepted modified ciphertext", v.name)
+			}
+		}
+	}
+}
+
+func TestNewGCMSIV(t *testing.T) {
+	for _, k := range []int{0, 8, 15, 17, 24, 33} {
+		if _, err := NewGCMSIV(make([]byte, k), aes.NewCipher); err == nil {
+			t.Fatalf("NewGCMSIV accepted key size %d", k)
+		}
+	}
+	if _, err := NewGCMSIV(make([]byte, 16), nil); err == nil {
+		t.Fatal("NewGCMSIV accepted nil cipher constructor")
+	}
+}
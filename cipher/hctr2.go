@@ -0,0 +1,141 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+// HCTR2BlockSize is the block size of HCTR2 in bytes.
+// Every message must be at least HCTR2BlockSize bytes long.
+const HCTR2BlockSize = 16
+
+// HCTR2 is a tweakable, length-preserving wide-block cipher. Every bit of
+// the ciphertext depends on every bit of the plaintext and the tweak, so
+// HCTR2 can be used to encrypt filenames or sectors without expanding them.
+type HCTR2 interface {
+	// Encrypt encrypts the message in src using the tweak and writes the
+	// ciphertext into dst. The length of src must be at least HCTR2BlockSize
+	// bytes and dst must be at least as large as src. Dst and src may overlap
+	// entirely or not at all.
+	Encrypt(dst, src, tweak []byte)
+
+	// Decrypt decrypts the message in src using the tweak and writes the
+	// plaintext into dst. The length of src must be at least HCTR2BlockSize
+	// bytes and dst must be at least as large as src. Dst and src may overlap
+	// entirely or not at all.
+	Decrypt(dst, src, tweak []byte)
+}
+
+// The HCTR2 cipher
+type hctr2 struct {
+	blockCipher cipher.Block
+	hashKey     [16]byte // the POLYVAL key: E(bin(0))
+	l           [16]byte // E(bin(1))
+}
+
+// NewHCTR2 returns a HCTR2 implementing the HCTR2 construction
+// (POLYVAL hash and XCTR mode) specified in "Length-preserving
+// encryption with HCTR2" (https://eprint.iacr.org/2021/1441) wrapping
+// the cipher.Block. The block size of the cipher must be 128 bit -
+// e.g. AES, Camellia or Serpent.
+func NewHCTR2(c cipher.Block) (HCTR2, error) {
+	if c == nil {
+		return nil, errors.New("the cipher.Block must not be nil")
+	}
+	if c.BlockSize() != HCTR2BlockSize {
+		return nil, errors.New("cipher block size not supported")
+	}
+	h := &hctr2{blockCipher: c}
+	c.Encrypt(h.hashKey[:], h.hashKey[:])
+	h.l[0] = 1
+	c.Encrypt(h.l[:], h.l[:])
+	return h, nil
+}
+
+func (h *hctr2) Encrypt(dst, src, tweak []byte) {
+	checkHCTR2Args(dst, src)
+
+	var mm, uu, s [16]byte
+	h.hash(&mm, tweak, src[16:])
+	crypto.XOR(mm[:], mm[:], src[:16])
+	h.blockCipher.Encrypt(uu[:], mm[:])
+
+	crypto.XOR(s[:], mm[:], uu[:])
+	crypto.XOR(s[:], s[:], h.l[:])
+	h.xctr(dst[16:len(src)], src[16:], &s)
+
+	h.hash(&mm, tweak, dst[16:len(src)])
+	crypto.XOR(dst[:16], uu[:], mm[:])
+}
+
+func (h *hctr2) Decrypt(dst, src, tweak []byte) {
+	checkHCTR2Args(dst, src)
+
+	var mm, uu, s [16]byte
+	h.hash(&uu, tweak, src[16:])
+	crypto.XOR(uu[:], uu[:], src[:16])
+	h.blockCipher.Decrypt(mm[:], uu[:])
+
+	crypto.XOR(s[:], mm[:], uu[:])
+	crypto.XOR(s[:], s[:], h.l[:])
+	h.xctr(dst[16:len(src)], src[16:], &s)
+
+	h.hash(&uu, tweak, dst[16:len(src)])
+	crypto.XOR(dst[:16], mm[:], uu[:])
+}
+
+// hash computes the POLYVAL hash of the tweak and the msg:
+// POLYVAL(bin(2|T| + 2) || pad(T) || msg) if msg is a multiple of the
+// block size and POLYVAL(bin(2|T| + 3) || pad(T) || pad(msg || 1)) otherwise.
+func (h *hctr2) hash(out *[16]byte, tweak, msg []byte) {
+	var p polyval
+	p.init(&h.hashKey)
+
+	var block [16]byte
+	n := uint64(len(tweak))*16 + 2
+	if len(msg)%16 != 0 {
+		n++
+	}
+	binary.LittleEndian.PutUint64(block[:], n)
+	p.update(block[:])
+	p.update(tweak)
+
+	k := len(msg) - len(msg)%16
+	p.update(msg[:k])
+	if k < len(msg) {
+		block = [16]byte{}
+		block[copy(block[:], msg[k:])] = 1
+		p.update(block[:])
+	}
+	p.sum(out)
+}
+
+// xctr encrypts the bytes in src with the XCTR mode - the i-th key
+// stream block is E(S xor bin(i)) starting with i = 1 - and writes
+// the ciphertext into dst.
+func (h *hctr2) xctr(dst, src []byte, s *[16]byte) {
+	var ctr, block [16]byte
+	for i := uint64(1); len(src) > 0; i++ {
+		ctr = *s
+		binary.LittleEndian.PutUint64(ctr[:], binary.LittleEndian.Uint64(ctr[:])^i)
+		h.blockCipher.Encrypt(block[:], ctr[:])
+		k := crypto.XOR(dst, src, block[:])
+		dst, src = dst[k:], src[k:]
+	}
+}
+
+// checkHCTR2Args panics if the length of dst or src is not valid.
+func checkHCTR2Args(dst, src []byte) {
+	if len(src) < HCTR2BlockSize {
+		panic("src must be at least one block long")
+	}
+	if len(dst) < len(src) {
+		panic("dst buffer to small")
+	}
+}
@@ -0,0 +1,246 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"math/big"
+	"testing"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/camellia"
+	"github.com/enceve/crypto/serpent"
+)
+
+func testHCTR2(t *testing.T, name string, c cipher.Block) {
+	h, err := NewHCTR2(c)
+	if err != nil {
+		t.Fatalf("%s: Failed to create HCTR2 instance: %s", name, err)
+	}
+
+	msg := make([]byte, 100)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	tweak := make([]byte, 33)
+	for i := range tweak {
+		tweak[i] = byte(255 - i)
+	}
+	for _, tweakSize := range []int{0, 1, 16, 17, 32, 33} {
+		for size := HCTR2BlockSize; size <= len(msg); size++ {
+			ciphertext := make([]byte, size)
+			h.Encrypt(ciphertext, msg[:size], tweak[:tweakSize])
+
+			buf := append([]byte{}, msg[:size]...)
+			h.Encrypt(buf, buf, tweak[:tweakSize])
+			if !bytes.Equal(buf, ciphertext) {
+				t.Fatalf("%s: Tweak size %d - size %d: in-place encryption failed", name, tweakSize, size)
+			}
+
+			h.Decrypt(buf, buf, tweak[:tweakSize])
+			if !bytes.Equal(buf, msg[:size]) {
+				t.Fatalf("%s: Tweak size %d - size %d: decryption failed", name, tweakSize, size)
+			}
+		}
+	}
+}
+
+func TestHCTR2(t *testing.T) {
+	for _, keysize := range []int{16, 24, 32} {
+		c, err := aes.NewCipher(make([]byte, keysize))
+		if err != nil {
+			t.Fatalf("Failed to create AES instance: %s", err)
+		}
+		testHCTR2(t, "AES", c)
+
+		c, err = camellia.NewCipher(make([]byte, keysize))
+		if err != nil {
+			t.Fatalf("Failed to create Camellia instance: %s", err)
+		}
+		testHCTR2(t, "Camellia", c)
+
+		c, err = serpent.NewCipher(make([]byte, keysize))
+		if err != nil {
+			t.Fatalf("Failed to create Serpent instance: %s", err)
+		}
+		testHCTR2(t, "Serpent", c)
+	}
+}
+
+// refPolyval computes POLYVAL(H, X_1, ..., X_n) bit by bit following
+// RFC 8452 (Section 3) - independent from the polyval type.
+func refPolyval(key, msg []byte) []byte {
+	toInt := func(b []byte) *big.Int {
+		r := make([]byte, len(b))
+		for i := range b {
+			r[len(b)-1-i] = b[i]
+		}
+		return new(big.Int).SetBytes(r)
+	}
+	// x^128 + x^127 + x^126 + x^121 + 1
+	poly := new(big.Int).SetBit(new(big.Int), 128, 1)
+	poly.SetBit(poly, 127, 1).SetBit(poly, 126, 1).SetBit(poly, 121, 1).SetBit(poly, 0, 1)
+
+	h, s := toInt(key), new(big.Int)
+	for len(msg) > 0 {
+		a := s.Xor(s, toInt(msg[:16]))
+		r := new(big.Int)
+		for i := 127; i >= 0; i-- { // r = a * h mod poly
+			r.Lsh(r, 1)
+			if r.Bit(128) == 1 {
+				r.Xor(r, poly)
+			}
+			if h.Bit(i) == 1 {
+				r.Xor(r, a)
+			}
+		}
+		for i := 0; i < 128; i++ { // r = r * x^-128 mod poly
+			if r.Bit(0) == 1 {
+				r.Xor(r, poly)
+			}
+			r.Rsh(r, 1)
+		}
+		s, msg = r, msg[16:]
+	}
+
+	out, b := make([]byte, 16), s.Bytes()
+	for i := range b {
+		out[i] = b[len(b)-1-i]
+	}
+	return out
+}
+
+// refHCTR2 encrypts msg with HCTR2 following the pseudo code
+// of the HCTR2 paper (Figure 1) using refPolyval.
+func refHCTR2(c cipher.Block, msg, tweak []byte) []byte {
+	hashKey, l := make([]byte, 16), make([]byte, 16)
+	c.Encrypt(hashKey, hashKey)
+	l[0] = 1
+	c.Encrypt(l, l)
+
+	hash := func(n []byte) []byte {
+		in := make([]byte, 16, 16+len(tweak)+len(n)+32)
+		length := 16*len(tweak) + 2
+		if len(n)%16 != 0 {
+			length++
+		}
+		in[0], in[1] = byte(length), byte(length>>8)
+		in = append(in, tweak...)
+		in = append(in, make([]byte, (16-len(tweak)%16)%16)...)
+		in = append(in, n...)
+		if len(n)%16 != 0 {
+			in = append(in, 1)
+			in = append(in, make([]byte, (16-len(in)%16)%16)...)
+		}
+		return refPolyval(hashKey, in)
+	}
+
+	out := make([]byte, len(msg))
+	mm, uu := make([]byte, 16), make([]byte, 16)
+	crypto.XOR(mm, msg[:16], hash(msg[16:]))
+	c.Encrypt(uu, mm)
+
+	s, block := make([]byte, 16), make([]byte, 16)
+	crypto.XOR(s, mm, uu)
+	crypto.XOR(s, s, l)
+	for i := 16; i < len(msg); i += 16 {
+		copy(block, s)
+		block[0] ^= byte(i / 16)
+		c.Encrypt(block, block)
+		crypto.XOR(out[i:], msg[i:], block)
+	}
+	crypto.XOR(out[:16], uu, hash(out[16:]))
+	return out
+}
+
+// TestHCTR2Reference compares HCTR2 with a straightforward implementation
+// of the HCTR2 paper which does not use the polyval type of this package.
+// The reference POLYVAL is checked against RFC 8452 (Appendix A).
+func TestHCTR2Reference(t *testing.T) {
+	key := fromHex("25629347589242761d31f826ba4b757b")
+	msg := fromHex("4f4f95668c83dfb6401762bb2d01a262d1a24ddd2721d006bbe45f20d3c9f362")
+	ref := fromHex("f7a3b47b846119fae5b7866cf5e5b77e")
+	if sum := refPolyval(key, msg); !bytes.Equal(sum, ref) {
+		t.Fatalf("Reference POLYVAL failed:\nFound   : %x\nExpected: %x", sum, ref)
+	}
+
+	c, err := aes.NewCipher(fromHex("000102030405060708090A0B0C0D0E0F"))
+	if err != nil {
+		t.Fatalf("Failed to create AES instance: %s", err)
+	}
+	h, err := NewHCTR2(c)
+	if err != nil {
+		t.Fatalf("Failed to create HCTR2 instance: %s", err)
+	}
+	msg = make([]byte, 100)
+	for i := range msg {
+		msg[i] = byte(i * 7)
+	}
+	tweak := []byte("HCTR2 reference tweak - longer than two blocks")
+	for _, tweakSize := range []int{0, 5, 16, 33, len(tweak)} {
+		for _, size := range []int{16, 17, 31, 32, 33, 100} {
+			expected := refHCTR2(c, msg[:size], tweak[:tweakSize])
+			ciphertext := make([]byte, size)
+			h.Encrypt(ciphertext, msg[:size], tweak[:tweakSize])
+			if !bytes.Equal(ciphertext, expected) {
+				t.Fatalf("Tweak size %d - size %d: HCTR2 encryption failed:\nFound   : %x\nExpected: %x", tweakSize, size, ciphertext, expected)
+			}
+		}
+	}
+}
+
+func TestHCTR2Diffusion(t *testing.T) {
+	c, err := aes.NewCipher(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("Failed to create AES instance: %s", err)
+	}
+	h, err := NewHCTR2(c)
+	if err != nil {
+		t.Fatalf("Failed to create HCTR2 instance: %s", err)
+	}
+	msg, tweak := make([]byte, 67), make([]byte, 8)
+	ciphertext := make([]byte, len(msg))
+	h.Encrypt(ciphertext, msg, tweak)
+
+	// modifying any byte of the message or the tweak must change every block
+	modified := make([]byte, len(msg))
+	for i := 0; i < len(msg)+len(tweak); i++ {
+		if i < len(msg) {
+			msg[i] ^= 1
+		} else {
+			tweak[i-len(msg)] ^= 1
+		}
+		h.Encrypt(modified, msg, tweak)
+		for j := 0; j < len(msg); j += 16 {
+			k := j + 16
+			if k > len(msg) {
+				k = len(msg)
+			}
+			if bytes.Equal(modified[j:k], ciphertext[j:k]) {
+				t.Fatalf("Modification of byte %d does not change the ciphertext block %d", i, j/16)
+			}
+		}
+		if i < len(msg) {
+			msg[i] ^= 1
+		} else {
+			tweak[i-len(msg)] ^= 1
+		}
+	}
+}
+
+func TestNewHCTR2(t *testing.T) {
+	if _, err := NewHCTR2(nil); err == nil {
+		t.Fatal("NewHCTR2 accepted a nil cipher.Block")
+	}
+	c, err := des.NewCipher(make([]byte, 8))
+	if err != nil {
+		t.Fatalf("Failed to create DES instance: %s", err)
+	}
+	if _, err = NewHCTR2(c); err == nil {
+		t.Fatal("NewHCTR2 accepted a 64 bit block cipher")
+	}
+}
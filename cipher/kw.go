@@ -0,0 +1,178 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+// The initial values of the key wrap (RFC 3394)
+// and the key wrap with padding (RFC 5649).
+var (
+	kwIV  = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+	kwpIV = []byte{0xa6, 0x59, 0x59, 0xa6}
+)
+
+// WrapKey wraps the key with the key encryption key (KEK) c using
+// the key wrap algorithm specified in RFC 3394 (NIST SP 800-38F KW).
+// The block size of the cipher must be 128 bit (like AES or Camellia -
+// see RFC 3657). The length of the key must be a multiple of 8 and
+// at least 16 bytes. The wrapped key is 8 bytes longer than the key.
+func WrapKey(c cipher.Block, key []byte) ([]byte, error) {
+	if c == nil {
+		return nil, errors.New("the cipher.Block must not be nil")
+	}
+	if c.BlockSize() != 16 {
+		return nil, errors.New("cipher block size not supported")
+	}
+	if n := len(key); n < 16 || n%8 != 0 {
+		return nil, crypto.KeySizeError(n)
+	}
+	wrapped := make([]byte, 8+len(key))
+	copy(wrapped, kwIV)
+	copy(wrapped[8:], key)
+	kwWrap(c, wrapped)
+	return wrapped, nil
+}
+
+// UnwrapKey unwraps the wrapped key with the key encryption key (KEK) c
+// using the key unwrap algorithm specified in RFC 3394 (NIST SP 800-38F KW).
+// If the integrity check fails, a crypto.AuthenticationError is returned.
+func UnwrapKey(c cipher.Block, wrapped []byte) ([]byte, error) {
+	if c == nil {
+		return nil, errors.New("the cipher.Block must not be nil")
+	}
+	if c.BlockSize() != 16 {
+		return nil, errors.New("cipher block size not supported")
+	}
+	if n := len(wrapped); n < 24 || n%8 != 0 {
+		return nil, crypto.AuthenticationError{}
+	}
+	buf := make([]byte, len(wrapped))
+	copy(buf, wrapped)
+	kwUnwrap(c, buf)
+
+	if subtle.ConstantTimeCompare(buf[:8], kwIV) != 1 {
+		return nil, crypto.AuthenticationError{}
+	}
+	return buf[8:], nil
+}
+
+// WrapKeyWithPadding wraps the key with the key encryption key (KEK) c
+// using the key wrap with padding algorithm specified in RFC 5649
+// (NIST SP 800-38F KWP). The block size of the cipher must be 128 bit
+// (like AES or Camellia). The key must not be empty and not be
+// longer than 2^32 - 1 bytes.
+func WrapKeyWithPadding(c cipher.Block, key []byte) ([]byte, error) {
+	if c == nil {
+		return nil, errors.New("the cipher.Block must not be nil")
+	}
+	if c.BlockSize() != 16 {
+		return nil, errors.New("cipher block size not supported")
+	}
+	if n := uint64(len(key)); n == 0 || n > 1<<32-1 {
+		return nil, crypto.KeySizeError(len(key))
+	}
+
+	n := len(key)
+	wrapped := make([]byte, 8+(n+7)/8*8)
+	copy(wrapped, kwpIV)
+	wrapped[4], wrapped[5], wrapped[6], wrapped[7] = byte(n>>24), byte(n>>16), byte(n>>8), byte(n)
+	copy(wrapped[8:], key)
+
+	if len(wrapped) == 16 {
+		c.Encrypt(wrapped, wrapped)
+	} else {
+		kwWrap(c, wrapped)
+	}
+	return wrapped, nil
+}
+
+// UnwrapKeyWithPadding unwraps the wrapped key with the key encryption
+// key (KEK) c using the key unwrap with padding algorithm specified in
+// RFC 5649 (NIST SP 800-38F KWP). If the integrity check fails,
+// a crypto.AuthenticationError is returned.
+func UnwrapKeyWithPadding(c cipher.Block, wrapped []byte) ([]byte, error) {
+	if c == nil {
+		return nil, errors.New("the cipher.Block must not be nil")
+	}
+	if c.BlockSize() != 16 {
+		return nil, errors.New("cipher block size not supported")
+	}
+	if n := len(wrapped); n < 16 || n%8 != 0 {
+		return nil, crypto.AuthenticationError{}
+	}
+	buf := make([]byte, len(wrapped))
+	copy(buf, wrapped)
+	if len(buf) == 16 {
+		c.Decrypt(buf, buf)
+	} else {
+		kwUnwrap(c, buf)
+	}
+
+	ok := subtle.ConstantTimeCompare(buf[:4], kwpIV)
+
+	// the length must satisfy: len(buf) - 16 < n <= len(buf) - 8
+	n := int64(uint32(buf[4])<<24 | uint32(buf[5])<<16 | uint32(buf[6])<<8 | uint32(buf[7]))
+	padded := int64(len(buf) - 8)
+	ok &= int(^uint64((n-padded+7)|(padded-n)) >> 63)
+
+	// the padding bytes must be zero
+	var pad byte
+	for i := int64(8); i < int64(len(buf)); i++ {
+		isPad := byte(^uint64(i-n-8) >> 63)
+		pad |= buf[i] & -isPad
+	}
+	ok &= subtle.ConstantTimeByteEq(pad, 0)
+
+	if ok != 1 {
+		return nil, crypto.AuthenticationError{}
+	}
+	return buf[8 : 8+n], nil
+}
+
+// kwWrap computes the wrapping function W of RFC 3394 in place.
+// The first 8 bytes of buf must contain the initial value and
+// the remaining bytes must contain the (padded) key.
+func kwWrap(c cipher.Block, buf []byte) {
+	var block [16]byte
+	n := uint64(len(buf)/8 - 1)
+	copy(block[:8], buf[:8])
+	for j := uint64(0); j < 6; j++ {
+		for i := uint64(1); i <= n; i++ {
+			copy(block[8:], buf[8*i:8*i+8])
+			c.Encrypt(block[:], block[:])
+			t := n*j + i
+			for k := 0; k < 8; k++ {
+				block[7-k] ^= byte(t >> uint(8*k))
+			}
+			copy(buf[8*i:], block[8:])
+		}
+	}
+	copy(buf[:8], block[:8])
+}
+
+// kwUnwrap computes the unwrapping function W^-1 of RFC 3394 in place.
+// The first 8 bytes of buf contain the integrity check value afterwards.
+func kwUnwrap(c cipher.Block, buf []byte) {
+	var block [16]byte
+	n := uint64(len(buf)/8 - 1)
+	copy(block[:8], buf[:8])
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := n*uint64(j) + i
+			for k := 0; k < 8; k++ {
+				block[7-k] ^= byte(t >> uint(8*k))
+			}
+			copy(block[8:], buf[8*i:8*i+8])
+			c.Decrypt(block[:], block[:])
+			copy(buf[8*i:], block[8:])
+		}
+	}
+	copy(buf[:8], block[:8])
+}
@@ -0,0 +1,196 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"testing"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/camellia"
+)
+
+type kwTestVector struct {
+	kek, key, wrapped string
+}
+
+// AES key wrap test vectors from RFC 3394 (Section 4)
+var kwAESVectors = []kwTestVector{
+	{
+		kek:     "000102030405060708090A0B0C0D0E0F",
+		key:     "00112233445566778899AABBCCDDEEFF",
+		wrapped: "1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5",
+	},
+	{
+		kek:     "000102030405060708090A0B0C0D0E0F1011121314151617",
+		key:     "00112233445566778899AABBCCDDEEFF",
+		wrapped: "96778B25AE6CA435F92B5B97C050AED2468AB8A17AD84E5D",
+	},
+	{
+		kek:     "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		key:     "00112233445566778899AABBCCDDEEFF",
+		wrapped: "64E8C3F9CE0F5BA263E9777905818A2A93C8191E7D6E8AE7",
+	},
+	{
+		kek:     "000102030405060708090A0B0C0D0E0F1011121314151617",
+		key:     "00112233445566778899AABBCCDDEEFF0001020304050607",
+		wrapped: "031D33264E15D33268F24EC260743EDCE1C6C7DDEE725A936BA814915C6762D2",
+	},
+	{
+		kek:     "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		key:     "00112233445566778899AABBCCDDEEFF0001020304050607",
+		wrapped: "A8F9BC1612C68B3FF6E6F4FBE30E71E4769C8B80A32CB8958CD5D17D6B254DA1",
+	},
+	{
+		kek:     "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		key:     "00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F",
+		wrapped: "28C9F404C4B810F4CBCCB35CFB87F8263F5786E2D80ED326CBC7F0E71A99F43BFB988B9B7A02DD21",
+	},
+}
+
+// Camellia key wrap test vectors from RFC 3657 (Section 4)
+var kwCamelliaVectors = []kwTestVector{
+	{
+		kek:     "000102030405060708090A0B0C0D0E0F",
+		key:     "00112233445566778899AABBCCDDEEFF",
+		wrapped: "635D6AC46EEDEBD3A7F4A06421A4CBD1746B24795BA2F708",
+	},
+	{
+		kek:     "000102030405060708090A0B0C0D0E0F1011121314151617",
+		key:     "00112233445566778899AABBCCDDEEFF",
+		wrapped: "FE8F5C4E2164CDFE36233C9F898F93DF6E6F1D892D187742",
+	},
+	{
+		kek:     "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		key:     "00112233445566778899AABBCCDDEEFF",
+		wrapped: "B43E6793EE3B35B7698253B26BAD0CA2D5E7793C6F5DDD48",
+	},
+	{
+		kek:     "000102030405060708090A0B0C0D0E0F1011121314151617",
+		key:     "00112233445566778899AABBCCDDEEFF0001020304050607",
+		wrapped: "EA7B7515BDE2F268849FA2B4D96ADBACC8111073D463DA9FB5E7648F6DD2FE76",
+	},
+	{
+		kek:     "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		key:     "00112233445566778899AABBCCDDEEFF0001020304050607",
+		wrapped: "C7CB865E14A7DC00B339F9D9041ED4C3BA4E34EEDADD7A1C5F98534180CD59BE",
+	},
+	{
+		kek:     "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		key:     "00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F",
+		wrapped: "96A502A1E0C12700EC01D9E9B3688D50B7AE25FBAE06DD18F0E30092AC1ABD5BC7575DA930DF1636",
+	},
+}
+
+// AES key wrap with padding test vectors from RFC 5649 (Section 6)
+var kwpAESVectors = []kwTestVector{
+	{
+		kek:     "5840DF6E29B02AF1AB493B705BF16EA1AE8338F4DCC176A8",
+		key:     "C37B7E6492584340BED12207808941155068F738",
+		wrapped: "138BDEAA9B8FA7FC61F97742E72248EE5AE6AE5360D1AE6A5F54F373FA543B6A",
+	},
+	{
+		kek:     "5840DF6E29B02AF1AB493B705BF16EA1AE8338F4DCC176A8",
+		key:     "466F7250617369",
+		wrapped: "AFBEB0F07DFBF5419200F2CCB50BB24F",
+	},
+}
+
+func testKWVectors(t *testing.T, name string, vectors []kwTestVector, newCipher func([]byte) (cipher.Block, error), padding bool) {
+	wrap, unwrap := WrapKey, UnwrapKey
+	if padding {
+		wrap, unwrap = WrapKeyWithPadding, UnwrapKeyWithPadding
+	}
+	for i, v := range vectors {
+		kek, key, wrapped := fromHex(v.kek), fromHex(v.key), fromHex(v.wrapped)
+
+		block, err := newCipher(kek)
+		if err != nil {
+			t.Fatalf("%s: TestVector %d: Failed to create cipher instance: %s", name, i, err)
+		}
+
+		buf, err := wrap(block, key)
+		if err != nil {
+			t.Fatalf("%s: TestVector %d: Wrap failed: %s", name, i, err)
+		}
+		if !bytes.Equal(buf, wrapped) {
+			t.Fatalf("%s: TestVector %d Wrap failed:\nFound   : %s\nExpected: %s", name, i, hex.EncodeToString(buf), hex.EncodeToString(wrapped))
+		}
+
+		buf, err = unwrap(block, wrapped)
+		if err != nil {
+			t.Fatalf("%s: TestVector %d: Unwrap failed: %s", name, i, err)
+		}
+		if !bytes.Equal(buf, key) {
+			t.Fatalf("%s: TestVector %d Unwrap failed:\nFound   : %s\nExpected: %s", name, i, hex.EncodeToString(buf), hex.EncodeToString(key))
+		}
+
+		for j := range wrapped {
+			wrapped[j] ^= 0x01
+			if _, err = unwrap(block, wrapped); err != (crypto.AuthenticationError{}) {
+				t.Fatalf("%s: TestVector %d: Unwrap accepted modified input - error: %v", name, i, err)
+			}
+			wrapped[j] ^= 0x01
+		}
+	}
+}
+
+func TestKWVectors(t *testing.T) {
+	testKWVectors(t, "KW-AES", kwAESVectors, aes.NewCipher, false)
+	testKWVectors(t, "KW-Camellia", kwCamelliaVectors, camellia.NewCipher, false)
+	testKWVectors(t, "KWP-AES", kwpAESVectors, aes.NewCipher, true)
+}
+
+func TestKWP(t *testing.T) {
+	block, err := camellia.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create Camellia instance: %s", err)
+	}
+	key := make([]byte, 64)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	for i := 1; i <= len(key); i++ {
+		wrapped, err := WrapKeyWithPadding(block, key[:i])
+		if err != nil {
+			t.Fatalf("Wrap failed: %s", err)
+		}
+		if n := 8 + (i+7)/8*8; len(wrapped) != n {
+			t.Fatalf("Wrap returned %d bytes - expected %d bytes", len(wrapped), n)
+		}
+		unwrapped, err := UnwrapKeyWithPadding(block, wrapped)
+		if err != nil {
+			t.Fatalf("Unwrap failed: %s", err)
+		}
+		if !bytes.Equal(unwrapped, key[:i]) {
+			t.Fatalf("Unwrap failed:\nFound   : %s\nExpected: %s", hex.EncodeToString(unwrapped), hex.EncodeToString(key[:i]))
+		}
+	}
+}
+
+func TestKWBadSizes(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES instance: %s", err)
+	}
+	for _, n := range []int{0, 8, 15, 17, 23} {
+		if _, err = WrapKey(block, make([]byte, n)); err == nil {
+			t.Fatalf("WrapKey accepted a %d byte key", n)
+		}
+		if _, err = UnwrapKey(block, make([]byte, n)); err == nil {
+			t.Fatalf("UnwrapKey accepted a %d byte wrapped key", n)
+		}
+	}
+	if _, err = WrapKeyWithPadding(block, nil); err == nil {
+		t.Fatal("WrapKeyWithPadding accepted an empty key")
+	}
+	for _, n := range []int{0, 8, 17} {
+		if _, err = UnwrapKeyWithPadding(block, make([]byte, n)); err == nil {
+			t.Fatalf("UnwrapKeyWithPadding accepted a %d byte wrapped key", n)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+// The number of precomputed L_i values. This limits the max. number of blocks
+// (of the plaintext or additional data) to 2^ocbTableSize - 1.
+const ocbTableSize = 64
+
+// The OCB3 cipher
+type ocbCipher struct {
+	blockCipher     cipher.Block
+	lStar, lDollar  []byte
+	l               [ocbTableSize][]byte
+	nonceSize, size int
+}
+
+// NewOCB returns a cipher.AEAD implementing the OCB3 mode
+// specified in RFC 7253 wrapping the cipher.Block. OCB3 is
+// a one-pass scheme AEAD cipher with provable security.
+// The block size of the cipher must be 128 bit (like AES,
+// Camellia or Serpent).
+// The noncesize argument specifies the length of the nonce in bytes
+// and must be between 1 and 15. The tagsize argument specifies the
+// number of bytes of the auth. tag and must be between 1 and 16.
+func NewOCB(c cipher.Block, noncesize, tagsize int) (cipher.AEAD, error) {
+	if c == nil {
+		return nil, errors.New("the cipher.Block must not be nil")
+	}
+	if c.BlockSize() != 16 {
+		return nil, errors.New("cipher block size not supported")
+	}
+	if noncesize < 1 || noncesize > 15 {
+		return nil, errors.New("noncesize must between 1 and 15")
+	}
+	if tagsize < 1 || tagsize > 16 {
+		return nil, errors.New("tagSize must between 1 and 16")
+	}
+
+	o := &ocbCipher{
+		blockCipher: c,
+		lStar:       make([]byte, 16),
+		lDollar:     make([]byte, 16),
+		nonceSize:   noncesize,
+		size:        tagsize,
+	}
+	c.Encrypt(o.lStar, o.lStar)
+	double(o.lDollar, o.lStar)
+
+	prev := o.lDollar
+	for i := range o.l {
+		o.l[i] = make([]byte, 16)
+		double(o.l[i], prev)
+		prev = o.l[i]
+	}
+	return o, nil
+}
+
+func (c *ocbCipher) NonceSize() int { return c.nonceSize }
+
+func (c *ocbCipher) Overhead() int { return c.size }
+
+func (c *ocbCipher) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if n := len(nonce); n != c.nonceSize {
+		panic(crypto.NonceSizeError(n))
+	}
+	ret, out := sliceForAppend(dst, len(plaintext)+c.size)
+
+	offset, checksum := c.initOffset(nonce), make([]byte, 16)
+	n := len(plaintext) - (len(plaintext) % 16)
+
+	var i uint64
+	for j := 0; j < n; j += 16 {
+		i++
+		crypto.XOR(offset, offset, c.l[ntz(i)])
+		crypto.XOR(checksum, checksum, plaintext[j:j+16])
+
+		crypto.XOR(out[j:], plaintext[j:j+16], offset)
+		c.blockCipher.Encrypt(out[j:], out[j:j+16])
+		crypto.XOR(out[j:], out[j:j+16], offset)
+	}
+
+	if n < len(plaintext) {
+		pad := make([]byte, 16)
+		crypto.XOR(offset, offset, c.lStar)
+		c.blockCipher.Encrypt(pad, offset)
+
+		r := crypto.XOR(checksum, checksum, plaintext[n:])
+		checksum[r] ^= 0x80
+		crypto.XOR(out[n:], plaintext[n:], pad)
+	}
+
+	crypto.XOR(checksum, checksum, offset)
+	crypto.XOR(checksum, checksum, c.lDollar)
+	c.blockCipher.Encrypt(checksum, checksum)
+
+	c.hash(checksum, additionalData)
+	copy(out[len(plaintext):], checksum[:c.size])
+	return ret
+}
+
+func (c *ocbCipher) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if n := len(nonce); n != c.nonceSize {
+		return nil, crypto.NonceSizeError(n)
+	}
+	if len(ciphertext) < c.size {
+		return nil, crypto.AuthenticationError{}
+	}
+
+	hash := ciphertext[len(ciphertext)-c.size:]
+	ciphertext = ciphertext[:len(ciphertext)-c.size]
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+
+	offset, checksum := c.initOffset(nonce), make([]byte, 16)
+	n := len(ciphertext) - (len(ciphertext) % 16)
+
+	var i uint64
+	for j := 0; j < n; j += 16 {
+		i++
+		crypto.XOR(offset, offset, c.l[ntz(i)])
+
+		crypto.XOR(out[j:], ciphertext[j:j+16], offset)
+		c.blockCipher.Decrypt(out[j:], out[j:j+16])
+		crypto.XOR(out[j:], out[j:j+16], offset)
+
+		crypto.XOR(checksum, checksum, out[j:j+16])
+	}
+
+	if n < len(ciphertext) {
+		pad := make([]byte, 16)
+		crypto.XOR(offset, offset, c.lStar)
+		c.blockCipher.Encrypt(pad, offset)
+
+		r := crypto.XOR(out[n:], ciphertext[n:], pad)
+		crypto.XOR(checksum, checksum, out[n:n+r])
+		checksum[r] ^= 0x80
+	}
+
+	crypto.XOR(checksum, checksum, offset)
+	crypto.XOR(checksum, checksum, c.lDollar)
+	c.blockCipher.Encrypt(checksum, checksum)
+
+	c.hash(checksum, additionalData)
+	if subtle.ConstantTimeCompare(checksum[:c.size], hash) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, crypto.AuthenticationError{}
+	}
+	return ret, nil
+}
+
+// initOffset computes the initial offset (Offset_0)
+// from the nonce and the tag size.
+func (c *ocbCipher) initOffset(nonce []byte) []byte {
+	var block [16]byte
+	copy(block[16-len(nonce):], nonce)
+	block[15-len(nonce)] |= 1
+	block[0] |= byte(((c.size * 8) % 128) << 1)
+
+	bottom := uint(block[15] & 63)
+	block[15] &^= 63
+
+	var stretch [24]byte
+	c.blockCipher.Encrypt(stretch[:16], block[:])
+	for i := 0; i < 8; i++ {
+		stretch[16+i] = stretch[i] ^ stretch[i+1]
+	}
+
+	offset := make([]byte, 16)
+	byteShift, bitShift := bottom/8, bottom%8
+	for i := range offset {
+		offset[i] = stretch[uint(i)+byteShift] << bitShift
+		if bitShift > 0 {
+			offset[i] |= stretch[uint(i)+byteShift+1] >> (8 - bitShift)
+		}
+	}
+	return offset
+}
+
+// hash computes the OCB3 hash of the additional
+// data and xors it into dst.
+func (c *ocbCipher) hash(dst, additionalData []byte) {
+	offset, block := make([]byte, 16), make([]byte, 16)
+	n := len(additionalData) - (len(additionalData) % 16)
+
+	var i uint64
+	for j := 0; j < n; j += 16 {
+		i++
+		crypto.XOR(offset, offset, c.l[ntz(i)])
+		crypto.XOR(block, additionalData[j:j+16], offset)
+		c.blockCipher.Encrypt(block, block)
+		crypto.XOR(dst, dst, block)
+	}
+
+	if n < len(additionalData) {
+		crypto.XOR(offset, offset, c.lStar)
+		for i := range block {
+			block[i] = 0
+		}
+		r := copy(block, additionalData[n:])
+		block[r] = 0x80
+
+		crypto.XOR(block, block, offset)
+		c.blockCipher.Encrypt(block, block)
+		crypto.XOR(dst, dst, block)
+	}
+}
+
+// double computes the doubling of src in GF(2^n) - where n is the
+// block size in bits - and writes the result to dst. The used polynomials
+// are the same as in the cmac package.
+func double(dst, src []byte) {
+	var p uint32
+	switch len(src) {
+	case 8:
+		p = 0x1b
+	case 16:
+		p = 0x87
+	case 32:
+		p = 0x425
+	case 64:
+		p = 0x125
+	case 128:
+		p = 0x80043
+	default:
+		panic("block size not supported")
+	}
+
+	var b, bit byte
+	for i := len(src) - 1; i >= 0; i-- {
+		bit = src[i] >> 7
+		dst[i] = src[i]<<1 | b
+		b = bit
+	}
+	p = uint32(subtle.ConstantTimeSelect(int(b), int(p), 0))
+
+	n := len(dst)
+	dst[n-1] ^= byte(p)
+	dst[n-2] ^= byte(p >> 8)
+	dst[n-3] ^= byte(p >> 16)
+}
+
+// ntz returns the number of trailing zero bits of i.
+// The argument must not be 0.
+func ntz(i uint64) (n int) {
+	for i&1 == 0 {
+		i >>= 1
+		n++
+	}
+	return
+}
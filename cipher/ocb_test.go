@@ -0,0 +1,319 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"testing"
+
+	"github.com/enceve/crypto/camellia"
+	"github.com/enceve/crypto/serpent"
+)
+
+// OCB-AES test vectors from RFC 7253 (Appendix A)
+var ocbVectors = []struct {
+	key, nonce, data, msg string
+	ciphertext            string
+	tagSize               int
+}{
+	{
+		key:        "000102030405060708090A0B0C0D0E0F",
+		nonce:      "BBAA99887766554433221100",
+		data:       "",
+		msg:        "",
+		ciphertext: "785407BFFFC8AD9EDCC5520AC9111EE6",
+		tagSize:    16,
+	},
+	{
+		key:        "000102030405060708090A0B0C0D0E0F",
+		nonce:      "BBAA99887766554433221101",
+		data:       "0001020304050607",
+		msg:        "0001020304050607",
+		ciphertext: "6820B3657B6F615A5725BDA0D3B4EB3A257C9AF1F8F03009",
+		tagSize:    16,
+	},
+	{
+		key:        "000102030405060708090A0B0C0D0E0F",
+		nonce:      "BBAA99887766554433221102",
+		data:       "0001020304050607",
+		msg:        "",
+		ciphertext: "81017F8203F081277152FADE694A0A00",
+		tagSize:    16,
+	},
+	{
+		key:        "000102030405060708090A0B0C0D0E0F",
+		nonce:      "BBAA99887766554433221103",
+		data:       "",
+		msg:        "0001020304050607",
+		ciphertext: "45DD69F8F5AAE72414054CD1F35D82760B2CD00D2F99BFA9",
+		tagSize:    16,
+	},
+	{
+		key:        "000102030405060708090A0B0C0D0E0F",
+		nonce:      "BBAA99887766554433221104",
+		data:       "000102030405060708090A0B0C0D0E0F",
+		msg:        "000102030405060708090A0B0C0D0E0F",
+		ciphertext: "571D535B60B277188BE5147170A9A22C3AD7A4FF3835B8C5701C1CCEC8FC3358",
+		tagSize:    16,
+	},
+	{
+		key:        "000102030405060708090A0B0C0D0E0F",
+		nonce:      "BBAA99887766554433221105",
+		data:       "000102030405060708090A0B0C0D0E0F",
+		msg:        "",
+		ciphertext: "8CF761B6902EF764462AD86498CA6B97",
+		tagSize:    16,
+	},
+	{
+		key:        "000102030405060708090A0B0C0D0E0F",
+		nonce:      "BBAA99887766554433221106",
+		data:       "",
+		msg:        "000102030405060708090A0B0C0D0E0F",
+		ciphertext: "5CE88EC2E0692706A915C00AEB8B2396F40E1C743F52436BDF06D8FA1ECA343D",
+		tagSize:    16,
+	},
+	{
+		key:   "000102030405060708090A0B0C0D0E0F",
+		nonce: "BBAA99887766554433221107",
+		data:  "000102030405060708090A0B0C0D0E0F1011121314151617",
+		msg:   "000102030405060708090A0B0C0D0E0F1011121314151617",
+		ciphertext: "1CA2207308C87C010756104D8840CE1952F09673A448A122" +
+			"C92C62241051F57356D7F3C90BB0E07F",
+		tagSize: 16,
+	},
+	{
+		key:        "000102030405060708090A0B0C0D0E0F",
+		nonce:      "BBAA99887766554433221108",
+		data:       "000102030405060708090A0B0C0D0E0F1011121314151617",
+		msg:        "",
+		ciphertext: "6DC225A071FC1B9F7C69F93B0F1E10DE",
+		tagSize:    16,
+	},
+	{
+		key:   "000102030405060708090A0B0C0D0E0F",
+		nonce: "BBAA99887766554433221109",
+		data:  "",
+		msg:   "000102030405060708090A0B0C0D0E0F1011121314151617",
+		ciphertext: "221BD0DE7FA6FE993ECCD769460A0AF2D6CDED0C395B1C3C" +
+			"E725F32494B9F914D85C0B1EB38357FF",
+		tagSize: 16,
+	},
+	{
+		key:   "000102030405060708090A0B0C0D0E0F",
+		nonce: "BBAA9988776655443322110A",
+		data:  "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		msg:   "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		ciphertext: "BD6F6C496201C69296C11EFD138A467ABD3C707924B964DEAFFC40319AF5A485" +
+			"40FBBA186C5553C68AD9F592A79A4240",
+		tagSize: 16,
+	},
+	{
+		key:        "000102030405060708090A0B0C0D0E0F",
+		nonce:      "BBAA9988776655443322110B",
+		data:       "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		msg:        "",
+		ciphertext: "FE80690BEE8A485D11F32965BC9D2A32",
+		tagSize:    16,
+	},
+	{
+		key:   "000102030405060708090A0B0C0D0E0F",
+		nonce: "BBAA9988776655443322110C",
+		data:  "",
+		msg:   "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		ciphertext: "2942BFC773BDA23CABC6ACFD9BFD5835BD300F0973792EF46040C53F1432BCDF" +
+			"B5E1DDE3BC18A5F840B52E653444D5DF",
+		tagSize: 16,
+	},
+	{
+		key:   "000102030405060708090A0B0C0D0E0F",
+		nonce: "BBAA9988776655443322110D",
+		data: "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F" +
+			"2021222324252627",
+		msg: "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F" +
+			"2021222324252627",
+		ciphertext: "D5CA91748410C1751FF8A2F618255B68A0A12E093FF454606E59F9C1D0DDC54B" +
+			"65E8628E568BAD7AED07BA06A4A69483A7035490C5769E60",
+		tagSize: 16,
+	},
+	{
+		key:   "000102030405060708090A0B0C0D0E0F",
+		nonce: "BBAA9988776655443322110E",
+		data: "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F" +
+			"2021222324252627",
+		msg:        "",
+		ciphertext: "C5CD9D1850C141E358649994EE701B68",
+		tagSize:    16,
+	},
+	{
+		key:   "000102030405060708090A0B0C0D0E0F",
+		nonce: "BBAA9988776655443322110F",
+		data:  "",
+		msg: "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F" +
+			"2021222324252627",
+		ciphertext: "4412923493C57D5DE0D700F753CCE0D1D2D95060122E9F15A5DDBFC5787E50B5" +
+			"CC55EE507BCB084E479AD363AC366B95A98CA5F3000B1479",
+		tagSize: 16,
+	},
+	{
+		key:   "0F0E0D0C0B0A09080706050403020100",
+		nonce: "BBAA9988776655443322110D",
+		data: "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F" +
+			"2021222324252627",
+		msg: "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F" +
+			"2021222324252627",
+		ciphertext: "1792A4E31E0755FB03E31B22116E6C2DDF9EFD6E33D536F1A0124B0A55BAE884" +
+			"ED93481529C76B6AD0C515F4D1CDD4FDAC4F02AA",
+		tagSize: 12,
+	},
+}
+
+func TestOCBVectors(t *testing.T) {
+	for i, v := range ocbVectors {
+		key, nonce := fromHex(v.key), fromHex(v.nonce)
+		data, msg := fromHex(v.data), fromHex(v.msg)
+		ciphertext := fromHex(v.ciphertext)
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create AES instance: %s", i, err)
+		}
+		ocb, err := NewOCB(block, len(nonce), v.tagSize)
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create OCB instance: %s", i, err)
+		}
+
+		buf := ocb.Seal(nil, nonce, msg, data)
+		if !bytes.Equal(buf, ciphertext) {
+			t.Fatalf("TestVector %d Seal failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(ciphertext))
+		}
+
+		buf, err = ocb.Open(buf[:0], nonce, buf, data)
+		if err != nil {
+			t.Fatalf("TestVector %d: Open failed: %s", i, err)
+		}
+		if !bytes.Equal(buf, msg) {
+			t.Fatalf("TestVector %d Open failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(msg))
+		}
+	}
+}
+
+// The iterative OCB-AES test from RFC 7253 (Appendix A)
+func TestOCBIterative(t *testing.T) {
+	results := []struct {
+		keySize, tagSize int
+		output           string
+	}{
+		{16, 16, "67E944D23256C5E0B6C61FA22FDF1EA2"},
+		{24, 16, "F673F2C3E7174AAE7BAE986CA9F29E17"},
+		{32, 16, "D90EB8E9C977C88B79DD793D7FFA161C"},
+		{16, 12, "77A3D8E73589158D25D01209"},
+		{24, 12, "05D56EAD2752C86BE6932C5E"},
+		{32, 12, "5458359AC23B0CBA9E6330DD"},
+		{16, 8, "192C9B7BD90BA06A"},
+		{24, 8, "0066BC6E0EF34E24"},
+		{32, 8, "7D4EA5D445501CBE"},
+	}
+	for i, v := range results {
+		key := make([]byte, v.keySize)
+		key[len(key)-1] = byte(v.tagSize * 8)
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			t.Fatalf("Test %d: Failed to create AES instance: %s", i, err)
+		}
+		ocb, err := NewOCB(block, 12, v.tagSize)
+		if err != nil {
+			t.Fatalf("Test %d: Failed to create OCB instance: %s", i, err)
+		}
+
+		nonce := make([]byte, 12)
+		setNonce := func(n int) {
+			nonce[9], nonce[10], nonce[11] = byte(n>>16), byte(n>>8), byte(n)
+		}
+
+		var c []byte
+		for j := 0; j < 128; j++ {
+			s := make([]byte, j)
+			setNonce(3*j + 1)
+			c = ocb.Seal(c, nonce, s, s)
+			setNonce(3*j + 2)
+			c = ocb.Seal(c, nonce, s, nil)
+			setNonce(3*j + 3)
+			c = ocb.Seal(c, nonce, nil, s)
+		}
+		setNonce(385)
+		output := ocb.Seal(nil, nonce, nil, c)
+		if ref := fromHex(v.output); !bytes.Equal(output, ref) {
+			t.Fatalf("Test %d: Output does not match:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(output), hex.EncodeToString(ref))
+		}
+	}
+}
+
+func testOCB(t *testing.T, name string, block cipher.Block) {
+	msg, data := make([]byte, 100), make([]byte, 35)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	for noncesize := 1; noncesize <= 15; noncesize++ {
+		for _, tagsize := range []int{1, 8, 12, 16} {
+			ocb, err := NewOCB(block, noncesize, tagsize)
+			if err != nil {
+				t.Fatalf("%s: Failed to create OCB instance: %s", name, err)
+			}
+			nonce := make([]byte, noncesize)
+			nonce[0] = byte(tagsize)
+			for i := range msg {
+				ciphertext := ocb.Seal(nil, nonce, msg[:i], data[:i%len(data)])
+				plaintext, err := ocb.Open(nil, nonce, ciphertext, data[:i%len(data)])
+				if err != nil {
+					t.Fatalf("%s: Open failed: %s", name, err)
+				}
+				if !bytes.Equal(plaintext, msg[:i]) {
+					t.Fatalf("%s: Open failed:\nFound   : %s\nExpected: %s", name, hex.EncodeToString(plaintext), hex.EncodeToString(msg[:i]))
+				}
+
+				if tagsize < 8 {
+					continue // forgeries are likely for short tags
+				}
+				ciphertext[i/2] ^= 0x40
+				if _, err = ocb.Open(nil, nonce, ciphertext, data[:i%len(data)]); err == nil {
+					t.Fatalf("%s: Open accepted modified ciphertext", name)
+				}
+			}
+		}
+	}
+}
+
+func TestOCB(t *testing.T) {
+	key := make([]byte, 16)
+	c, err := camellia.NewCipher(key)
+	if err != nil {
+		t.Fatalf("Failed to create Camellia instance: %s", err)
+	}
+	testOCB(t, "Camellia", c)
+
+	s, err := serpent.NewCipher(key)
+	if err != nil {
+		t.Fatalf("Failed to create Serpent instance: %s", err)
+	}
+	testOCB(t, "Serpent", s)
+}
+
+func TestNewOCB(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES instance: %s", err)
+	}
+	badSizes := []struct{ nonceSize, tagSize int }{
+		{0, 16}, {16, 16}, {12, 0}, {12, 17},
+	}
+	for i, v := range badSizes {
+		if _, err = NewOCB(block, v.nonceSize, v.tagSize); err == nil {
+			t.Fatalf("Test %d: NewOCB accepted nonce size %d and tag size %d", i, v.nonceSize, v.tagSize)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"hash"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/cmac"
+)
+
+// SIV is a cipher.AEAD implementing the SIV mode. Beside the
+// cipher.AEAD methods, SIV can process a vector of additional
+// data components.
+type SIV interface {
+	cipher.AEAD
+
+	// SealVector encrypts and authenticates plaintext, authenticates
+	// every additional data component and appends the result to dst,
+	// returning the updated slice. The SIV (auth. tag) is prepended
+	// to the ciphertext. The number of additional data components
+	// must be smaller than the block size of the cipher in bits.
+	SealVector(dst, plaintext []byte, additionalData ...[]byte) []byte
+
+	// OpenVector decrypts and authenticates ciphertext, authenticates
+	// every additional data component and, if successful, appends the
+	// resulting plaintext to dst, returning the updated slice.
+	OpenVector(dst, ciphertext []byte, additionalData ...[]byte) ([]byte, error)
+}
+
+// The SIV cipher
+type sivCipher struct {
+	blockCipher cipher.Block
+	mac         hash.Hash
+	nonceSize   int
+}
+
+// NewSIV returns a SIV implementing the (nonce-misuse-resistant)
+// SIV mode specified in RFC 5297. SIV uses the mac cipher for
+// authentication (S2V using CMac) and the enc cipher for encryption
+// (CTR mode). Both ciphers must use different keys and the same
+// block size, which must be supported by CMac (see crypto/cmac for details).
+// The noncesize argument specifies the length of the nonce in bytes.
+// The nonce is processed as the last additional data component
+// by Seal and Open. If the noncesize is 0, SIV is a deterministic
+// AEAD cipher and Seal and Open don't accept nonces.
+func NewSIV(mac, enc cipher.Block, noncesize int) (SIV, error) {
+	m, err := cmac.New(mac)
+	if err != nil {
+		return nil, err
+	}
+	if enc == nil {
+		return nil, errors.New("the cipher.Block must not be nil")
+	}
+	if enc.BlockSize() != mac.BlockSize() {
+		return nil, errors.New("the block size of the ciphers must be equal")
+	}
+	if noncesize < 0 {
+		return nil, errors.New("noncesize must not be negative")
+	}
+	return &sivCipher{
+		blockCipher: enc,
+		mac:         m,
+		nonceSize:   noncesize,
+	}, nil
+}
+
+func (c *sivCipher) NonceSize() int { return c.nonceSize }
+
+func (c *sivCipher) Overhead() int { return c.blockCipher.BlockSize() }
+
+func (c *sivCipher) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if n := len(nonce); n != c.nonceSize {
+		panic(crypto.NonceSizeError(n))
+	}
+	if c.nonceSize == 0 {
+		return c.SealVector(dst, plaintext, additionalData)
+	}
+	return c.SealVector(dst, plaintext, additionalData, nonce)
+}
+
+func (c *sivCipher) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if n := len(nonce); n != c.nonceSize {
+		return nil, crypto.NonceSizeError(n)
+	}
+	if c.nonceSize == 0 {
+		return c.OpenVector(dst, ciphertext, additionalData)
+	}
+	return c.OpenVector(dst, ciphertext, additionalData, nonce)
+}
+
+func (c *sivCipher) SealVector(dst, plaintext []byte, additionalData ...[]byte) []byte {
+	bs := c.blockCipher.BlockSize()
+	if len(additionalData) >= bs*8 {
+		panic("too many additional data components")
+	}
+	ret, out := sliceForAppend(dst, len(plaintext)+bs)
+
+	v := c.s2v(plaintext, additionalData)
+
+	// plaintext and out may overlap inexactly,
+	// so copy (memmove) before encryption
+	copy(out[bs:], plaintext)
+	c.ctrCrypt(out[bs:], out[bs:], v)
+	copy(out, v)
+	return ret
+}
+
+func (c *sivCipher) OpenVector(dst, ciphertext []byte, additionalData ...[]byte) ([]byte, error) {
+	bs := c.blockCipher.BlockSize()
+	if len(additionalData) >= bs*8 {
+		return nil, errors.New("too many additional data components")
+	}
+	if len(ciphertext) < bs {
+		return nil, crypto.AuthenticationError{}
+	}
+
+	v := make([]byte, bs)
+	copy(v, ciphertext)
+	ciphertext = ciphertext[bs:]
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	copy(out, ciphertext)
+	c.ctrCrypt(out, out, v)
+
+	if subtle.ConstantTimeCompare(c.s2v(out, additionalData), v) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, crypto.AuthenticationError{}
+	}
+	return ret, nil
+}
+
+// s2v computes the S2V function of the additional data
+// components and the plaintext (the last component).
+func (c *sivCipher) s2v(plaintext []byte, additionalData [][]byte) []byte {
+	bs := c.blockCipher.BlockSize()
+	d := make([]byte, bs)
+
+	c.mac.Reset()
+	c.mac.Write(d)
+	d = c.mac.Sum(d[:0])
+
+	for _, v := range additionalData {
+		c.mac.Reset()
+		c.mac.Write(v)
+		double(d, d)
+		crypto.XOR(d, d, c.mac.Sum(nil))
+	}
+
+	c.mac.Reset()
+	if n := len(plaintext); n >= bs {
+		c.mac.Write(plaintext[:n-bs])
+		crypto.XOR(d, d, plaintext[n-bs:])
+	} else {
+		double(d, d)
+		crypto.XOR(d, d, plaintext)
+		d[n] ^= 0x80
+	}
+	c.mac.Write(d)
+	v := c.mac.Sum(nil)
+	c.mac.Reset()
+	return v
+}
+
+// ctrCrypt encrypts the bytes in src with the CTR mode using the
+// synthetic IV v and writes the ciphertext into dst.
+func (c *sivCipher) ctrCrypt(dst, src, v []byte) {
+	bs := c.blockCipher.BlockSize()
+	ctr, block := make([]byte, bs), make([]byte, bs)
+	copy(ctr, v)
+	ctr[bs-8] &= 0x7f
+	ctr[bs-4] &= 0x7f
+
+	for len(src) > 0 {
+		c.blockCipher.Encrypt(block, ctr)
+		n := crypto.XOR(dst, src, block)
+		dst, src = dst[n:], src[n:]
+
+		for k := len(ctr) - 1; k >= 0; k-- {
+			ctr[k]++
+			if ctr[k] != 0 {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"testing"
+
+	"github.com/enceve/crypto/camellia"
+	"github.com/enceve/crypto/skein/threefish"
+)
+
+// SIV-AES test vectors from RFC 5297 (Appendix A)
+var sivVectors = []struct {
+	key        string
+	data       []string
+	msg        string
+	ciphertext string
+}{
+	{
+		key: "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+		data: []string{
+			"101112131415161718191a1b1c1d1e1f2021222324252627",
+		},
+		msg:        "112233445566778899aabbccddee",
+		ciphertext: "85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c",
+	},
+	{
+		key: "7f7e7d7c7b7a79787776757473727170404142434445464748494a4b4c4d4e4f",
+		data: []string{
+			"00112233445566778899aabbccddeeffdeaddadadeaddadaffeeddccbbaa99887766554433221100",
+			"102030405060708090a0",
+			"09f911029d74e35bd84156c5635688c0",
+		},
+		msg: "7468697320697320736f6d6520706c61696e7465787420746f20656e63727970" +
+			"74207573696e67205349562d414553",
+		ciphertext: "7bdb6e3b432667eb06f4d14bff2fbd0fcb900f2fddbe404326601965c889bf17" +
+			"dba77ceb094fa663b7a3f748ba8af829ea64ad544a272e9c485b62a3fd5c0d",
+	},
+}
+
+func TestSIVVectors(t *testing.T) {
+	for i, v := range sivVectors {
+		key, msg, ciphertext := fromHex(v.key), fromHex(v.msg), fromHex(v.ciphertext)
+		data := make([][]byte, len(v.data))
+		for j := range data {
+			data[j] = fromHex(v.data[j])
+		}
+
+		mac, err := aes.NewCipher(key[:len(key)/2])
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create AES instance: %s", i, err)
+		}
+		enc, err := aes.NewCipher(key[len(key)/2:])
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create AES instance: %s", i, err)
+		}
+		siv, err := NewSIV(mac, enc, 0)
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create SIV instance: %s", i, err)
+		}
+
+		buf := siv.SealVector(nil, msg, data...)
+		if !bytes.Equal(buf, ciphertext) {
+			t.Fatalf("TestVector %d SealVector failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(ciphertext))
+		}
+		buf, err = siv.OpenVector(buf[:0], buf, data...)
+		if err != nil {
+			t.Fatalf("TestVector %d: OpenVector failed: %s", i, err)
+		}
+		if !bytes.Equal(buf, msg) {
+			t.Fatalf("TestVector %d OpenVector failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(msg))
+		}
+
+		// Test the cipher.AEAD interface (deterministic)
+		if len(data) == 1 {
+			buf = siv.Seal(nil, nil, msg, data[0])
+			if !bytes.Equal(buf, ciphertext) {
+				t.Fatalf("TestVector %d Seal failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(ciphertext))
+			}
+			buf, err = siv.Open(buf[:0], nil, buf, data[0])
+			if err != nil {
+				t.Fatalf("TestVector %d: Open failed: %s", i, err)
+			}
+			if !bytes.Equal(buf, msg) {
+				t.Fatalf("TestVector %d Open failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(msg))
+			}
+		}
+	}
+}
+
+func testSIV(t *testing.T, name string, mac, enc cipher.Block) {
+	siv, err := NewSIV(mac, enc, 12)
+	if err != nil {
+		t.Fatalf("%s: Failed to create SIV instance: %s", name, err)
+	}
+	nonce := make([]byte, siv.NonceSize())
+	msg, data := make([]byte, 300), make([]byte, 40)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+
+	// The nonce is the last additional data component
+	if !bytes.Equal(siv.Seal(nil, nonce, msg, data), siv.SealVector(nil, msg, data, nonce)) {
+		t.Fatalf("%s: Seal differs from SealVector", name)
+	}
+	for i := 0; i < len(msg); i += 7 {
+		ciphertext := siv.Seal(nil, nonce, msg[:i], data[:i%len(data)])
+		plaintext, err := siv.Open(nil, nonce, ciphertext, data[:i%len(data)])
+		if err != nil {
+			t.Fatalf("%s: Open failed: %s", name, err)
+		}
+		if !bytes.Equal(plaintext, msg[:i]) {
+			t.Fatalf("%s: Open failed:\nFound   : %s\nExpected: %s", name, hex.EncodeToString(plaintext), hex.EncodeToString(msg[:i]))
+		}
+
+		ciphertext[len(ciphertext)/2] ^= 0x01
+		if _, err = siv.Open(nil, nonce, ciphertext, data[:i%len(data)]); err == nil {
+			t.Fatalf("%s: Open accepted modified ciphertext", name)
+		}
+	}
+}
+
+func TestSIV(t *testing.T) {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	c0, err := camellia.NewCipher(key[:16])
+	if err != nil {
+		t.Fatalf("Failed to create Camellia instance: %s", err)
+	}
+	c1, err := camellia.NewCipher(key[16:])
+	if err != nil {
+		t.Fatalf("Failed to create Camellia instance: %s", err)
+	}
+	testSIV(t, "Camellia", c0, c1)
+
+	var tweak [threefish.TweakSize]byte
+	t0, err := threefish.NewCipher(&tweak, key)
+	if err != nil {
+		t.Fatalf("Failed to create Threefish instance: %s", err)
+	}
+	tweak[0] = 1
+	t1, err := threefish.NewCipher(&tweak, key)
+	if err != nil {
+		t.Fatalf("Failed to create Threefish instance: %s", err)
+	}
+	testSIV(t, "Threefish", t0, t1)
+}
+
+func TestNewSIV(t *testing.T) {
+	c, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES instance: %s", err)
+	}
+	var tweak [threefish.TweakSize]byte
+	tf, err := threefish.NewCipher(&tweak, make([]byte, 32))
+	if err != nil {
+		t.Fatalf("Failed to create Threefish instance: %s", err)
+	}
+
+	if _, err = NewSIV(c, tf, 0); err == nil {
+		t.Fatal("NewSIV accepted ciphers with different block sizes")
+	}
+	if _, err = NewSIV(c, nil, 0); err == nil {
+		t.Fatal("NewSIV accepted nil cipher")
+	}
+	if _, err = NewSIV(c, c, -1); err == nil {
+		t.Fatal("NewSIV accepted negative nonce size")
+	}
+}
@@ -0,0 +1,229 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"errors"
+	"io"
+
+	"github.com/enceve/crypto"
+)
+
+// STREAMOverhead is the number of nonce bytes used by STREAM
+// for the segment counter (4 bytes) and the last-segment flag (1 byte).
+// The nonce prefix must be STREAMOverhead bytes shorter than the
+// nonce of the AEAD cipher.
+const STREAMOverhead = 5
+
+const streamMaxSegments = 1 << 32
+
+var (
+	errSTREAMClosed   = errors.New("the STREAM encrypter is closed")
+	errSTREAMTooLarge = errors.New("too many STREAM segments")
+)
+
+// stream holds the common state of the STREAM encrypter and decrypter.
+type stream struct {
+	aead           cipher.AEAD
+	nonce          []byte
+	additionalData []byte
+	segmentSize    int
+	counter        uint64
+}
+
+func newStream(aead cipher.AEAD, noncePrefix, additionalData []byte, segmentSize int) (*stream, error) {
+	if aead == nil {
+		return nil, errors.New("the cipher.AEAD must not be nil")
+	}
+	if n := len(noncePrefix); n != aead.NonceSize()-STREAMOverhead {
+		return nil, crypto.NonceSizeError(n)
+	}
+	if segmentSize < 1 {
+		return nil, errors.New("segmentSize must be greater than 0")
+	}
+	nonce := make([]byte, aead.NonceSize())
+	copy(nonce, noncePrefix)
+
+	ad := make([]byte, len(additionalData))
+	copy(ad, additionalData)
+	return &stream{
+		aead:           aead,
+		nonce:          nonce,
+		additionalData: ad,
+		segmentSize:    segmentSize,
+	}, nil
+}
+
+// nextNonce sets the nonce of the next segment: the nonce
+// prefix followed by the segment counter (32 bit big endian)
+// and the last-segment flag.
+func (s *stream) nextNonce(last bool) ([]byte, error) {
+	if s.counter >= streamMaxSegments {
+		return nil, errSTREAMTooLarge
+	}
+	n := len(s.nonce)
+	s.nonce[n-5] = byte(s.counter >> 24)
+	s.nonce[n-4] = byte(s.counter >> 16)
+	s.nonce[n-3] = byte(s.counter >> 8)
+	s.nonce[n-2] = byte(s.counter)
+	s.nonce[n-1] = 0
+	if last {
+		s.nonce[n-1] = 1
+	}
+	s.counter++
+	return s.nonce, nil
+}
+
+// NewSTREAMEncrypter returns an io.WriteCloser implementing the encryption
+// of the STREAM construction (Hoang, Reyhanitabar, Rogaway and Vizár -
+// "Online Authenticated-Encryption and its Nonce-Reuse Misuse-Resistance").
+// All data written to it is split into segments of segmentSize bytes. Every
+// segment is encrypted with the AEAD cipher - using the nonce prefix, the
+// segment counter and a last-segment flag as nonce - and written to w.
+// The additional data is authenticated with every segment.
+// Close encrypts the last segment - but does not close w - and must
+// be called after all data is written. The flag of the last segment
+// makes the ciphertext resistant against truncation.
+// The length of the nonce prefix must be aead.NonceSize() - STREAMOverhead
+// and the same nonce prefix must never be used twice with the same key.
+func NewSTREAMEncrypter(aead cipher.AEAD, noncePrefix, additionalData []byte, segmentSize int, w io.Writer) (io.WriteCloser, error) {
+	s, err := newStream(aead, noncePrefix, additionalData, segmentSize)
+	if err != nil {
+		return nil, err
+	}
+	return &streamEncrypter{
+		stream: s,
+		w:      w,
+		buf:    make([]byte, 0, segmentSize+aead.Overhead()),
+	}, nil
+}
+
+type streamEncrypter struct {
+	*stream
+	w      io.Writer
+	buf    []byte // the plaintext of the current segment
+	closed bool
+	err    error
+}
+
+func (e *streamEncrypter) Write(p []byte) (n int, err error) {
+	if e.closed {
+		return 0, errSTREAMClosed
+	}
+	if e.err != nil {
+		return 0, e.err
+	}
+	for len(p) > 0 {
+		// a full segment is only sealed, if there is more data,
+		// because the last segment is sealed by Close
+		if len(e.buf) == e.segmentSize {
+			if e.err = e.seal(false); e.err != nil {
+				return n, e.err
+			}
+		}
+		k := copy(e.buf[len(e.buf):e.segmentSize], p)
+		e.buf = e.buf[:len(e.buf)+k]
+		n += k
+		p = p[k:]
+	}
+	return n, nil
+}
+
+func (e *streamEncrypter) Close() error {
+	if e.closed {
+		return errSTREAMClosed
+	}
+	e.closed = true
+	if e.err != nil {
+		return e.err
+	}
+	return e.seal(true)
+}
+
+// seal encrypts the buffered segment and writes it to w.
+func (e *streamEncrypter) seal(last bool) error {
+	nonce, err := e.nextNonce(last)
+	if err != nil {
+		return err
+	}
+	ciphertext := e.aead.Seal(e.buf[:0], nonce, e.buf, e.additionalData)
+	e.buf = e.buf[:0]
+
+	_, err = e.w.Write(ciphertext)
+	return err
+}
+
+// NewSTREAMDecrypter returns an io.Reader implementing the decryption of the
+// STREAM construction. It reads the segments produced by a STREAM encrypter
+// (see NewSTREAMEncrypter) with the same parameters from r. Every segment is
+// verified before its plaintext is released. If a segment is not authentic -
+// e.g. because the ciphertext was modified, reordered or truncated - Read
+// returns a crypto.AuthenticationError.
+// Notice that all plaintext released before such an error is authentic, but
+// it may be incomplete. The plaintext is complete only if Read returns io.EOF.
+func NewSTREAMDecrypter(aead cipher.AEAD, noncePrefix, additionalData []byte, segmentSize int, r io.Reader) (io.Reader, error) {
+	s, err := newStream(aead, noncePrefix, additionalData, segmentSize)
+	if err != nil {
+		return nil, err
+	}
+	return &streamDecrypter{
+		stream: s,
+		r:      r,
+		buf:    make([]byte, 0, segmentSize+aead.Overhead()+1),
+	}, nil
+}
+
+type streamDecrypter struct {
+	*stream
+	r         io.Reader
+	buf       []byte // the ciphertext of the next segment and one lookahead byte
+	plaintext []byte // the unread plaintext of the current segment
+	err       error
+}
+
+func (d *streamDecrypter) Read(p []byte) (n int, err error) {
+	for len(d.plaintext) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.err = d.open()
+	}
+	n = copy(p, d.plaintext)
+	d.plaintext = d.plaintext[n:]
+	return n, nil
+}
+
+// open reads and decrypts the next segment. It returns io.EOF
+// after the last segment was decrypted successfully.
+func (d *streamDecrypter) open() error {
+	size := d.segmentSize + d.aead.Overhead()
+
+	n, err := io.ReadFull(d.r, d.buf[len(d.buf):size+1])
+	d.buf = d.buf[:len(d.buf)+n]
+	last := err == io.EOF || err == io.ErrUnexpectedEOF
+	if err != nil && !last {
+		return err
+	}
+
+	segment := d.buf
+	if !last {
+		segment = d.buf[:size]
+	}
+	nonce, err := d.nextNonce(last)
+	if err != nil {
+		return err
+	}
+	plaintext, err := d.aead.Open(nil, nonce, segment, d.additionalData)
+	if err != nil {
+		return crypto.AuthenticationError{}
+	}
+	d.plaintext = plaintext
+
+	if last {
+		return io.EOF
+	}
+	d.buf = d.buf[:copy(d.buf, d.buf[size:])] // keep the lookahead byte
+	return nil
+}
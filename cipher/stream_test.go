@@ -0,0 +1,171 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"io/ioutil"
+	"testing"
+	"testing/iotest"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/chacha20"
+)
+
+func streamEncrypt(t *testing.T, aead cipher.AEAD, prefix, data, msg []byte, segmentSize, chunk int) []byte {
+	var buf bytes.Buffer
+	w, err := NewSTREAMEncrypter(aead, prefix, data, segmentSize, &buf)
+	if err != nil {
+		t.Fatalf("Failed to create STREAM encrypter: %s", err)
+	}
+	for i := 0; i < len(msg); i += chunk {
+		j := i + chunk
+		if j > len(msg) {
+			j = len(msg)
+		}
+		if _, err = w.Write(msg[i:j]); err != nil {
+			t.Fatalf("Write failed: %s", err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("Close failed: %s", err)
+	}
+	return buf.Bytes()
+}
+
+func testSTREAM(t *testing.T, name string, aead cipher.AEAD) {
+	prefix := make([]byte, aead.NonceSize()-STREAMOverhead)
+	for i := range prefix {
+		prefix[i] = byte(i)
+	}
+	data := []byte("additional data")
+
+	msg := make([]byte, 1000)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	for _, segmentSize := range []int{1, 16, 100, 999, 1000, 1001} {
+		for _, size := range []int{0, 1, segmentSize, 2 * segmentSize, 999, len(msg)} {
+			if size > len(msg) {
+				continue
+			}
+			ciphertext := streamEncrypt(t, aead, prefix, data, msg[:size], segmentSize, 7)
+
+			segments := (size + segmentSize - 1) / segmentSize
+			if size == 0 {
+				segments = 1 // the (empty) last segment
+			}
+			if n := size + segments*aead.Overhead(); len(ciphertext) != n {
+				t.Fatalf("%s: Segment size %d - size %d: ciphertext has %d bytes - expected %d", name, segmentSize, size, len(ciphertext), n)
+			}
+
+			r, err := NewSTREAMDecrypter(aead, prefix, data, segmentSize, iotest.HalfReader(bytes.NewReader(ciphertext)))
+			if err != nil {
+				t.Fatalf("%s: Failed to create STREAM decrypter: %s", name, err)
+			}
+			plaintext, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatalf("%s: Segment size %d - size %d: decryption failed: %s", name, segmentSize, size, err)
+			}
+			if !bytes.Equal(plaintext, msg[:size]) {
+				t.Fatalf("%s: Segment size %d - size %d: decryption returned wrong plaintext", name, segmentSize, size)
+			}
+		}
+	}
+}
+
+func TestSTREAM(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES instance: %s", err)
+	}
+	eax, err := NewEAX(block, 16)
+	if err != nil {
+		t.Fatalf("Failed to create EAX instance: %s", err)
+	}
+	testAEAD(t, "EAX", eax)
+	testSTREAM(t, "EAX", eax)
+
+	var key [32]byte
+	chacha := chacha20.NewChaCha20Poly1305(&key)
+	testAEAD(t, "ChaCha20Poly1305", chacha)
+	testSTREAM(t, "ChaCha20Poly1305", chacha)
+}
+
+func TestSTREAMSegments(t *testing.T) {
+	var key [32]byte
+	aead := chacha20.NewChaCha20Poly1305(&key)
+	prefix, data := make([]byte, aead.NonceSize()-STREAMOverhead), []byte("additional data")
+	msg := make([]byte, 50)
+
+	const segmentSize = 16
+	ciphertext := streamEncrypt(t, aead, prefix, data, msg, segmentSize, len(msg))
+
+	// the segments are sealed with prefix || counter || last-segment flag
+	var expected []byte
+	for i := 0; i < len(msg); i += segmentSize {
+		j, last := i+segmentSize, byte(0)
+		if j >= len(msg) {
+			j, last = len(msg), 1
+		}
+		nonce := append(append([]byte{}, prefix...), 0, 0, 0, byte(i/segmentSize), last)
+		expected = aead.Seal(expected, nonce, msg[i:j], data)
+	}
+	if !bytes.Equal(ciphertext, expected) {
+		t.Fatal("STREAM segments do not match the expected segment encryption")
+	}
+
+	open := func(ciphertext []byte) ([]byte, error) {
+		r, err := NewSTREAMDecrypter(aead, prefix, data, segmentSize, bytes.NewReader(ciphertext))
+		if err != nil {
+			t.Fatalf("Failed to create STREAM decrypter: %s", err)
+		}
+		return ioutil.ReadAll(r)
+	}
+	segment := segmentSize + aead.Overhead()
+
+	// truncation at a segment boundary
+	if _, err := open(ciphertext[:2*segment]); err != (crypto.AuthenticationError{}) {
+		t.Fatalf("STREAM decrypter accepted a truncated ciphertext - error: %v", err)
+	}
+
+	// reordering of segments
+	reordered := append([]byte{}, ciphertext[segment:2*segment]...)
+	reordered = append(reordered, ciphertext[:segment]...)
+	reordered = append(reordered, ciphertext[2*segment:]...)
+	if _, err := open(reordered); err != (crypto.AuthenticationError{}) {
+		t.Fatalf("STREAM decrypter accepted reordered segments - error: %v", err)
+	}
+
+	// modification of the last segment - the preceding segments are released
+	ciphertext[len(ciphertext)-1] ^= 1
+	plaintext, err := open(ciphertext)
+	if err != (crypto.AuthenticationError{}) {
+		t.Fatalf("STREAM decrypter accepted a modified ciphertext - error: %v", err)
+	}
+	if !bytes.Equal(plaintext, msg[:3*segmentSize]) {
+		t.Fatal("STREAM decrypter released wrong plaintext before the modified segment")
+	}
+}
+
+func TestNewSTREAM(t *testing.T) {
+	var key [32]byte
+	aead := chacha20.NewChaCha20Poly1305(&key)
+	prefix := make([]byte, aead.NonceSize()-STREAMOverhead)
+
+	if _, err := NewSTREAMEncrypter(aead, prefix[1:], nil, 16, ioutil.Discard); err == nil {
+		t.Fatal("NewSTREAMEncrypter accepted an invalid nonce prefix")
+	}
+	if _, err := NewSTREAMEncrypter(aead, prefix, nil, 0, ioutil.Discard); err == nil {
+		t.Fatal("NewSTREAMEncrypter accepted a segment size of 0")
+	}
+	if _, err := NewSTREAMDecrypter(aead, append(prefix, 0), nil, 16, nil); err == nil {
+		t.Fatal("NewSTREAMDecrypter accepted an invalid nonce prefix")
+	}
+	if _, err := NewSTREAMDecrypter(nil, prefix, nil, 16, nil); err == nil {
+		t.Fatal("NewSTREAMDecrypter accepted a nil AEAD")
+	}
+}
@@ -0,0 +1,232 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"hash"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/blake2/blake2b"
+	"github.com/enceve/crypto/hc128"
+	"github.com/enceve/crypto/hc256"
+	"github.com/enceve/crypto/poly1305"
+)
+
+// The encrypt-then-MAC cipher for stream ciphers
+type streamMAC struct {
+	newStream  func(nonce []byte) (cipher.Stream, error)
+	newMac     func(key []byte) (hash.Hash, error)
+	nonceSize  int
+	macKeySize int
+	size       int
+}
+
+// NewStreamMAC returns a cipher.AEAD implementing an encrypt-then-MAC scheme
+// using the stream cipher returned by newStream and the MAC returned by newMac.
+// The newStream function must return a cipher.Stream keyed with the encryption
+// key for a nonce of nonceSize bytes - e.g. a wrapper around hc128.NewCipher.
+// The newMac function must return a MAC (as hash.Hash) for a key of macKeySize
+// bytes - e.g. a wrapper around cmac.New, blake2b.New, skein.New or
+// NewPoly1305MAC.
+//
+// For every nonce the first macKeySize bytes of the key stream are used as MAC
+// key, and the rest of the key stream encrypts the plaintext. So the MAC key is
+// unique for every message and independent from the encryption, and one-time
+// MACs like Poly1305 can be used. The additional data and the ciphertext are
+// authenticated followed by the length of both (in bytes) encoded as two 64 bit
+// little endian integers.
+// The tagsize argument specifies the number of bytes of the auth. tag and must
+// be between 1 and the size of the MAC.
+func NewStreamMAC(newStream func(nonce []byte) (cipher.Stream, error), nonceSize int, newMac func(key []byte) (hash.Hash, error), macKeySize, tagsize int) (cipher.AEAD, error) {
+	if newStream == nil {
+		return nil, errors.New("the stream cipher constructor must not be nil")
+	}
+	if newMac == nil {
+		return nil, errors.New("the MAC constructor must not be nil")
+	}
+	if nonceSize < 1 {
+		return nil, errors.New("nonceSize must be greater than 0")
+	}
+	if macKeySize < 1 {
+		return nil, errors.New("macKeySize must be greater than 0")
+	}
+	if _, err := newStream(make([]byte, nonceSize)); err != nil {
+		return nil, err
+	}
+	mac, err := newMac(make([]byte, macKeySize))
+	if err != nil {
+		return nil, err
+	}
+	if tagsize < 1 || tagsize > mac.Size() {
+		return nil, errors.New("tagSize must between 1 and the size of the MAC")
+	}
+
+	return &streamMAC{
+		newStream:  newStream,
+		newMac:     newMac,
+		nonceSize:  nonceSize,
+		macKeySize: macKeySize,
+		size:       tagsize,
+	}, nil
+}
+
+// NewHC256BLAKE2b returns a cipher.AEAD implementing the HC-256-BLAKE2b
+// encrypt-then-MAC scheme: the HC-256 stream cipher and BLAKE2b-256 keyed
+// with a 64 byte MAC key (see NewStreamMAC). The key must be 32 bytes long.
+// The nonce is 32 bytes and the auth. tag is 32 bytes long.
+func NewHC256BLAKE2b(key []byte) (cipher.AEAD, error) {
+	if k := len(key); k != 32 {
+		return nil, crypto.KeySizeError(k)
+	}
+	var k [32]byte
+	copy(k[:], key)
+
+	newStream := func(nonce []byte) (cipher.Stream, error) {
+		var n [32]byte
+		copy(n[:], nonce)
+		return hc256.NewCipher(&n, &k), nil
+	}
+	newMac := func(key []byte) (hash.Hash, error) {
+		return blake2b.New(32, &blake2b.Config{Key: key})
+	}
+	return NewStreamMAC(newStream, 32, newMac, blake2b.Size, 32)
+}
+
+// NewHC128Poly1305 returns a cipher.AEAD implementing the HC-128-Poly1305
+// encrypt-then-MAC scheme: the HC-128 stream cipher and the Poly1305 one-time
+// MAC (see NewStreamMAC). The key must be 16 bytes long. The nonce is 16 bytes
+// and the auth. tag is 16 bytes long.
+func NewHC128Poly1305(key []byte) (cipher.AEAD, error) {
+	if k := len(key); k != 16 {
+		return nil, crypto.KeySizeError(k)
+	}
+	var k [16]byte
+	copy(k[:], key)
+
+	newStream := func(nonce []byte) (cipher.Stream, error) {
+		var n [16]byte
+		copy(n[:], nonce)
+		return hc128.NewCipher(&n, &k), nil
+	}
+	return NewStreamMAC(newStream, 16, NewPoly1305MAC, 32, poly1305.TagSize)
+}
+
+func (c *streamMAC) NonceSize() int { return c.nonceSize }
+
+func (c *streamMAC) Overhead() int { return c.size }
+
+func (c *streamMAC) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if n := len(nonce); n != c.nonceSize {
+		panic(crypto.NonceSizeError(n))
+	}
+	stream, mac, err := c.init(nonce)
+	if err != nil {
+		panic(err)
+	}
+	ret, out := sliceForAppend(dst, len(plaintext)+c.size)
+
+	stream.XORKeyStream(out, plaintext)
+
+	tag := authenticateStreamMAC(mac, out[:len(plaintext)], additionalData)
+	copy(out[len(plaintext):], tag[:c.size])
+	return ret
+}
+
+func (c *streamMAC) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if n := len(nonce); n != c.nonceSize {
+		return nil, crypto.NonceSizeError(n)
+	}
+	if len(ciphertext) < c.size {
+		return nil, crypto.AuthenticationError{}
+	}
+	stream, mac, err := c.init(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := ciphertext[len(ciphertext)-c.size:]
+	ciphertext = ciphertext[:len(ciphertext)-c.size]
+
+	tag := authenticateStreamMAC(mac, ciphertext, additionalData)
+	if subtle.ConstantTimeCompare(tag[:c.size], hash) != 1 {
+		return nil, crypto.AuthenticationError{}
+	}
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	stream.XORKeyStream(out, ciphertext)
+	return ret, nil
+}
+
+// init returns the stream cipher for the nonce and the
+// MAC keyed with the first macKeySize bytes of the key stream.
+func (c *streamMAC) init(nonce []byte) (cipher.Stream, hash.Hash, error) {
+	stream, err := c.newStream(nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+	macKey := make([]byte, c.macKeySize)
+	stream.XORKeyStream(macKey, macKey)
+
+	mac, err := c.newMac(macKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stream, mac, nil
+}
+
+// authenticateStreamMAC computes the MAC of the additional data and
+// the ciphertext followed by the length of both (in bytes) encoded
+// as two 64 bit little endian integers.
+func authenticateStreamMAC(mac hash.Hash, ciphertext, additionalData []byte) []byte {
+	mac.Write(additionalData)
+	mac.Write(ciphertext)
+
+	var buf [16]byte
+	adLen, ctLen := uint64(len(additionalData)), uint64(len(ciphertext))
+	for i := 0; i < 8; i++ {
+		buf[i] = byte(adLen >> uint(8*i))
+		buf[i+8] = byte(ctLen >> uint(8*i))
+	}
+	mac.Write(buf[:])
+	return mac.Sum(nil)
+}
+
+// NewPoly1305MAC returns a hash.Hash computing the Poly1305 one-time MAC
+// for the use with NewStreamMAC. The key must be 32 bytes long. Notice that
+// Poly1305 is insecure if one key is used twice - Reset does not change the key.
+func NewPoly1305MAC(key []byte) (hash.Hash, error) {
+	if k := len(key); k != 32 {
+		return nil, crypto.KeySizeError(k)
+	}
+	p := new(poly1305MAC)
+	copy(p.key[:], key)
+	p.Reset()
+	return p, nil
+}
+
+// The hash.Hash wrapper for poly1305.Hash
+type poly1305MAC struct {
+	key  [32]byte
+	hash *poly1305.Hash
+}
+
+func (p *poly1305MAC) Size() int { return poly1305.TagSize }
+
+func (p *poly1305MAC) BlockSize() int { return poly1305.TagSize }
+
+func (p *poly1305MAC) Reset() { p.hash = poly1305.New(&p.key) }
+
+func (p *poly1305MAC) Write(msg []byte) (int, error) { return p.hash.Write(msg) }
+
+func (p *poly1305MAC) Sum(b []byte) []byte {
+	// Don't change the state so the
+	// caller can keep writing and suming.
+	h := *p.hash
+	var tag [poly1305.TagSize]byte
+	h.Sum(&tag)
+	return append(b, tag[:]...)
+}
@@ -0,0 +1,166 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"hash"
+	"testing"
+
+	"github.com/enceve/crypto/cmac"
+	"github.com/enceve/crypto/hc128"
+	"github.com/enceve/crypto/hc256"
+	"github.com/enceve/crypto/poly1305"
+	"github.com/enceve/crypto/skein"
+)
+
+func TestStreamMAC(t *testing.T) {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+
+	aead, err := NewHC256BLAKE2b(key)
+	if err != nil {
+		t.Fatalf("Failed to create HC-256-BLAKE2b instance: %s", err)
+	}
+	testAEAD(t, "HC-256-BLAKE2b", aead)
+
+	aead, err = NewHC128Poly1305(key[:16])
+	if err != nil {
+		t.Fatalf("Failed to create HC-128-Poly1305 instance: %s", err)
+	}
+	testAEAD(t, "HC-128-Poly1305", aead)
+
+	var k128 [16]byte
+	copy(k128[:], key)
+	hc128Stream := func(nonce []byte) (cipher.Stream, error) {
+		var n [16]byte
+		copy(n[:], nonce)
+		return hc128.NewCipher(&n, &k128), nil
+	}
+	cmacAES := func(key []byte) (hash.Hash, error) {
+		c, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cmac.New(c)
+	}
+	aead, err = NewStreamMAC(hc128Stream, 16, cmacAES, 16, 12)
+	if err != nil {
+		t.Fatalf("Failed to create HC-128-CMac instance: %s", err)
+	}
+	testAEAD(t, "HC-128-CMac", aead)
+
+	var k256 [32]byte
+	copy(k256[:], key)
+	hc256Stream := func(nonce []byte) (cipher.Stream, error) {
+		var n [32]byte
+		copy(n[:], nonce)
+		return hc256.NewCipher(&n, &k256), nil
+	}
+	skeinMAC := func(key []byte) (hash.Hash, error) {
+		return skein.New(64, &skein.Config{Key: key}), nil
+	}
+	aead, err = NewStreamMAC(hc256Stream, 32, skeinMAC, 64, 64)
+	if err != nil {
+		t.Fatalf("Failed to create HC-256-Skein instance: %s", err)
+	}
+	testAEAD(t, "HC-256-Skein", aead)
+}
+
+func TestHC128Poly1305(t *testing.T) {
+	var key, nonce [16]byte
+	for i := range key {
+		key[i] = byte(i)
+		nonce[i] = byte(0xA0 + i)
+	}
+	msg, data := []byte("the plaintext message"), []byte("the additional data")
+
+	// the MAC key is the first 32 bytes of the key stream
+	stream := hc128.NewCipher(&nonce, &key)
+	var macKey [32]byte
+	stream.XORKeyStream(macKey[:], macKey[:])
+	expected := make([]byte, len(msg), len(msg)+poly1305.TagSize)
+	stream.XORKeyStream(expected, msg)
+
+	macMsg := append(append(append([]byte{}, data...), expected...), byte(len(data)), 0, 0, 0, 0, 0, 0, 0, byte(len(msg)), 0, 0, 0, 0, 0, 0, 0)
+	var tag [poly1305.TagSize]byte
+	poly1305.Sum(&tag, macMsg, &macKey)
+	expected = append(expected, tag[:]...)
+
+	aead, err := NewHC128Poly1305(key[:])
+	if err != nil {
+		t.Fatalf("Failed to create HC-128-Poly1305 instance: %s", err)
+	}
+	if ciphertext := aead.Seal(nil, nonce[:], msg, data); !bytes.Equal(ciphertext, expected) {
+		t.Fatalf("Seal failed:\nFound   : %s\nExpected: %s", hex.EncodeToString(ciphertext), hex.EncodeToString(expected))
+	}
+}
+
+func TestPoly1305MAC(t *testing.T) {
+	var key [32]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	msg := make([]byte, 100)
+
+	mac, err := NewPoly1305MAC(key[:])
+	if err != nil {
+		t.Fatalf("Failed to create Poly1305 instance: %s", err)
+	}
+	var expected [poly1305.TagSize]byte
+	for _, size := range []int{0, 15, 16, 100} {
+		poly1305.Sum(&expected, msg[:size], &key)
+
+		mac.Reset()
+		mac.Write(msg[:size/2])
+		mac.Sum(nil) // Sum must not change the state
+		mac.Write(msg[size/2 : size])
+		if sum := mac.Sum(nil); !bytes.Equal(sum, expected[:]) {
+			t.Fatalf("Size %d: Poly1305 does not match:\nFound:    %x\nExpected: %x", size, sum, expected)
+		}
+	}
+
+	if _, err = NewPoly1305MAC(key[:16]); err == nil {
+		t.Fatal("NewPoly1305MAC accepted a key of 16 bytes")
+	}
+}
+
+func TestNewStreamMAC(t *testing.T) {
+	if _, err := NewHC256BLAKE2b(make([]byte, 16)); err == nil {
+		t.Fatal("NewHC256BLAKE2b accepted a key of 16 bytes")
+	}
+	if _, err := NewHC128Poly1305(make([]byte, 32)); err == nil {
+		t.Fatal("NewHC128Poly1305 accepted a key of 32 bytes")
+	}
+
+	var key [16]byte
+	newStream := func(nonce []byte) (cipher.Stream, error) {
+		var n [16]byte
+		copy(n[:], nonce)
+		return hc128.NewCipher(&n, &key), nil
+	}
+	if _, err := NewStreamMAC(nil, 16, NewPoly1305MAC, 32, 16); err == nil {
+		t.Fatal("NewStreamMAC accepted nil stream cipher constructor")
+	}
+	if _, err := NewStreamMAC(newStream, 16, nil, 32, 16); err == nil {
+		t.Fatal("NewStreamMAC accepted nil MAC constructor")
+	}
+	if _, err := NewStreamMAC(newStream, 0, NewPoly1305MAC, 32, 16); err == nil {
+		t.Fatal("NewStreamMAC accepted nonce size 0")
+	}
+	if _, err := NewStreamMAC(newStream, 16, NewPoly1305MAC, 16, 16); err == nil {
+		t.Fatal("NewStreamMAC accepted invalid MAC key size")
+	}
+	badTagSizes := []int{0, 17}
+	for i, v := range badTagSizes {
+		if _, err := NewStreamMAC(newStream, 16, NewPoly1305MAC, 32, v); err == nil {
+			t.Fatalf("Test %d: NewStreamMAC accepted tag size %d", i, v)
+		}
+	}
+}
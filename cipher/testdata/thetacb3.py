@@ -0,0 +1,74 @@
+#!/usr/bin/env python3
+# Use of this source code is governed by a license
+# that can be found in the LICENSE file.
+
+# Generates the ΘCB3-Threefish test vectors of thetacb3_test.go following
+# Krovetz and Rogaway, "The Software Performance of Authenticated-Encryption
+# Modes" (2011), with the tweak encoding documented at NewThetaCB3.
+#
+# Usage: python3 thetacb3.py
+
+import os
+import sys
+
+sys.path.insert(0, os.path.join(os.path.dirname(os.path.abspath(__file__)), '..', '..', 'skein', 'testdata'))
+from skein import threefish, gohex  # runs the Skein / Threefish self test
+
+MESSAGE, FINAL, PAD, FINAL_PARTIAL, DATA, DATA_PARTIAL = range(6)
+
+
+def tweak(nonce, ctr, domain):
+    ctrsize = 16 - 1 - len(nonce)
+    return (ctr % (1 << (8 * ctrsize))).to_bytes(ctrsize, 'little') + nonce + bytes([domain])
+
+
+def xor(a, b):
+    return bytes(x ^ y for x, y in zip(a, b))
+
+
+def pad(b, size):
+    return b + b'\x80' + bytes(size - len(b) - 1)
+
+
+def seal(key, nonce, msg, data, tagsize):
+    bs = len(key)
+    n = len(msg) // bs
+    checksum, ciphertext = bytes(bs), b''
+    for i in range(n):
+        block = msg[i * bs:(i + 1) * bs]
+        checksum = xor(checksum, block)
+        ciphertext += threefish(key, tweak(nonce, i + 1, MESSAGE), block)
+    final = FINAL
+    if len(msg) % bs != 0:
+        rest = msg[n * bs:]
+        checksum = xor(checksum, pad(rest, bs))
+        ciphertext += xor(rest, threefish(key, tweak(nonce, n, PAD), bytes(bs)))
+        final = FINAL_PARTIAL
+    tag = threefish(key, tweak(nonce, n, final), checksum)
+
+    zero, n = bytes(len(nonce)), len(data) // bs
+    for i in range(n):
+        tag = xor(tag, threefish(key, tweak(zero, i + 1, DATA), data[i * bs:(i + 1) * bs]))
+    if len(data) % bs != 0:
+        tag = xor(tag, threefish(key, tweak(zero, n, DATA_PARTIAL), pad(data[n * bs:], bs)))
+    return ciphertext + tag[:tagsize]
+
+
+# keySize, nonceSize, msgSize, dataSize, tagSize
+CASES = (
+    (32, 8, 0, 0, 32), (32, 8, 32, 0, 16), (32, 11, 101, 33, 32), (32, 4, 31, 64, 16),
+    (64, 8, 0, 0, 64), (64, 8, 64, 0, 16), (64, 4, 63, 128, 16),
+    (128, 8, 128, 0, 16), (128, 4, 127, 256, 16),
+)
+
+if __name__ == '__main__':
+    for keysize, noncesize, msgsize, datasize, tagsize in CASES:
+        key = bytes(range(keysize))
+        nonce = bytes(range(0xA0, 0xA0 + noncesize))
+        msg = bytes(i * 7 & 0xFF for i in range(msgsize))
+        data = bytes((i * 3 + 1) & 0xFF for i in range(datasize))
+        print('\t{')
+        print('\t\tkeySize: %d, nonceSize: %d, msgSize: %d, dataSize: %d, tagSize: %d,' %
+              (keysize, noncesize, msgsize, datasize, tagsize))
+        print('\t\tciphertext: %s,' % gohex(seal(key, nonce, msg, data, tagsize)))
+        print('\t},')
@@ -0,0 +1,50 @@
+#!/usr/bin/env python3
+# Use of this source code is governed by a license
+# that can be found in the LICENSE file.
+
+# Generates the Threefish-Skein test vectors of threefishskein_test.go
+# following the construction documented at NewThreefishSkein.
+#
+# Usage: python3 threefishskein.py
+
+import os
+import sys
+
+sys.path.insert(0, os.path.join(os.path.dirname(os.path.abspath(__file__)), '..', '..', 'skein', 'testdata'))
+from skein import skein, threefish, gohex  # runs the Skein / Threefish self test
+
+ENCRYPTION = b'enceve/crypto threefish-skein encryption'
+AUTHENTICATION = b'enceve/crypto threefish-skein authentication'
+
+
+def seal(key, nonce, msg, data, tagsize):
+    bs = len(key)
+    enckey = skein(bs, bs, b'', key=key, personal=ENCRYPTION)
+    block = nonce + bytes(bs - len(nonce))
+    ciphertext = b''
+    for i in range(0, len(msg), bs):
+        keystream = threefish(enckey, (i // bs).to_bytes(16, 'little'), block)
+        ciphertext += bytes(x ^ y for x, y in zip(msg[i:i + bs], keystream))
+
+    lengths = len(data).to_bytes(8, 'little') + len(ciphertext).to_bytes(8, 'little')
+    tag = skein(bs, bs, data + ciphertext + lengths, key=key, personal=AUTHENTICATION, nonce=nonce)
+    return ciphertext + tag[:tagsize]
+
+
+# keySize, msgSize, dataSize, tagSize
+CASES = (
+    (32, 0, 0, 32), (32, 32, 0, 16), (32, 101, 33, 32),
+    (64, 0, 11, 64), (64, 63, 128, 16), (64, 200, 5, 32),
+    (128, 128, 0, 16), (128, 257, 3, 64),
+)
+
+if __name__ == '__main__':
+    for keysize, msgsize, datasize, tagsize in CASES:
+        key = bytes(range(keysize))
+        nonce = bytes(range(0xA0, 0xB0))
+        msg = bytes(i * 7 & 0xFF for i in range(msgsize))
+        data = bytes((i * 3 + 1) & 0xFF for i in range(datasize))
+        print('\t{')
+        print('\t\tkeySize: %d, msgSize: %d, dataSize: %d, tagSize: %d,' % (keysize, msgsize, datasize, tagsize))
+        print('\t\tciphertext: %s,' % gohex(seal(key, nonce, msg, data, tagsize)))
+        print('\t},')
@@ -0,0 +1,211 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/skein/threefish"
+)
+
+// The domain separation constants of ΘCB3.
+// The constant is stored in the last byte of the tweak.
+const (
+	tcbMessage      = 0x0 // full message block
+	tcbFinal        = 0x1 // checksum - last message block is full
+	tcbPad          = 0x2 // pad for the partial message block
+	tcbFinalPartial = 0x3 // checksum - last message block is partial
+	tcbData         = 0x4 // full additional data block
+	tcbDataPartial  = 0x5 // partial additional data block
+)
+
+// The ΘCB3 cipher
+type thetaCB3 struct {
+	cipher          threefish.TweakableBlock
+	nonceSize, size int
+	maxBlocks       uint64
+	ctrSize         int
+}
+
+// NewThetaCB3 returns a cipher.AEAD implementing ΘCB3 - the
+// tweakable block cipher form of OCB - using the Threefish
+// tweakable block cipher. ΘCB3 is a one-pass AEAD scheme,
+// so no separate MAC pass is needed.
+// The tweak of every block cipher call is the concatenation of
+// the block index (little endian), the nonce and a domain separation
+// byte, so the noncesize argument must be between 1 and TweakSize - 5
+// and limits the max. number of blocks per message to
+// 2^(8 * (TweakSize - 1 - noncesize)) - 1.
+// The tagsize argument specifies the number of bytes of the auth. tag
+// and must be between 1 and the block size of the cipher.
+func NewThetaCB3(c threefish.TweakableBlock, noncesize, tagsize int) (cipher.AEAD, error) {
+	if c == nil {
+		return nil, errors.New("the threefish.TweakableBlock must not be nil")
+	}
+	if noncesize < 1 || noncesize > threefish.TweakSize-5 {
+		return nil, errors.New("noncesize must between 1 and TweakSize - 5")
+	}
+	if tagsize < 1 || tagsize > c.BlockSize() {
+		return nil, errors.New("tagSize must between 1 and BlockSize() of the given cipher")
+	}
+
+	ctrSize := threefish.TweakSize - 1 - noncesize
+	maxBlocks := ^uint64(0)
+	if ctrSize < 8 {
+		maxBlocks = 1<<uint(8*ctrSize) - 1
+	}
+	return &thetaCB3{
+		cipher:    c,
+		nonceSize: noncesize,
+		size:      tagsize,
+		ctrSize:   ctrSize,
+		maxBlocks: maxBlocks,
+	}, nil
+}
+
+func (c *thetaCB3) NonceSize() int { return c.nonceSize }
+
+func (c *thetaCB3) Overhead() int { return c.size }
+
+func (c *thetaCB3) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if n := len(nonce); n != c.nonceSize {
+		panic(crypto.NonceSizeError(n))
+	}
+	bs := c.cipher.BlockSize()
+	if uint64(len(plaintext)/bs) > c.maxBlocks || uint64(len(additionalData)/bs) > c.maxBlocks {
+		panic("message too large for the nonce size")
+	}
+
+	ret, out := sliceForAppend(dst, len(plaintext)+c.size)
+
+	var tweak [threefish.TweakSize]byte
+	copy(tweak[c.ctrSize:], nonce)
+
+	checksum := make([]byte, bs)
+	n := len(plaintext) - (len(plaintext) % bs)
+
+	var ctr uint64
+	for i := 0; i < n; i += bs {
+		ctr++
+		c.setTweak(&tweak, ctr, tcbMessage)
+		crypto.XOR(checksum, checksum, plaintext[i:i+bs])
+		c.cipher.EncryptTweak(out[i:], plaintext[i:i+bs], &tweak)
+	}
+
+	final := byte(tcbFinal)
+	if n < len(plaintext) {
+		pad := make([]byte, bs)
+		c.setTweak(&tweak, ctr, tcbPad)
+		c.cipher.EncryptTweak(pad, pad, &tweak)
+
+		r := crypto.XOR(checksum, checksum, plaintext[n:])
+		checksum[r] ^= 0x80
+		crypto.XOR(out[n:], plaintext[n:], pad)
+		final = tcbFinalPartial
+	}
+	c.setTweak(&tweak, ctr, final)
+	c.cipher.EncryptTweak(checksum, checksum, &tweak)
+
+	c.hash(checksum, additionalData)
+	copy(out[len(plaintext):], checksum[:c.size])
+	return ret
+}
+
+func (c *thetaCB3) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if n := len(nonce); n != c.nonceSize {
+		return nil, crypto.NonceSizeError(n)
+	}
+	if len(ciphertext) < c.size {
+		return nil, crypto.AuthenticationError{}
+	}
+	bs := c.cipher.BlockSize()
+	if uint64(len(ciphertext)/bs) > c.maxBlocks || uint64(len(additionalData)/bs) > c.maxBlocks {
+		return nil, crypto.AuthenticationError{}
+	}
+
+	hash := ciphertext[len(ciphertext)-c.size:]
+	ciphertext = ciphertext[:len(ciphertext)-c.size]
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+
+	var tweak [threefish.TweakSize]byte
+	copy(tweak[c.ctrSize:], nonce)
+
+	checksum := make([]byte, bs)
+	n := len(ciphertext) - (len(ciphertext) % bs)
+
+	var ctr uint64
+	for i := 0; i < n; i += bs {
+		ctr++
+		c.setTweak(&tweak, ctr, tcbMessage)
+		c.cipher.DecryptTweak(out[i:], ciphertext[i:i+bs], &tweak)
+		crypto.XOR(checksum, checksum, out[i:i+bs])
+	}
+
+	final := byte(tcbFinal)
+	if n < len(ciphertext) {
+		pad := make([]byte, bs)
+		c.setTweak(&tweak, ctr, tcbPad)
+		c.cipher.EncryptTweak(pad, pad, &tweak)
+
+		r := crypto.XOR(out[n:], ciphertext[n:], pad)
+		crypto.XOR(checksum, checksum, out[n:n+r])
+		checksum[r] ^= 0x80
+		final = tcbFinalPartial
+	}
+	c.setTweak(&tweak, ctr, final)
+	c.cipher.EncryptTweak(checksum, checksum, &tweak)
+
+	c.hash(checksum, additionalData)
+	if subtle.ConstantTimeCompare(checksum[:c.size], hash) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, crypto.AuthenticationError{}
+	}
+	return ret, nil
+}
+
+// hash computes the (nonce independent) hash of the
+// additional data and xors it into dst.
+func (c *thetaCB3) hash(dst, additionalData []byte) {
+	bs := c.cipher.BlockSize()
+	n := len(additionalData) - (len(additionalData) % bs)
+
+	var tweak [threefish.TweakSize]byte
+	block := make([]byte, bs)
+
+	var ctr uint64
+	for i := 0; i < n; i += bs {
+		ctr++
+		c.setTweak(&tweak, ctr, tcbData)
+		c.cipher.EncryptTweak(block, additionalData[i:i+bs], &tweak)
+		crypto.XOR(dst, dst, block)
+	}
+	if n < len(additionalData) {
+		for i := range block {
+			block[i] = 0
+		}
+		r := copy(block, additionalData[n:])
+		block[r] = 0x80
+
+		c.setTweak(&tweak, ctr, tcbDataPartial)
+		c.cipher.EncryptTweak(block, block, &tweak)
+		crypto.XOR(dst, dst, block)
+	}
+}
+
+// setTweak writes the block index ctr and the domain
+// separation constant into the tweak. The nonce part
+// of the tweak is not modified.
+func (c *thetaCB3) setTweak(tweak *[threefish.TweakSize]byte, ctr uint64, domain byte) {
+	for i := 0; i < c.ctrSize; i++ {
+		tweak[i] = byte(ctr)
+		ctr >>= 8
+	}
+	tweak[threefish.TweakSize-1] = domain
+}
@@ -0,0 +1,145 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/enceve/crypto/skein/threefish"
+)
+
+// ΘCB3-Threefish test vectors generated by "python3 testdata/thetacb3.py".
+// The key is 0x00, 0x01, ... , the nonce is 0xA0, 0xA1, ... and the i-th byte
+// of the message and the additional data is i*7 and i*3+1 respectively.
+var thetaCB3Vectors = []struct {
+	keySize, nonceSize, msgSize, dataSize, tagSize int
+	ciphertext                                     string
+}{
+	{
+		keySize: 32, nonceSize: 8, msgSize: 0, dataSize: 0, tagSize: 32,
+		ciphertext: "76135D2F93DA1A7E0D6F7BA1D502517DF0A3A16180772202C05DF2767BCB9F0F",
+	},
+	{
+		keySize: 32, nonceSize: 8, msgSize: 32, dataSize: 0, tagSize: 16,
+		ciphertext: "02350DA87F68DD780FBC64D8755F4C0765D119FA08DE603FF0907903B8EA2183" +
+			"766E44FDDCF1ECA0965343BF6EEE06FA",
+	},
+	{
+		keySize: 32, nonceSize: 11, msgSize: 101, dataSize: 33, tagSize: 32,
+		ciphertext: "5C11625A3590DF4A01E3E2C84A1895B00EC7B0BF5AC4B9853B41AE81C4FB894A" +
+			"5AE7C4959BD451F7A67A658C85E613575F4A871CD3C7B3630F28C58B77D4A1EF" +
+			"2199840748E9960D1ED4A17E8040E5321A7C0C7FEF2FA356E61FAB4C2BA085C3" +
+			"D7BA799E57FED0880866A5BFE9CA7CDB9B8A6BF2D95F9ECAAFCF875F0CBF05C6" +
+			"04996CD65A",
+	},
+	{
+		keySize: 32, nonceSize: 4, msgSize: 31, dataSize: 64, tagSize: 16,
+		ciphertext: "37E99F7BB66B80BA641E4DB853A87A0C6489EEF30C943BD585ED3F56DAE51866" +
+			"AF9183BC73EC39B8B8579B02FA0C5D",
+	},
+	{
+		keySize: 64, nonceSize: 8, msgSize: 0, dataSize: 0, tagSize: 64,
+		ciphertext: "18DAA6176FEAD47C0288123727BDD2D33250500252AB462F86A2D1ED85C861DD" +
+			"D46E6C87A02B6C0D88A243D38ADF3EEB1D1783B055F156759B787C0B0C06A2D4",
+	},
+	{
+		keySize: 64, nonceSize: 8, msgSize: 64, dataSize: 0, tagSize: 16,
+		ciphertext: "06BD0A917C497719C1C35DCD3F7521885406DFE0018ADE722D3BF4232097C8FB" +
+			"FA551171C9B0CFD586B8B4C836FBE7929A4B1E97D2C5CB46C19B1FF099CAD72A" +
+			"4C801D7E64A0ECE950750F855AB6BE41",
+	},
+	{
+		keySize: 64, nonceSize: 4, msgSize: 63, dataSize: 128, tagSize: 16,
+		ciphertext: "522A686B287F8B56FF5FBA4F14E2EB9A9419CBFDC88C69A998ABF5E4BDD6F4C2" +
+			"2E2F06A80FE5FDDF3DB7FC0A3637514CE5B6333EE032CD48B34F8622C72BC0D7" +
+			"6B4412281217644A84707E6612AB34",
+	},
+	{
+		keySize: 128, nonceSize: 8, msgSize: 128, dataSize: 0, tagSize: 16,
+		ciphertext: "0D8A6CEDA1823961F43B5EB83E2ED432EC17651FF4BCD5EE75689845B85AE0DC" +
+			"95C488B04B7E362C7C0CF779C0C949A306200A7B5AF59870FAA585C9045CD044" +
+			"6165B293C432DA69671C1A1F7443A50F1A052A5418D97C656EA4CB928F3C3251" +
+			"7128AC561E9E041A9463C3A0F526D52455E89062A2B1528079C943A622F7E74A" +
+			"420678130931C2A01132314DFF0B270F",
+	},
+	{
+		keySize: 128, nonceSize: 4, msgSize: 127, dataSize: 256, tagSize: 16,
+		ciphertext: "CAFFA118A985FAFAF4196C1B5D84AF996E78F5C60370A65B899FC40877847CE6" +
+			"4BE1EAB5740BF8CDCB0B41771498EBD6B7C769D2BD4066C6ED958270BA7E6A88" +
+			"7B434B4B91F8AD36BC55141C8116FC7480552D08DC023ECAF4C7E6D4031EBF87" +
+			"33D781442F5668541CDAE756DB38489D95571F32FBD973652F8CC6218890A25C" +
+			"E9A4A04B3B55DD462BFF7FFB98BBE5",
+	},
+}
+
+func TestThetaCB3Vectors(t *testing.T) {
+	for i, v := range thetaCB3Vectors {
+		key, nonce := make([]byte, v.keySize), make([]byte, v.nonceSize)
+		msg, data := make([]byte, v.msgSize), make([]byte, v.dataSize)
+		for j := range key {
+			key[j] = byte(j)
+		}
+		for j := range nonce {
+			nonce[j] = byte(0xA0 + j)
+		}
+		for j := range msg {
+			msg[j] = byte(j * 7)
+		}
+		for j := range data {
+			data[j] = byte(j*3 + 1)
+		}
+		ciphertext, err := hex.DecodeString(v.ciphertext)
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to decode hex ciphertext: %s", i, err)
+		}
+
+		c, err := threefish.NewTweakableCipher(nil, key)
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create Threefish instance: %s", i, err)
+		}
+		aead, err := NewThetaCB3(c, v.nonceSize, v.tagSize)
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create ΘCB3 instance: %s", i, err)
+		}
+
+		buf := aead.Seal(nil, nonce, msg, data)
+		if !bytes.Equal(buf, ciphertext) {
+			t.Fatalf("TestVector %d Seal failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(ciphertext))
+		}
+
+		buf, err = aead.Open(buf[:0], nonce, buf, data)
+		if err != nil {
+			t.Fatalf("TestVector %d: Open failed: %s", i, err)
+		}
+		if !bytes.Equal(buf, msg) {
+			t.Fatalf("TestVector %d Open failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(msg))
+		}
+
+		ciphertext[0] ^= 1
+		if _, err = aead.Open(nil, nonce, ciphertext, data); err == nil {
+			t.Fatalf("TestVector %d: Open accepted modified ciphertext", i)
+		}
+	}
+}
+
+func TestNewThetaCB3(t *testing.T) {
+	c, err := threefish.NewTweakableCipher(nil, make([]byte, threefish.BlockSize512))
+	if err != nil {
+		t.Fatalf("Failed to create Threefish instance: %s", err)
+	}
+
+	badSizes := []struct{ nonceSize, tagSize int }{
+		{0, 16}, {threefish.TweakSize - 4, 16}, {8, 0}, {8, threefish.BlockSize512 + 1},
+	}
+	for i, v := range badSizes {
+		if _, err = NewThetaCB3(c, v.nonceSize, v.tagSize); err == nil {
+			t.Fatalf("Test %d: NewThetaCB3 accepted nonce size %d and tag size %d", i, v.nonceSize, v.tagSize)
+		}
+	}
+	if _, err = NewThetaCB3(nil, 8, 16); err == nil {
+		t.Fatal("NewThetaCB3 accepted nil cipher")
+	}
+}
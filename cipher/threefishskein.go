@@ -0,0 +1,156 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"hash"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/skein"
+	"github.com/enceve/crypto/skein/skein1024"
+	"github.com/enceve/crypto/skein/skein256"
+	"github.com/enceve/crypto/skein/threefish"
+)
+
+// The nonce size of the Threefish-Skein AEAD cipher in bytes.
+const ThreefishSkeinNonceSize = 16
+
+// The personalization strings used for key separation.
+var (
+	tfsEncryption = []byte("enceve/crypto threefish-skein encryption")
+	tfsMac        = []byte("enceve/crypto threefish-skein authentication")
+)
+
+// The Threefish-Skein cipher
+type threefishSkein struct {
+	cipher threefish.TweakableBlock
+	key    []byte
+	newMac func(hashsize int, conf *skein.Config) hash.Hash
+	size   int
+}
+
+// NewThreefishSkein returns a cipher.AEAD implementing an encrypt-then-MAC
+// scheme using only Skein and Threefish. The plaintext is encrypted with
+// Threefish in counter mode - the counter is passed as tweak - and the
+// additional data and ciphertext are authenticated with Skein-MAC.
+// The encryption key and the MAC are separated from the given key
+// through the Skein personalization (skein.Config.Personal).
+// The length of the key must be 32, 64 or 128 byte and selects
+// Threefish-256 and Skein-256, Threefish-512 and Skein-512
+// or Threefish-1024 and Skein-1024.
+// The tagsize argument specifies the number of bytes of the auth. tag
+// and must be between 1 and the length of the key.
+func NewThreefishSkein(key []byte, tagsize int) (cipher.AEAD, error) {
+	var newMac func(int, *skein.Config) hash.Hash
+	var sum func([]byte, int, *skein.Config) []byte
+	switch k := len(key); k {
+	default:
+		return nil, crypto.KeySizeError(k)
+	case threefish.BlockSize256:
+		newMac, sum = skein256.New, skein256.Sum
+	case threefish.BlockSize512:
+		newMac, sum = skein.New, skein.Sum
+	case threefish.BlockSize1024:
+		newMac, sum = skein1024.New, skein1024.Sum
+	}
+	if tagsize < 1 || tagsize > len(key) {
+		return nil, errors.New("tagSize must between 1 and the length of the key")
+	}
+
+	encKey := sum(nil, len(key), &skein.Config{Key: key, Personal: tfsEncryption})
+	c, err := threefish.NewTweakableCipher(nil, encKey)
+	if err != nil {
+		return nil, err
+	}
+
+	k := make([]byte, len(key))
+	copy(k, key)
+	return &threefishSkein{
+		cipher: c,
+		key:    k,
+		newMac: newMac,
+		size:   tagsize,
+	}, nil
+}
+
+func (c *threefishSkein) NonceSize() int { return ThreefishSkeinNonceSize }
+
+func (c *threefishSkein) Overhead() int { return c.size }
+
+func (c *threefishSkein) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if n := len(nonce); n != ThreefishSkeinNonceSize {
+		panic(crypto.NonceSizeError(n))
+	}
+	ret, out := sliceForAppend(dst, len(plaintext)+c.size)
+
+	c.ctrCrypt(out, plaintext, nonce)
+
+	tag := c.authenticate(out[:len(plaintext)], nonce, additionalData)
+	copy(out[len(plaintext):], tag[:c.size])
+	return ret
+}
+
+func (c *threefishSkein) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if n := len(nonce); n != ThreefishSkeinNonceSize {
+		return nil, crypto.NonceSizeError(n)
+	}
+	if len(ciphertext) < c.size {
+		return nil, crypto.AuthenticationError{}
+	}
+
+	hash := ciphertext[len(ciphertext)-c.size:]
+	ciphertext = ciphertext[:len(ciphertext)-c.size]
+
+	tag := c.authenticate(ciphertext, nonce, additionalData)
+	if subtle.ConstantTimeCompare(tag[:c.size], hash) != 1 {
+		return nil, crypto.AuthenticationError{}
+	}
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	c.ctrCrypt(out, ciphertext, nonce)
+	return ret, nil
+}
+
+// authenticate computes the Skein-MAC of the additional data and
+// the ciphertext followed by the length of both (in bytes) encoded
+// as two 64 bit little endian integers.
+func (c *threefishSkein) authenticate(ciphertext, nonce, additionalData []byte) []byte {
+	mac := c.newMac(len(c.key), &skein.Config{Key: c.key, Personal: tfsMac, Nonce: nonce})
+	mac.Write(additionalData)
+	mac.Write(ciphertext)
+
+	var buf [16]byte
+	adLen, ctLen := uint64(len(additionalData)), uint64(len(ciphertext))
+	for i := 0; i < 8; i++ {
+		buf[i] = byte(adLen >> uint(8*i))
+		buf[i+8] = byte(ctLen >> uint(8*i))
+	}
+	mac.Write(buf[:])
+	return mac.Sum(nil)
+}
+
+// ctrCrypt encrypts the bytes in src with Threefish in counter mode
+// and writes the ciphertext into dst. The (zero padded) nonce is
+// encrypted using the block counter as tweak.
+func (c *threefishSkein) ctrCrypt(dst, src, nonce []byte) {
+	bs := c.cipher.BlockSize()
+	block, keyStream := make([]byte, bs), make([]byte, bs)
+	copy(block, nonce)
+
+	var tweak [threefish.TweakSize]byte
+	var ctr uint64
+	for len(src) > 0 {
+		for i := 0; i < 8; i++ {
+			tweak[i] = byte(ctr >> uint(8*i))
+		}
+		c.cipher.EncryptTweak(keyStream, block, &tweak)
+		ctr++
+
+		n := crypto.XOR(dst, src, keyStream)
+		dst, src = dst[n:], src[n:]
+	}
+}
@@ -0,0 +1,195 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/enceve/crypto/skein"
+	"github.com/enceve/crypto/skein/threefish"
+)
+
+// Threefish-Skein test vectors generated by
+// "python3 testdata/threefishskein.py". The key is 0x00, 0x01, ... ,
+// the nonce is 0xA0, 0xA1, ... and the i-th byte of the message and
+// the additional data is i*7 and i*3+1 respectively.
+var threefishSkeinVectors = []struct {
+	keySize, msgSize, dataSize, tagSize int
+	ciphertext                          string
+}{
+	{
+		keySize: 32, msgSize: 0, dataSize: 0, tagSize: 32,
+		ciphertext: "537376902A15A2FE98A49415A41861574F50336687E7A35001FA65115D7602D2",
+	},
+	{
+		keySize: 32, msgSize: 32, dataSize: 0, tagSize: 16,
+		ciphertext: "7D2101F0C54BEED27A0524289E44682D403552361A19647A11F6248CC21AE4DF" +
+			"91F8BE82749A494D112B8B86880D49CD",
+	},
+	{
+		keySize: 32, msgSize: 101, dataSize: 33, tagSize: 32,
+		ciphertext: "7D2101F0C54BEED27A0524289E44682D403552361A19647A11F6248CC21AE4DF" +
+			"B772013E604C35089EEDA975F16A5B276AC6E2A64B71C8DD46277B4001DBB91C" +
+			"79E2F475331897734A364E8ED3FD9F8C5AE4E2E0C77706BA4B84D9283AB04F1E" +
+			"AA68EEE21B96B5531B8FE1D49755F37FD3D16E2E218E786221E45BA4BEFE608E" +
+			"7D7DC0E2C5",
+	},
+	{
+		keySize: 64, msgSize: 0, dataSize: 11, tagSize: 64,
+		ciphertext: "3C21E8289872CA1F80A1A3C5CC5E03BD31B658EB8CE76E068122507358824605" +
+			"32734C06DD5B013F80AB453FA8EB431DAC7AB67E19380383C470AF4F64ACE8C3",
+	},
+	{
+		keySize: 64, msgSize: 63, dataSize: 128, tagSize: 16,
+		ciphertext: "E36634DB4208183C6549F264D8275A14DF0174AEA33DDAD643A85819A5C25665" +
+			"C673C5C08237800A4787B04455529266017B7E24ABF79E6245CBF046FBFAC0E1" +
+			"4B7E273189B05B8925CCA02CC7D09E",
+	},
+	{
+		keySize: 64, msgSize: 200, dataSize: 5, tagSize: 32,
+		ciphertext: "E36634DB4208183C6549F264D8275A14DF0174AEA33DDAD643A85819A5C25665" +
+			"C673C5C08237800A4787B04455529266017B7E24ABF79E6245CBF046FBFAC06A" +
+			"8BED94FB891E5F3040DC7F362943C38D6C134DEC61C02237C855BC28CC2BE996" +
+			"86EB590FEE6B2C7CB5F81D9D3A32F991AC365831F90AFDD2C930BBCECB55C0F5" +
+			"47A986BEB079DD86CB4C7A3416A778C5B08D849380A728F20C4CA39AAC58A1A5" +
+			"95FFF19A08B8DB9CF41D7AA3BEEEE34BDBB4B85189331B0F6B7E4DAC612AF655" +
+			"DE6014220C3FE4847FB96C466FFE3BC3D636A85470C5F9AEC799AA1C6B97F49A" +
+			"F72CCBC7D5E0406A",
+	},
+	{
+		keySize: 128, msgSize: 128, dataSize: 0, tagSize: 16,
+		ciphertext: "3B09A2846080F5AF9F990674F8A01A39548605D09C909C6F6B1F3D36B4035E03" +
+			"BAB84A7FB13155FB65179E87AB051292540E9D144BDA895E10138201EAF22064" +
+			"317E7F68CBC6E9DAF7AC53165696DD22A175981FE561E7D8EE386AF55A3A05C0" +
+			"4C516C6FCEF8E030D7A766DBC54CB89186803858D4B93F51A377DC59D111E29D" +
+			"A9D67E2BEC84D7B764589CCB2A6EBA39",
+	},
+	{
+		keySize: 128, msgSize: 257, dataSize: 3, tagSize: 64,
+		ciphertext: "3B09A2846080F5AF9F990674F8A01A39548605D09C909C6F6B1F3D36B4035E03" +
+			"BAB84A7FB13155FB65179E87AB051292540E9D144BDA895E10138201EAF22064" +
+			"317E7F68CBC6E9DAF7AC53165696DD22A175981FE561E7D8EE386AF55A3A05C0" +
+			"4C516C6FCEF8E030D7A766DBC54CB89186803858D4B93F51A377DC59D111E29D" +
+			"AF86D31E287DD8B99BDCCCC62FE5F2F4A3B235B0AEDED9C63DB3CD3CD86B6527" +
+			"7632AD0A7EA38E6DA8F387FCD82CEAB78518C44A055256A06331BACF7AD7F1CB" +
+			"AF17655ADC2AD7CBBEAE25F918CE884E54CAEC6F68DB505B22993E4C258B9DD1" +
+			"9B7EDEBE49F0518011A2E08E990C12EF82F33E9420CA7BB62A3F30C6958F0C28" +
+			"70A9A0027F7BA50F5BE5A616CABCC57FB27A8079C4EFD3C2961213C0793CD025" +
+			"620DA611AAE30F3D9A39E98B9077BCC21FA81A4A993156A1E44CC98C54CF766A" +
+			"B6",
+	},
+}
+
+func TestThreefishSkeinVectors(t *testing.T) {
+	for i, v := range threefishSkeinVectors {
+		key, nonce := make([]byte, v.keySize), make([]byte, ThreefishSkeinNonceSize)
+		msg, data := make([]byte, v.msgSize), make([]byte, v.dataSize)
+		for j := range key {
+			key[j] = byte(j)
+		}
+		for j := range nonce {
+			nonce[j] = byte(0xA0 + j)
+		}
+		for j := range msg {
+			msg[j] = byte(j * 7)
+		}
+		for j := range data {
+			data[j] = byte(j*3 + 1)
+		}
+		ciphertext, err := hex.DecodeString(v.ciphertext)
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to decode hex ciphertext: %s", i, err)
+		}
+
+		aead, err := NewThreefishSkein(key, v.tagSize)
+		if err != nil {
+			t.Fatalf("TestVector %d: Failed to create Threefish-Skein instance: %s", i, err)
+		}
+
+		buf := aead.Seal(nil, nonce, msg, data)
+		if !bytes.Equal(buf, ciphertext) {
+			t.Fatalf("TestVector %d Seal failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(ciphertext))
+		}
+
+		buf, err = aead.Open(buf[:0], nonce, buf, data)
+		if err != nil {
+			t.Fatalf("TestVector %d: Open failed: %s", i, err)
+		}
+		if !bytes.Equal(buf, msg) {
+			t.Fatalf("TestVector %d Open failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(msg))
+		}
+
+		ciphertext[len(ciphertext)-1] ^= 1
+		if _, err = aead.Open(nil, nonce, ciphertext, data); err == nil {
+			t.Fatalf("TestVector %d: Open accepted modified tag", i)
+		}
+	}
+}
+
+// TestThreefishSkeinComposition recomputes a Threefish-512-Skein-512
+// ciphertext from the threefish and skein packages - which are checked
+// against the official Skein 1.3 KATs - following the construction:
+// Threefish-CTR under the personalized Skein key and a Skein-MAC over the
+// additional data, the ciphertext and both lengths.
+func TestThreefishSkeinComposition(t *testing.T) {
+	key, nonce := make([]byte, 64), make([]byte, ThreefishSkeinNonceSize)
+	msg, data := make([]byte, 100), []byte("additional data")
+	for i := range key {
+		key[i] = byte(i)
+	}
+	for i := range nonce {
+		nonce[i] = byte(0xA0 + i)
+	}
+	for i := range msg {
+		msg[i] = byte(i * 7)
+	}
+
+	encKey := skein.Sum(nil, 64, &skein.Config{Key: key, Personal: []byte("enceve/crypto threefish-skein encryption")})
+	c, err := threefish.NewTweakableCipher(nil, encKey)
+	if err != nil {
+		t.Fatalf("Failed to create Threefish instance: %s", err)
+	}
+	block, keyStream := make([]byte, 64), make([]byte, 64)
+	copy(block, nonce)
+	var tweak [threefish.TweakSize]byte
+	expected := make([]byte, len(msg))
+	for i := range msg {
+		if i%64 == 0 {
+			tweak[0] = byte(i / 64)
+			c.EncryptTweak(keyStream, block, &tweak)
+		}
+		expected[i] = msg[i] ^ keyStream[i%64]
+	}
+
+	mac := skein.New(64, &skein.Config{Key: key, Personal: []byte("enceve/crypto threefish-skein authentication"), Nonce: nonce})
+	mac.Write(data)
+	mac.Write(expected)
+	mac.Write([]byte{byte(len(data)), 0, 0, 0, 0, 0, 0, 0, byte(len(msg)), 0, 0, 0, 0, 0, 0, 0})
+	expected = append(expected, mac.Sum(nil)...)
+
+	aead, err := NewThreefishSkein(key, 64)
+	if err != nil {
+		t.Fatalf("Failed to create Threefish-Skein instance: %s", err)
+	}
+	if ciphertext := aead.Seal(nil, nonce, msg, data); !bytes.Equal(ciphertext, expected) {
+		t.Fatalf("Seal failed:\nFound   : %s\nExpected: %s", hex.EncodeToString(ciphertext), hex.EncodeToString(expected))
+	}
+}
+
+func TestNewThreefishSkein(t *testing.T) {
+	badKeySizes := []int{0, 16, 31, 33, 63, 65, 127, 129}
+	for i, v := range badKeySizes {
+		if _, err := NewThreefishSkein(make([]byte, v), 16); err == nil {
+			t.Fatalf("Test %d: NewThreefishSkein accepted key size %d", i, v)
+		}
+	}
+	badTagSizes := []int{0, 65}
+	for i, v := range badTagSizes {
+		if _, err := NewThreefishSkein(make([]byte, 64), v); err == nil {
+			t.Fatalf("Test %d: NewThreefishSkein accepted tag size %d", i, v)
+		}
+	}
+}
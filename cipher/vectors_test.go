@@ -10,6 +10,14 @@ import (
 	"testing"
 )
 
+func fromHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
 type testVector struct {
 	msg, key, nonce, data string
 	ciphertext            string
@@ -102,14 +110,13 @@ func TestVectors(t *testing.T) {
 			t.Fatalf("TestVector %d: Failed to create EAX instance: %s", i, err)
 		}
 
-		buf := make([]byte, len(ciphertext))
-		buf = eax.Seal(buf, nonce, msg, data)
+		buf := eax.Seal(nil, nonce, msg, data)
 
 		if !bytes.Equal(buf, ciphertext) {
 			t.Fatalf("TestVector %d Seal failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(ciphertext))
 		}
 
-		buf, err = eax.Open(buf, nonce, buf, data)
+		buf, err = eax.Open(buf[:0], nonce, buf, data)
 
 		if err != nil {
 			t.Fatalf("TestVector %d: Open failed: %s", i, err)
@@ -0,0 +1,179 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+// XTSBlockSize is the block size of the XTS mode in bytes.
+// Every sector (data unit) must be at least XTSBlockSize bytes long.
+const XTSBlockSize = 16
+
+// XTS is a block cipher mode for the encryption of sectors (data units)
+// of storage devices. Every sector is identified by its sector number,
+// which is used as the tweak of the sector.
+type XTS interface {
+	// Encrypt encrypts the sector in src and writes the ciphertext into
+	// dst. The length of src must be at least XTSBlockSize bytes and dst
+	// must be at least as large as src. Dst and src may overlap entirely
+	// or not at all.
+	Encrypt(dst, src []byte, sector uint64)
+
+	// Decrypt decrypts the sector in src and writes the plaintext into
+	// dst. The length of src must be at least XTSBlockSize bytes and dst
+	// must be at least as large as src. Dst and src may overlap entirely
+	// or not at all.
+	Decrypt(dst, src []byte, sector uint64)
+}
+
+// The XTS cipher
+type xtsCipher struct {
+	blockCipher, tweakCipher cipher.Block
+}
+
+// NewXTS returns a XTS implementing the XTS-AES mode specified in
+// IEEE 1619 (and NIST SP 800-38E) for any block cipher with a block
+// size of 128 bit (like AES, Camellia or Serpent). The key is split into
+// two halves - the first half is the key of the data cipher, the second
+// half is the key of the tweak cipher - and the newCipher function
+// (like aes.NewCipher, camellia.NewCipher or serpent.NewCipher) must
+// return a cipher.Block for both halves.
+// The sector number is encoded as a 128 bit little endian integer, which
+// is compatible to the "plain64" IV of dm-crypt and to VeraCrypt.
+// Sectors, which are not a multiple of the block size, are processed
+// using ciphertext stealing.
+func NewXTS(key []byte, newCipher func(key []byte) (cipher.Block, error)) (XTS, error) {
+	if k := len(key); k == 0 || k%2 != 0 {
+		return nil, crypto.KeySizeError(k)
+	}
+	if newCipher == nil {
+		return nil, errors.New("the cipher constructor must not be nil")
+	}
+	c, err := newCipher(key[:len(key)/2])
+	if err != nil {
+		return nil, err
+	}
+	t, err := newCipher(key[len(key)/2:])
+	if err != nil {
+		return nil, err
+	}
+	if c.BlockSize() != XTSBlockSize {
+		return nil, errors.New("cipher block size not supported")
+	}
+	return &xtsCipher{
+		blockCipher: c,
+		tweakCipher: t,
+	}, nil
+}
+
+func (c *xtsCipher) Encrypt(dst, src []byte, sector uint64) {
+	c.checkArgs(dst, src)
+
+	var tweak, block [XTSBlockSize]byte
+	c.initTweak(&tweak, sector)
+
+	n := len(src) - len(src)%XTSBlockSize
+	if n < len(src) {
+		n -= XTSBlockSize // the last complete block is used for ciphertext stealing
+	}
+	for i := 0; i < n; i += XTSBlockSize {
+		crypto.XOR(block[:], src[i:i+XTSBlockSize], tweak[:])
+		c.blockCipher.Encrypt(block[:], block[:])
+		crypto.XOR(dst[i:], block[:], tweak[:])
+		mulAlpha(&tweak)
+	}
+
+	if n < len(src) {
+		tail := src[n+XTSBlockSize:]
+		crypto.XOR(block[:], src[n:n+XTSBlockSize], tweak[:])
+		c.blockCipher.Encrypt(block[:], block[:])
+		crypto.XOR(block[:], block[:], tweak[:])
+		mulAlpha(&tweak)
+
+		// swap the last partial block and the
+		// stolen ciphertext of the previous block
+		var last [XTSBlockSize]byte
+		copy(last[:], tail)
+		copy(dst[n+XTSBlockSize:], block[:len(tail)])
+		copy(last[len(tail):], block[len(tail):])
+
+		crypto.XOR(block[:], last[:], tweak[:])
+		c.blockCipher.Encrypt(block[:], block[:])
+		crypto.XOR(dst[n:], block[:], tweak[:])
+	}
+}
+
+func (c *xtsCipher) Decrypt(dst, src []byte, sector uint64) {
+	c.checkArgs(dst, src)
+
+	var tweak, block [XTSBlockSize]byte
+	c.initTweak(&tweak, sector)
+
+	n := len(src) - len(src)%XTSBlockSize
+	if n < len(src) {
+		n -= XTSBlockSize // the last complete block is used for ciphertext stealing
+	}
+	for i := 0; i < n; i += XTSBlockSize {
+		crypto.XOR(block[:], src[i:i+XTSBlockSize], tweak[:])
+		c.blockCipher.Decrypt(block[:], block[:])
+		crypto.XOR(dst[i:], block[:], tweak[:])
+		mulAlpha(&tweak)
+	}
+
+	if n < len(src) {
+		prevTweak := tweak
+		mulAlpha(&tweak)
+
+		tail := src[n+XTSBlockSize:]
+		crypto.XOR(block[:], src[n:n+XTSBlockSize], tweak[:])
+		c.blockCipher.Decrypt(block[:], block[:])
+		crypto.XOR(block[:], block[:], tweak[:])
+
+		// swap the last partial block and the
+		// stolen plaintext of the previous block
+		var last [XTSBlockSize]byte
+		copy(last[:], tail)
+		copy(dst[n+XTSBlockSize:], block[:len(tail)])
+		copy(last[len(tail):], block[len(tail):])
+
+		crypto.XOR(block[:], last[:], prevTweak[:])
+		c.blockCipher.Decrypt(block[:], block[:])
+		crypto.XOR(dst[n:], block[:], prevTweak[:])
+	}
+}
+
+// checkArgs panics if the length of src or dst is not valid.
+func (c *xtsCipher) checkArgs(dst, src []byte) {
+	if len(src) < XTSBlockSize {
+		panic("src must be at least XTSBlockSize bytes long")
+	}
+	if len(dst) < len(src) {
+		panic("dst buffer to small")
+	}
+}
+
+// initTweak encrypts the sector number - encoded
+// as 128 bit little endian integer - with the tweak cipher.
+func (c *xtsCipher) initTweak(tweak *[XTSBlockSize]byte, sector uint64) {
+	for i := 0; i < 8; i++ {
+		tweak[i] = byte(sector >> uint(8*i))
+	}
+	c.tweakCipher.Encrypt(tweak[:], tweak[:])
+}
+
+// mulAlpha multiplies the tweak by the primitive element α (x) of
+// GF(2^128). In contrast to double the tweak is a little endian value.
+func mulAlpha(tweak *[XTSBlockSize]byte) {
+	var carry byte
+	for i := range tweak {
+		b := tweak[i] >> 7
+		tweak[i] = tweak[i]<<1 | carry
+		carry = b
+	}
+	tweak[0] ^= 0x87 & -carry
+}
@@ -0,0 +1,230 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"testing"
+
+	"github.com/enceve/crypto/camellia"
+	"github.com/enceve/crypto/serpent"
+)
+
+type xtsTestVector struct {
+	key        string
+	sector     uint64
+	msg        string
+	ciphertext string
+}
+
+// XTS-AES test vectors from IEEE 1619 (Vector 1 - 4, 10 and 15 - 18)
+var xtsVectors = []xtsTestVector{
+	{
+		key:        "0000000000000000000000000000000000000000000000000000000000000000",
+		sector:     0x0,
+		msg:        "0000000000000000000000000000000000000000000000000000000000000000",
+		ciphertext: "917cf69ebd68b2ec9b9fe9a3eadda692cd43d2f59598ed858c02c2652fbf922e",
+	},
+	{
+		key:        "1111111111111111111111111111111122222222222222222222222222222222",
+		sector:     0x3333333333,
+		msg:        "4444444444444444444444444444444444444444444444444444444444444444",
+		ciphertext: "c454185e6a16936e39334038acef838bfb186fff7480adc4289382ecd6d394f0",
+	},
+	{
+		key:        "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f022222222222222222222222222222222",
+		sector:     0x3333333333,
+		msg:        "4444444444444444444444444444444444444444444444444444444444444444",
+		ciphertext: "af85336b597afc1a900b2eb21ec949d292df4c047e0b21532186a5971a227a89",
+	},
+	{
+		key:        "2718281828459045235360287471352631415926535897932384626433832795",
+		sector:     0x0,
+		msg:        "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f606162636465666768696a6b6c6d6e6f707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9fa0a1a2a3a4a5a6a7a8a9aaabacadaeafb0b1b2b3b4b5b6b7b8b9babbbcbdbebfc0c1c2c3c4c5c6c7c8c9cacbcccdcecfd0d1d2d3d4d5d6d7d8d9dadbdcdddedfe0e1e2e3e4e5e6e7e8e9eaebecedeeeff0f1f2f3f4f5f6f7f8f9fafbfcfdfeff000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f606162636465666768696a6b6c6d6e6f707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9fa0a1a2a3a4a5a6a7a8a9aaabacadaeafb0b1b2b3b4b5b6b7b8b9babbbcbdbebfc0c1c2c3c4c5c6c7c8c9cacbcccdcecfd0d1d2d3d4d5d6d7d8d9dadbdcdddedfe0e1e2e3e4e5e6e7e8e9eaebecedeeeff0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+		ciphertext: "27a7479befa1d476489f308cd4cfa6e2a96e4bbe3208ff25287dd3819616e89cc78cf7f5e543445f8333d8fa7f56000005279fa5d8b5e4ad40e736ddb4d35412328063fd2aab53e5ea1e0a9f332500a5df9487d07a5c92cc512c8866c7e860ce93fdf166a24912b422976146ae20ce846bb7dc9ba94a767aaef20c0d61ad02655ea92dc4c4e41a8952c651d33174be51a10c421110e6d81588ede82103a252d8a750e8768defffed9122810aaeb99f9172af82b604dc4b8e51bcb08235a6f4341332e4ca60482a4ba1a03b3e65008fc5da76b70bf1690db4eae29c5f1badd03c5ccf2a55d705ddcd86d449511ceb7ec30bf12b1fa35b913f9f747a8afd1b130e94bff94effd01a91735ca1726acd0b197c4e5b03393697e126826fb6bbde8ecc1e08298516e2c9ed03ff3c1b7860f6de76d4cecd94c8119855ef5297ca67e9f3e7ff72b1e99785ca0a7e7720c5b36dc6d72cac9574c8cbbc2f801e23e56fd344b07f22154beba0f08ce8891e643ed995c94d9a69c9f1b5f499027a78572aeebd74d20cc39881c213ee770b1010e4bea718846977ae119f7a023ab58cca0ad752afe656bb3c17256a9f6e9bf19fdd5a38fc82bbe872c5539edb609ef4f79c203ebb140f2e583cb2ad15b4aa5b655016a8449277dbd477ef2c8d6c017db738b18deb4a427d1923ce3ff262735779a418f20a282df920147beabe421ee5319d0568",
+	},
+	{
+		key:        "27182818284590452353602874713526624977572470936999595749669676273141592653589793238462643383279502884197169399375105820974944592",
+		sector:     0xff,
+		msg:        "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f606162636465666768696a6b6c6d6e6f707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9fa0a1a2a3a4a5a6a7a8a9aaabacadaeafb0b1b2b3b4b5b6b7b8b9babbbcbdbebfc0c1c2c3c4c5c6c7c8c9cacbcccdcecfd0d1d2d3d4d5d6d7d8d9dadbdcdddedfe0e1e2e3e4e5e6e7e8e9eaebecedeeeff0f1f2f3f4f5f6f7f8f9fafbfcfdfeff000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f606162636465666768696a6b6c6d6e6f707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9fa0a1a2a3a4a5a6a7a8a9aaabacadaeafb0b1b2b3b4b5b6b7b8b9babbbcbdbebfc0c1c2c3c4c5c6c7c8c9cacbcccdcecfd0d1d2d3d4d5d6d7d8d9dadbdcdddedfe0e1e2e3e4e5e6e7e8e9eaebecedeeeff0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+		ciphertext: "1c3b3a102f770386e4836c99e370cf9bea00803f5e482357a4ae12d414a3e63b5d31e276f8fe4a8d66b317f9ac683f44680a86ac35adfc3345befecb4bb188fd5776926c49a3095eb108fd1098baec70aaa66999a72a82f27d848b21d4a741b0c5cd4d5fff9dac89aeba122961d03a757123e9870f8acf1000020887891429ca2a3e7a7d7df7b10355165c8b9a6d0a7de8b062c4500dc4cd120c0f7418dae3d0b5781c34803fa75421c790dfe1de1834f280d7667b327f6c8cd7557e12ac3a0f93ec05c52e0493ef31a12d3d9260f79a289d6a379bc70c50841473d1a8cc81ec583e9645e07b8d9670655ba5bbcfecc6dc3966380ad8fecb17b6ba02469a020a84e18e8f84252070c13e9f1f289be54fbc481457778f616015e1327a02b140f1505eb309326d68378f8374595c849d84f4c333ec4423885143cb47bd71c5edae9be69a2ffeceb1bec9de244fbe15992b11b77c040f12bd8f6a975a44a0f90c29a9abc3d4d893927284c58754cce294529f8614dcd2aba991925fedc4ae74ffac6e333b93eb4aff0479da9a410e4450e0dd7ae4c6e2910900575da401fc07059f645e8b7e9bfdef33943054ff84011493c27b3429eaedb4ed5376441a77ed43851ad77f16f541dfd269d50d6a5f14fb0aab1cbb4c1550be97f7ab4066193c4caa773dad38014bd2092fa755c824bb5e54c4f36ffda9fcea70b9c6e693e148c151",
+	},
+	{
+		key:        "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0bfbebdbcbbbab9b8b7b6b5b4b3b2b1b0",
+		sector:     0x123456789a,
+		msg:        "000102030405060708090a0b0c0d0e0f10",
+		ciphertext: "6c1625db4671522d3d7599601de7ca09ed",
+	},
+	{
+		key:        "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0bfbebdbcbbbab9b8b7b6b5b4b3b2b1b0",
+		sector:     0x123456789a,
+		msg:        "000102030405060708090a0b0c0d0e0f1011",
+		ciphertext: "d069444b7a7e0cab09e24447d24deb1fedbf",
+	},
+	{
+		key:        "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0bfbebdbcbbbab9b8b7b6b5b4b3b2b1b0",
+		sector:     0x123456789a,
+		msg:        "000102030405060708090a0b0c0d0e0f101112",
+		ciphertext: "e5df1351c0544ba1350b3363cd8ef4beedbf9d",
+	},
+	{
+		key:        "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0bfbebdbcbbbab9b8b7b6b5b4b3b2b1b0",
+		sector:     0x123456789a,
+		msg:        "000102030405060708090a0b0c0d0e0f10111213",
+		ciphertext: "9d84c813f719aa2c7be3f66171c7c5c2edbf9dac",
+	},
+}
+
+// XTS-Serpent and XTS-Camellia test vectors. There are no published XTS
+// vectors for these ciphers, so the ciphertexts were computed with the XTS
+// mode of libgcrypt 1.10.1 (the backend of cryptsetup / dm-crypt) for the
+// inputs of IEEE 1619 Vector 2, 4, 10, 15 and 18. The sector number is the
+// 128 bit little endian IV ("plain64").
+var xtsSerpentVectors = []xtsTestVector{
+	{
+		key:        "1111111111111111111111111111111122222222222222222222222222222222",
+		sector:     0x3333333333,
+		msg:        "4444444444444444444444444444444444444444444444444444444444444444",
+		ciphertext: "1a0a095fcd070798418612afb3d76813ed81cd0687431abb133dd61e2be177be",
+	},
+	{
+		key:        "2718281828459045235360287471352631415926535897932384626433832795",
+		sector:     0x0,
+		msg:        "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f606162636465666768696a6b6c6d6e6f707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9fa0a1a2a3a4a5a6a7a8a9aaabacadaeafb0b1b2b3b4b5b6b7b8b9babbbcbdbebfc0c1c2c3c4c5c6c7c8c9cacbcccdcecfd0d1d2d3d4d5d6d7d8d9dadbdcdddedfe0e1e2e3e4e5e6e7e8e9eaebecedeeeff0f1f2f3f4f5f6f7f8f9fafbfcfdfeff000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f606162636465666768696a6b6c6d6e6f707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9fa0a1a2a3a4a5a6a7a8a9aaabacadaeafb0b1b2b3b4b5b6b7b8b9babbbcbdbebfc0c1c2c3c4c5c6c7c8c9cacbcccdcecfd0d1d2d3d4d5d6d7d8d9dadbdcdddedfe0e1e2e3e4e5e6e7e8e9eaebecedeeeff0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+		ciphertext: "fe474ac8607eb48b0d10f4b00dbaf853656e384bdbaab19e28cab022b38575f4005c751406d62582e6cb08f72990238ea46857e4f0d832f3805167b50b8569e819fec4c73eea90d38fa3f20aac174ba0635a160ff0ce661f2c2107f1a403a3444161875d6bb3efd4fcaa327e55580441c90733c6a268d65a55794b6fcf89b919e5541315b21afa15c2f00659faa0250558fa4391168540bb0d344dc51e20d508cd222241119f6c7c8d57c9ba57e82cf7a042a8defca3ca984b43b1ce4bbf01676e2960bd1014848283820c637392027c553720801751c8bc4602cb38076de285aa29af24580df075080aa5342516f374a70b97bec1a9dc291a0a56c11a91978c0bc716ed5a22a62e8c2b4f547647538ee800ec92b955e6a2f3e24f6a6660d087e6d1cce36ac52d21cc9d6ab675aae219219fa15e4cfd72f9944e63c7aefced47e2fe7a6377fe9782b1106e361de1c480ec6941eca78ae02fe34926a241b2080f28b4a739a1992d1e434235d0cfec7767b23b9e1c35de4f5e733f5d6f074b2e50ab6c6bffea0067aa0e8232dd3db5e5762b773fbe1275fb92c689674dcaf7d450c07447ccd90ad4c63b172ee335bb53b586ad51ccd596b8dc0357e698522f6162c45c9c367107fb94e302c42b0875c735fb2e887bbb6700e1c9dd99b213531a4e768719041a2f383eef91641d18074e3188217cb0a5124c3cb020bddadff97cdd",
+	},
+	{
+		key:        "27182818284590452353602874713526624977572470936999595749669676273141592653589793238462643383279502884197169399375105820974944592",
+		sector:     0xff,
+		msg:        "4444444444444444444444444444444444444444444444444444444444444444",
+		ciphertext: "1b1fa92dfb1b0550164c36e9f5c71d562ec97462ed9bd7011c6d00c8301dec18",
+	},
+	{
+		key:        "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0bfbebdbcbbbab9b8b7b6b5b4b3b2b1b0",
+		sector:     0x123456789a,
+		msg:        "000102030405060708090a0b0c0d0e0f10",
+		ciphertext: "3fba5e10b3b76913b3caa7e447e9939b08",
+	},
+	{
+		key:        "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0bfbebdbcbbbab9b8b7b6b5b4b3b2b1b0",
+		sector:     0x123456789a,
+		msg:        "000102030405060708090a0b0c0d0e0f10111213",
+		ciphertext: "2cdbf480ac944f1e938b326fb9b8ba6108515248",
+	},
+}
+
+var xtsCamelliaVectors = []xtsTestVector{
+	{
+		key:        "1111111111111111111111111111111122222222222222222222222222222222",
+		sector:     0x3333333333,
+		msg:        "4444444444444444444444444444444444444444444444444444444444444444",
+		ciphertext: "c2b9dc441ddff2868d35420aa55e3d4fb53706ffbdd49170801fb239108944f5",
+	},
+	{
+		key:        "2718281828459045235360287471352631415926535897932384626433832795",
+		sector:     0x0,
+		msg:        "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f606162636465666768696a6b6c6d6e6f707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9fa0a1a2a3a4a5a6a7a8a9aaabacadaeafb0b1b2b3b4b5b6b7b8b9babbbcbdbebfc0c1c2c3c4c5c6c7c8c9cacbcccdcecfd0d1d2d3d4d5d6d7d8d9dadbdcdddedfe0e1e2e3e4e5e6e7e8e9eaebecedeeeff0f1f2f3f4f5f6f7f8f9fafbfcfdfeff000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f606162636465666768696a6b6c6d6e6f707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9fa0a1a2a3a4a5a6a7a8a9aaabacadaeafb0b1b2b3b4b5b6b7b8b9babbbcbdbebfc0c1c2c3c4c5c6c7c8c9cacbcccdcecfd0d1d2d3d4d5d6d7d8d9dadbdcdddedfe0e1e2e3e4e5e6e7e8e9eaebecedeeeff0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+		ciphertext: "c7f90aaacbb58f3360c3e94790b75057a3ad812ff5229602aa7feaac2978ca2a7ccd311a3c400a730966ad720e4d5d77bcb876803759a9019efbdb6c93efb68d1ec194a8d4b5b001d5019728cd7a1fe808da760065cf7b31c6faf23b00a76a9e6c438087e0bb4ee5dc8adfc31d1b4104fb54dd2927c265173688b0858d737e4b1d168a52bca6bca48cd10416bf8c010f7e6b591529d19bd36ceeacdc4558ca5b700e6a128682799f16d49d67cd70652621721ea1948a830c9242585ea2c531f37bd131d4158031615c5310ddeac8835c7da70566cc1ebb0547aeb40f84d8f6b5a1c6520052e8dcd91631b2479167aa282c2985a3f7f2249323801fa81b828ddc9f0bcdb43c20bcec4fc7eef8fdd9fb7e3f0d23fa3fa7cc661cfea686f6f785c743c1d4fce479c91df889cd2027845d5c8e4f1feb08214fa3e07e0b9ce742cfb73f43cc8671346ad95eec8f36c90a03fe1841dc9e2e75203ecc77e08fe843374ced1a5ab3fa43c9719fc5cecfffe7771e3593de6bc06a7ea934b8277408daf24a235b9f553a578252ea6dc3c7f2c8b5dcc5b9bbaaf2299f497aeffedc9fc928e2960b3584050dd62aea5abf69deee4f8f84b9cfa757eae0e896ef0f0eecc7a674b1fe7a6d11dd0e154a1e737f55eaf6e15bb671dab00cba265c48386d1c32b27d0587c21e7e2dd433cc06dbe7822963d152844fee27e802d4343c69c2bd20e67a",
+	},
+	{
+		key:        "27182818284590452353602874713526624977572470936999595749669676273141592653589793238462643383279502884197169399375105820974944592",
+		sector:     0xff,
+		msg:        "4444444444444444444444444444444444444444444444444444444444444444",
+		ciphertext: "c82f24c44876b790c7ccd9fb82d2c62b12955b02924f7888b64ae95ac93ff348",
+	},
+	{
+		key:        "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0bfbebdbcbbbab9b8b7b6b5b4b3b2b1b0",
+		sector:     0x123456789a,
+		msg:        "000102030405060708090a0b0c0d0e0f10",
+		ciphertext: "26f473fe80872a176faacd4b698525ef58",
+	},
+	{
+		key:        "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0bfbebdbcbbbab9b8b7b6b5b4b3b2b1b0",
+		sector:     0x123456789a,
+		msg:        "000102030405060708090a0b0c0d0e0f10111213",
+		ciphertext: "2673e2b087a55d7329ca97db665f633b58f7b50f",
+	},
+}
+
+func TestXTSVectors(t *testing.T) {
+	testXTSVectors(t, "AES", xtsVectors, aes.NewCipher)
+	testXTSVectors(t, "Serpent", xtsSerpentVectors, serpent.NewCipher)
+	testXTSVectors(t, "Camellia", xtsCamelliaVectors, camellia.NewCipher)
+}
+
+func testXTSVectors(t *testing.T, name string, vectors []xtsTestVector, newCipher func([]byte) (cipher.Block, error)) {
+	for i, v := range vectors {
+		key, msg, ciphertext := fromHex(v.key), fromHex(v.msg), fromHex(v.ciphertext)
+
+		xts, err := NewXTS(key, newCipher)
+		if err != nil {
+			t.Fatalf("%s: TestVector %d: Failed to create XTS instance: %s", name, i, err)
+		}
+
+		buf := make([]byte, len(msg))
+		xts.Encrypt(buf, msg, v.sector)
+		if !bytes.Equal(buf, ciphertext) {
+			t.Fatalf("%s: TestVector %d Encrypt failed:\nFound   : %s\nExpected: %s", name, i, hex.EncodeToString(buf), hex.EncodeToString(ciphertext))
+		}
+
+		xts.Decrypt(buf, buf, v.sector)
+		if !bytes.Equal(buf, msg) {
+			t.Fatalf("%s: TestVector %d Decrypt failed:\nFound   : %s\nExpected: %s", name, i, hex.EncodeToString(buf), hex.EncodeToString(msg))
+		}
+	}
+}
+
+func testXTS(t *testing.T, name string, keysize int, newCipher func([]byte) (cipher.Block, error)) {
+	key := make([]byte, 2*keysize)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	xts, err := NewXTS(key, newCipher)
+	if err != nil {
+		t.Fatalf("%s: Failed to create XTS instance: %s", name, err)
+	}
+
+	msg := make([]byte, 512)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	for i := XTSBlockSize; i <= len(msg); i++ {
+		ciphertext := make([]byte, i)
+		xts.Encrypt(ciphertext, msg[:i], uint64(i))
+
+		plaintext := make([]byte, i)
+		xts.Decrypt(plaintext, ciphertext, uint64(i))
+		if !bytes.Equal(plaintext, msg[:i]) {
+			t.Fatalf("%s: Decrypt failed:\nFound   : %s\nExpected: %s", name, hex.EncodeToString(plaintext), hex.EncodeToString(msg[:i]))
+		}
+
+		xts.Decrypt(plaintext, ciphertext, uint64(i+1))
+		if bytes.Equal(plaintext, msg[:i]) {
+			t.Fatalf("%s: Decrypt with wrong sector number succeeded", name)
+		}
+	}
+}
+
+func TestXTS(t *testing.T) {
+	for _, keysize := range []int{16, 24, 32} {
+		testXTS(t, "AES", keysize, aes.NewCipher)
+		testXTS(t, "Camellia", keysize, camellia.NewCipher)
+		testXTS(t, "Serpent", keysize, serpent.NewCipher)
+	}
+}
+
+func TestNewXTS(t *testing.T) {
+	if _, err := NewXTS(make([]byte, 33), aes.NewCipher); err == nil {
+		t.Fatal("NewXTS accepted a key with an odd length")
+	}
+	if _, err := NewXTS(make([]byte, 40), aes.NewCipher); err == nil {
+		t.Fatal("NewXTS accepted an invalid AES key size")
+	}
+	if _, err := NewXTS(make([]byte, 32), nil); err == nil {
+		t.Fatal("NewXTS accepted a nil cipher constructor")
+	}
+}
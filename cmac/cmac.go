@@ -17,6 +17,9 @@
 // tweakable block cipher. Ciphers with 64 bit blocks are
 // supported, but not recommened.
 // CMac (using AES) is specified in RFC 4493.
+// This package also implements the parallelizable
+// PMAC1 for 64 and 128 bit block ciphers (see NewPMAC),
+// OMAC2, XCBC-MAC (RFC 3566) and AES-CMAC-PRF-128 (RFC 4615).
 package cmac
 
 import (
@@ -0,0 +1,228 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cmac
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"hash"
+	"runtime"
+	"sync"
+
+	"github.com/enceve/crypto"
+)
+
+// The min. number of blocks processed by one goroutine. Writes
+// of less than 2*pmacParallelBlocks blocks are processed sequentially.
+const pmacParallelBlocks = 512
+
+// NewPMAC returns a hash.Hash computing the PMAC1 checksum specified in
+// "A Block-Cipher Mode of Operation for Parallelizable Message Authentication"
+// (http://web.cs.ucdavis.edu/~rogaway/ocb/pmac.pdf). In contrast to CMac
+// the block cipher calls of PMAC are independent, so large writes are
+// processed in parallel. Therefore the cipher.Block must be safe for
+// concurrent use (like AES, Camellia or Serpent). PMAC supports block
+// ciphers with a block size of 64 bit (8 byte) and 128 bit (16 byte).
+// If the block cipher is not supported, a non-nil error is returned.
+func NewPMAC(c cipher.Block) (hash.Hash, error) {
+	if c == nil {
+		return nil, errors.New("the cipher.Block must not be nil")
+	}
+	bs := c.BlockSize()
+
+	var p int
+	switch bs {
+	default:
+		return nil, errors.New("cipher block size not supported")
+	case 8:
+		p = p64
+	case 16:
+		p = p128
+	}
+
+	m := &pmacFunc{
+		cipher: c,
+		lInv:   make([]byte, bs),
+		offset: make([]byte, bs),
+		sum:    make([]byte, bs),
+		buf:    make([]byte, bs),
+	}
+
+	// L(0) = E(0^n) and L(i) = L(i-1)·x
+	m.l[0] = make([]byte, bs)
+	c.Encrypt(m.l[0], m.l[0])
+	for i := 1; i < len(m.l); i++ {
+		m.l[i] = make([]byte, bs)
+		v := shift(m.l[i], m.l[i-1])
+		m.l[i][bs-1] ^= byte(subtle.ConstantTimeSelect(v, p, 0))
+	}
+
+	// L(-1) = L(0)·x^-1
+	v := int(m.l[0][bs-1] & 1)
+	var b byte
+	for i := range m.l[0] {
+		m.lInv[i] = m.l[0][i]>>1 | b
+		b = m.l[0][i] << 7
+	}
+	m.lInv[0] ^= byte(subtle.ConstantTimeSelect(v, 0x80, 0))
+	m.lInv[bs-1] ^= byte(subtle.ConstantTimeSelect(v, p>>1, 0))
+
+	return m, nil
+}
+
+// The PMAC1 message auth. function
+type pmacFunc struct {
+	cipher cipher.Block
+	l      [64][]byte // L(i) = L·x^i
+	lInv   []byte     // L(-1) = L·x^-1
+	offset []byte
+	sum    []byte
+	ctr    uint64 // the number of processed blocks
+	buf    []byte
+	off    int
+}
+
+func (h *pmacFunc) Size() int { return h.cipher.BlockSize() }
+
+func (h *pmacFunc) BlockSize() int { return h.cipher.BlockSize() }
+
+func (h *pmacFunc) Reset() {
+	for i := range h.buf {
+		h.buf[i] = 0
+		h.offset[i] = 0
+		h.sum[i] = 0
+	}
+	h.ctr = 0
+	h.off = 0
+}
+
+func (h *pmacFunc) Write(msg []byte) (int, error) {
+	bs := h.BlockSize()
+	n := len(msg)
+
+	// the last block is processed by Sum, so
+	// keep (at least) one byte in the buffer
+	if h.off > 0 {
+		k := copy(h.buf[h.off:], msg)
+		h.off += k
+		msg = msg[k:]
+		if len(msg) == 0 {
+			return n, nil
+		}
+		h.processBlocks(h.buf)
+		h.off = 0
+	}
+
+	if length := len(msg); length > bs {
+		nn := length & (^(bs - 1))
+		if length == nn {
+			nn -= bs
+		}
+		h.processBlocks(msg[:nn])
+		msg = msg[nn:]
+	}
+
+	h.off += copy(h.buf, msg)
+	return n, nil
+}
+
+func (h *pmacFunc) Sum(b []byte) []byte {
+	bs := h.cipher.BlockSize()
+
+	// Don't change the state so the
+	// caller can keep writing and suming.
+	hash := make([]byte, bs)
+	copy(hash, h.sum)
+
+	crypto.XOR(hash, hash, h.buf[:h.off])
+	if h.off == bs {
+		crypto.XOR(hash, hash, h.lInv)
+	} else {
+		hash[h.off] ^= 0x80
+	}
+
+	h.cipher.Encrypt(hash, hash)
+	return append(b, hash...)
+}
+
+// processBlocks processes the complete blocks of msg. Large
+// messages are split into parts processed by multiple goroutines.
+func (h *pmacFunc) processBlocks(msg []byte) {
+	bs := h.cipher.BlockSize()
+	blocks := len(msg) / bs
+
+	workers := runtime.GOMAXPROCS(0)
+	if max := blocks / pmacParallelBlocks; workers > max {
+		workers = max
+	}
+	if workers < 2 {
+		h.ctr = h.process(h.offset, h.sum, msg, h.ctr)
+		return
+	}
+
+	part := (blocks + workers - 1) / workers * bs
+	sums := make([][]byte, workers)
+	var wg sync.WaitGroup
+	for i := range sums {
+		j, k := i*part, (i+1)*part
+		if k > len(msg) {
+			k = len(msg)
+		}
+		sums[i] = make([]byte, bs)
+
+		wg.Add(1)
+		go func(sum, msg []byte, ctr uint64) {
+			defer wg.Done()
+			offset := make([]byte, bs)
+			h.offsetAt(offset, ctr)
+			h.process(offset, sum, msg, ctr)
+		}(sums[i], msg[j:k], h.ctr+uint64(j/bs))
+	}
+	wg.Wait()
+
+	for _, sum := range sums {
+		crypto.XOR(h.sum, h.sum, sum)
+	}
+	h.ctr += uint64(blocks)
+	h.offsetAt(h.offset, h.ctr)
+}
+
+// process processes the complete blocks of msg - starting with the
+// block number ctr+1 - and updates the offset and the sum.
+// It returns the number of processed blocks including ctr.
+func (h *pmacFunc) process(offset, sum, msg []byte, ctr uint64) uint64 {
+	bs := h.cipher.BlockSize()
+	block := make([]byte, bs)
+	for i := 0; i < len(msg); i += bs {
+		ctr++
+		crypto.XOR(offset, offset, h.l[ntz(ctr)])
+		crypto.XOR(block, offset, msg[i:i+bs])
+		h.cipher.Encrypt(block, block)
+		crypto.XOR(sum, sum, block)
+	}
+	return ctr
+}
+
+// offsetAt computes the offset of the i-th block. The offset is
+// the sum of L(k) for all bits k set in the gray code of i.
+func (h *pmacFunc) offsetAt(offset []byte, i uint64) {
+	for j := range offset {
+		offset[j] = 0
+	}
+	for k, gray := 0, i^(i>>1); gray != 0; k, gray = k+1, gray>>1 {
+		if gray&1 == 1 {
+			crypto.XOR(offset, offset, h.l[k])
+		}
+	}
+}
+
+// ntz returns the number of trailing zeros of x (x != 0).
+func ntz(x uint64) (n int) {
+	for x&1 == 0 {
+		x >>= 1
+		n++
+	}
+	return
+}
@@ -0,0 +1,159 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"runtime"
+	"testing"
+)
+
+// Test vectors for PMAC-AES-128
+var pmacVectors = []struct {
+	key, msg, hash string
+}{
+	{
+		key:  "000102030405060708090a0b0c0d0e0f",
+		msg:  "",
+		hash: "4399572cd6ea5341b8d35876a7098af7",
+	},
+	{
+		key:  "000102030405060708090a0b0c0d0e0f",
+		msg:  "000102",
+		hash: "256ba5193c1b991b4df0c51f388a9e27",
+	},
+	{
+		key:  "000102030405060708090a0b0c0d0e0f",
+		msg:  "000102030405060708090a0b0c0d0e0f",
+		hash: "ebbd822fa458daf6dfdad7c27da76338",
+	},
+	{
+		key:  "000102030405060708090a0b0c0d0e0f",
+		msg:  "000102030405060708090a0b0c0d0e0f10111213",
+		hash: "0412ca150bbf79058d8c75a58c993f55",
+	},
+	{
+		key:  "000102030405060708090a0b0c0d0e0f",
+		msg:  "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		hash: "e97ac04e9e5e3399ce5355cd7407bc75",
+	},
+	{
+		key:  "000102030405060708090a0b0c0d0e0f",
+		msg:  "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f2021",
+		hash: "5cba7d5eb24f7c86ccc54604e53d5512",
+	},
+}
+
+func TestPMACVectors(t *testing.T) {
+	for i, v := range pmacVectors {
+		key, err := hex.DecodeString(v.key)
+		if err != nil {
+			t.Fatalf("Test vector %d: Failed to decode key - Caused by: %s", i, err)
+		}
+		msg, err := hex.DecodeString(v.msg)
+		if err != nil {
+			t.Fatalf("Test vector %d: Failed to decode msg - Caused by: %s", i, err)
+		}
+		tag, err := hex.DecodeString(v.hash)
+		if err != nil {
+			t.Fatalf("Test vector %d: Failed to decode hash - Caused by: %s", i, err)
+		}
+		c, err := aes.NewCipher(key)
+		if err != nil {
+			t.Fatalf("Test vector %d: Failed to create AES instance: %s", i, err)
+		}
+		h, err := NewPMAC(c)
+		if err != nil {
+			t.Fatalf("Test vector %d: Failed to create PMAC instance: %s", i, err)
+		}
+		h.Write(msg)
+		if sum := h.Sum(nil); !bytes.Equal(sum, tag) {
+			t.Fatalf("Test vector %d: Hash values don't match - found %x expected %x", i, sum, tag)
+		}
+	}
+}
+
+func TestNewPMAC(t *testing.T) {
+	if _, err := NewPMAC(nil); err == nil {
+		t.Fatal("PMAC allowed nil for block cipher")
+	}
+	for _, c := range []dummyCipher{8, 16} {
+		if _, err := NewPMAC(c); err != nil {
+			t.Fatalf("BlockSize: %d, Failed to create PMAC instance: %s", int(c), err)
+		}
+	}
+	for _, c := range []dummyCipher{20, 32} {
+		if _, err := NewPMAC(c); err == nil {
+			t.Fatalf("PMAC allowed invalid block size: %d", int(c))
+		}
+	}
+}
+
+func TestPMACWrite(t *testing.T) {
+	c, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Could not create AES instance: %s", err)
+	}
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	msg := make([]byte, 9*pmacParallelBlocks*c.BlockSize()+7)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	for _, size := range []int{0, 1, 16, 17, 2*pmacParallelBlocks*16 + 16, len(msg)} {
+		// large writes are processed in parallel
+		h0, err := NewPMAC(c)
+		if err != nil {
+			t.Fatalf("Failed to create PMAC instance: %s", err)
+		}
+		h0.Write(msg[:size])
+		tag0 := h0.Sum(nil)
+
+		h1, err := NewPMAC(c)
+		if err != nil {
+			t.Fatalf("Failed to create PMAC instance: %s", err)
+		}
+		for i := 0; i < size; i += 100 {
+			j := i + 100
+			if j > size {
+				j = size
+			}
+			h1.Write(msg[i:j])
+		}
+		tag1 := h1.Sum(nil)
+
+		if !bytes.Equal(tag0, tag1) {
+			t.Fatalf("Size %d: parallel and sequential PMAC differ\n parallel: %x \n sequential: %x", size, tag0, tag1)
+		}
+
+		h0.Reset()
+		if size > 0 {
+			h0.Write(msg[:1]) // the buffered block is processed before the parallel write
+			h0.Write(msg[1:size])
+		}
+		if tag := h0.Sum(nil); !bytes.Equal(tag, tag0) {
+			t.Fatalf("Size %d: PMAC differs after Reset", size)
+		}
+	}
+}
+
+func BenchmarkPMACWrite_64K(b *testing.B) {
+	c, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		b.Fatalf("Failed to create AES instance: %s", err)
+	}
+	h, err := NewPMAC(c)
+	if err != nil {
+		b.Fatalf("Failed to create PMAC instance: %s", err)
+	}
+
+	buf := make([]byte, 64*1024)
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Write(buf)
+	}
+}
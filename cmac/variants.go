@@ -0,0 +1,152 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cmac
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"hash"
+)
+
+// MinTagSize is the min. size of a truncated tag accepted
+// by VerifyTruncated. RFC 4493 recommends tags of at least 64 bit.
+const MinTagSize = 8
+
+// VerifyTruncated computes the checksum of msg using the hash.Hash - e.g.
+// returned by New, NewOMAC2, NewXCBC or NewPMAC - and compares the first
+// tagSize bytes with the given mac. This function returns true if and only
+// if the mac is tagSize bytes long and equal to the truncated checksum.
+// The tagSize must be between MinTagSize and the size of the hash.Hash -
+// otherwise this function returns false. For example AES-XCBC-MAC-96
+// (RFC 3566) uses a tagSize of 12. VerifyTruncated resets the hash.Hash.
+func VerifyTruncated(mac, msg []byte, h hash.Hash, tagSize int) bool {
+	if tagSize < MinTagSize || tagSize > h.Size() || len(mac) != tagSize {
+		return false
+	}
+	h.Reset()
+	h.Write(msg)
+	sum := h.Sum(nil)
+	h.Reset()
+	return subtle.ConstantTimeCompare(mac, sum[:tagSize]) == 1
+}
+
+// NewOMAC2 returns a hash.Hash computing the OMAC2 checksum. OMAC2
+// differs from CMac (OMAC1) only in the second subkey, which is L·x^-1
+// instead of L·x^2. OMAC2 supports block ciphers with a block size of
+// 64 bit (8 byte) and 128 bit (16 byte). If the block cipher is not
+// supported, a non-nil error is returned.
+func NewOMAC2(c cipher.Block) (hash.Hash, error) {
+	if c == nil {
+		return nil, errors.New("the cipher.Block must not be nil")
+	}
+	bs := c.BlockSize()
+
+	var p int
+	switch bs {
+	default:
+		return nil, errors.New("cipher block size not supported")
+	case 8:
+		p = p64
+	case 16:
+		p = p128
+	}
+
+	m := &macFunc{
+		cipher: c,
+		k0:     make([]byte, bs),
+		k1:     make([]byte, bs),
+		buf:    make([]byte, bs),
+	}
+	c.Encrypt(m.k0, m.k0)
+
+	// k1 = L·x^-1
+	v := int(m.k0[bs-1] & 1)
+	var b byte
+	for i := range m.k0 {
+		m.k1[i] = m.k0[i]>>1 | b
+		b = m.k0[i] << 7
+	}
+	m.k1[0] ^= byte(subtle.ConstantTimeSelect(v, 0x80, 0))
+	m.k1[bs-1] ^= byte(subtle.ConstantTimeSelect(v, p>>1, 0))
+
+	// k0 = L·x
+	v = shift(m.k0, m.k0)
+	m.k0[bs-1] ^= byte(subtle.ConstantTimeSelect(v, p, 0))
+	return m, nil
+}
+
+// NewXCBC returns a hash.Hash computing the XCBC-MAC checksum specified in
+// RFC 3566 (AES-XCBC-MAC-96 uses the first 12 bytes). The newCipher function
+// must return a cipher.Block with a block size of 128 bit (like aes.NewCipher)
+// for the key and the derived 16 byte key. If the key or the block cipher is
+// not supported, a non-nil error is returned.
+func NewXCBC(key []byte, newCipher func(key []byte) (cipher.Block, error)) (hash.Hash, error) {
+	if newCipher == nil {
+		return nil, errors.New("the cipher constructor must not be nil")
+	}
+	c, err := newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	bs := c.BlockSize()
+	if bs != 16 {
+		return nil, errors.New("cipher block size not supported")
+	}
+
+	// K1 = E(0x01^16), K2 = E(0x02^16) and K3 = E(0x03^16)
+	k := make([][]byte, 3)
+	for i := range k {
+		k[i] = make([]byte, bs)
+		for j := range k[i] {
+			k[i][j] = byte(i + 1)
+		}
+		c.Encrypt(k[i], k[i])
+	}
+	c1, err := newCipher(k[0])
+	if err != nil {
+		return nil, err
+	}
+
+	// XCBC is CMac with the cipher keyed with K1
+	// and the subkeys K2 and K3.
+	return &macFunc{
+		cipher: c1,
+		k0:     k[1],
+		k1:     k[2],
+		buf:    make([]byte, bs),
+	}, nil
+}
+
+// NewPRF returns a hash.Hash computing the AES-CMAC-PRF-128 pseudo-random
+// function specified in RFC 4615. In contrast to CMac the key may have any
+// length: keys, which are not 16 bytes long, are replaced by the CMac
+// checksum of the key computed with a zero key. The newCipher function must
+// return a cipher.Block with a block size of 128 bit (like aes.NewCipher)
+// for a 16 byte key.
+func NewPRF(key []byte, newCipher func(key []byte) (cipher.Block, error)) (hash.Hash, error) {
+	if newCipher == nil {
+		return nil, errors.New("the cipher constructor must not be nil")
+	}
+	if len(key) != 16 {
+		c, err := newCipher(make([]byte, 16))
+		if err != nil {
+			return nil, err
+		}
+		if c.BlockSize() != 16 {
+			return nil, errors.New("cipher block size not supported")
+		}
+		if key, err = Sum(key, c); err != nil {
+			return nil, err
+		}
+	}
+	c, err := newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if c.BlockSize() != 16 {
+		return nil, errors.New("cipher block size not supported")
+	}
+	return New(c)
+}
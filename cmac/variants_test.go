@@ -0,0 +1,228 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"hash"
+	"testing"
+)
+
+// Test vectors for AES-XCBC-MAC from RFC 3566 Section 4.6
+var xcbcVectors = []struct {
+	key, msg, hash string
+}{
+	{
+		key:  "000102030405060708090a0b0c0d0e0f",
+		msg:  "",
+		hash: "75f0251d528ac01c4573dfd584d79f29",
+	},
+	{
+		key:  "000102030405060708090a0b0c0d0e0f",
+		msg:  "000102",
+		hash: "5b376580ae2f19afe7219ceef172756f",
+	},
+	{
+		key:  "000102030405060708090a0b0c0d0e0f",
+		msg:  "000102030405060708090a0b0c0d0e0f",
+		hash: "d2a246fa349b68a79998a4394ff7a263",
+	},
+	{
+		key:  "000102030405060708090a0b0c0d0e0f",
+		msg:  "000102030405060708090a0b0c0d0e0f10111213",
+		hash: "47f51b4564966215b8985c63055ed308",
+	},
+	{
+		key:  "000102030405060708090a0b0c0d0e0f",
+		msg:  "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		hash: "f54f0ec8d2b9f3d36807734bd5283fd4",
+	},
+	{
+		key:  "000102030405060708090a0b0c0d0e0f",
+		msg:  "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f2021",
+		hash: "becbb3bccdb518a30677d5481fb6b4d8",
+	},
+	{
+		key:  "000102030405060708090a0b0c0d0e0f",
+		msg:  hex.EncodeToString(make([]byte, 1000)),
+		hash: "f0dafee895db30253761103b5d84528f",
+	},
+}
+
+// Test vectors for AES-CMAC-PRF-128 from RFC 4615 Section 4
+var prfVectors = []struct {
+	key, msg, hash string
+}{
+	{
+		key:  "000102030405060708090a0b0c0d0e0fedcb",
+		msg:  "000102030405060708090a0b0c0d0e0f10111213",
+		hash: "84a348a4a45d235babfffc0d2b4da09a",
+	},
+	{
+		key:  "000102030405060708090a0b0c0d0e0f",
+		msg:  "000102030405060708090a0b0c0d0e0f10111213",
+		hash: "980ae87b5f4c9c5214f5b6a8455e4c2d",
+	},
+	{
+		key:  "00010203040506070809",
+		msg:  "000102030405060708090a0b0c0d0e0f10111213",
+		hash: "290d9e112edb09ee141fcf64c0b72f3d",
+	},
+}
+
+func testVariantVectors(t *testing.T, name string, vectors []struct{ key, msg, hash string }, newHash func(key []byte) (hash.Hash, error)) {
+	for i, v := range vectors {
+		key, err := hex.DecodeString(v.key)
+		if err != nil {
+			t.Fatalf("%s: Test vector %d: Failed to decode hex key: %s", name, i, err)
+		}
+		msg, err := hex.DecodeString(v.msg)
+		if err != nil {
+			t.Fatalf("%s: Test vector %d: Failed to decode hex msg: %s", name, i, err)
+		}
+		tag, err := hex.DecodeString(v.hash)
+		if err != nil {
+			t.Fatalf("%s: Test vector %d: Failed to decode hex hash: %s", name, i, err)
+		}
+
+		h, err := newHash(key)
+		if err != nil {
+			t.Fatalf("%s: Test vector %d: Failed to create instance: %s", name, i, err)
+		}
+		h.Write(msg)
+		if sum := h.Sum(nil); !bytes.Equal(sum, tag) {
+			t.Fatalf("%s: Test vector %d : MAC does not match:\nFound:    %v\nExpected: %v", name, i, hex.EncodeToString(sum), hex.EncodeToString(tag))
+		}
+		if !VerifyTruncated(tag[:12], msg, h, 12) {
+			t.Fatalf("%s: Test vector %d: verification of the truncated MAC failed", name, i)
+		}
+	}
+}
+
+func TestXCBCVectors(t *testing.T) {
+	testVariantVectors(t, "XCBC", xcbcVectors, func(key []byte) (hash.Hash, error) {
+		return NewXCBC(key, aes.NewCipher)
+	})
+}
+
+func TestPRFVectors(t *testing.T) {
+	testVariantVectors(t, "CMAC-PRF", prfVectors, func(key []byte) (hash.Hash, error) {
+		return NewPRF(key, aes.NewCipher)
+	})
+}
+
+func TestOMAC2(t *testing.T) {
+	c, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Could not create AES instance: %s", err)
+	}
+	h, err := NewOMAC2(c)
+	if err != nil {
+		t.Fatalf("Failed to create OMAC2 instance: %s", err)
+	}
+
+	// L = E(0^n) and for L·x^-1: L >> 1 xor (10^119 || 1000011 >> 1) if the lsb of L is set
+	l := make([]byte, 16)
+	c.Encrypt(l, l)
+	lx, linv := make([]byte, 16), make([]byte, 16)
+	if shift(lx, l) == 1 {
+		lx[15] ^= 0x87
+	}
+	lsb := l[15] & 1
+	for i := 15; i >= 0; i-- {
+		linv[i] = l[i] >> 1
+		if i > 0 {
+			linv[i] |= l[i-1] << 7
+		}
+	}
+	if lsb == 1 {
+		linv[0] ^= 0x80
+		linv[15] ^= 0x43
+	}
+
+	msg := make([]byte, 48)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	for _, size := range []int{0, 1, 15, 16, 17, 32, 33, 48} {
+		// the CBC-MAC of the message - the last block is
+		// xored with L·x (complete) or L·x^-1 (padded)
+		n := (size + 15) / 16 * 16
+		if n == 0 {
+			n = 16
+		}
+		padded := make([]byte, n)
+		copy(padded, msg[:size])
+		if size == n {
+			for i := range lx {
+				padded[n-16+i] ^= lx[i]
+			}
+		} else {
+			padded[size] = 0x80
+			for i := range linv {
+				padded[n-16+i] ^= linv[i]
+			}
+		}
+		cipher.NewCBCEncrypter(c, make([]byte, 16)).CryptBlocks(padded, padded)
+		expected := padded[n-16:]
+
+		h.Reset()
+		h.Write(msg[:size])
+		if sum := h.Sum(nil); !bytes.Equal(sum, expected) {
+			t.Fatalf("Size %d: OMAC2 does not match:\nFound:    %x\nExpected: %x", size, sum, expected)
+		}
+	}
+
+	if _, err = NewOMAC2(dummyCipher(32)); err == nil {
+		t.Fatal("OMAC2 allowed invalid block size: 32")
+	}
+}
+
+func TestVerifyTruncated(t *testing.T) {
+	c, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Could not create AES instance: %s", err)
+	}
+	h, err := New(c)
+	if err != nil {
+		t.Fatalf("Failed to create CMac instance: %s", err)
+	}
+	msg := []byte("message")
+	tag, err := Sum(msg, c)
+	if err != nil {
+		t.Fatalf("Failed to compute CMac tag: %s", err)
+	}
+
+	for tagSize := MinTagSize; tagSize <= len(tag); tagSize++ {
+		h.Write([]byte("garbage")) // VerifyTruncated must reset the hash
+		if !VerifyTruncated(tag[:tagSize], msg, h, tagSize) {
+			t.Fatalf("Tag size %d: verification failed", tagSize)
+		}
+	}
+	if VerifyTruncated(tag[:MinTagSize-1], msg, h, MinTagSize-1) {
+		t.Fatalf("VerifyTruncated accepted a tag of %d bytes", MinTagSize-1)
+	}
+	if VerifyTruncated(tag[:12], msg, h, 16) {
+		t.Fatal("VerifyTruncated accepted a tag shorter than the tag size")
+	}
+	tag[0] ^= 1
+	if VerifyTruncated(tag[:12], msg, h, 12) {
+		t.Fatal("VerifyTruncated accepted a modified tag")
+	}
+}
+
+func TestNewXCBC(t *testing.T) {
+	if _, err := NewXCBC(make([]byte, 15), aes.NewCipher); err == nil {
+		t.Fatal("NewXCBC accepted an invalid key")
+	}
+	if _, err := NewXCBC(make([]byte, 16), nil); err == nil {
+		t.Fatal("NewXCBC accepted nil cipher constructor")
+	}
+	if _, err := NewPRF(make([]byte, 16), nil); err == nil {
+		t.Fatal("NewPRF accepted nil cipher constructor")
+	}
+}
@@ -0,0 +1,134 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// Package hkdf implements the HMAC-based extract-and-expand key derivation
+// function (HKDF) specified in RFC 5869. The HKDF can be used with any hash
+// function - the hash function is passed as constructor function - e.g.
+// BLAKE2b or Skein:
+//
+//	func() hash.Hash { h, _ := blake2b.New(64, nil); return h }
+//	func() hash.Hash { return skein.New(64, nil) }
+//
+// Furthermore this package provides a native BLAKE2b profile of the HKDF,
+// which uses keyed BLAKE2b instead of HMAC and the BLAKE2b personalization
+// for domain separation.
+package hkdf
+
+import (
+	"crypto/hmac"
+	"errors"
+	"hash"
+	"io"
+
+	"github.com/enceve/crypto/blake2/blake2b"
+)
+
+var errLimit = errors.New("the max. output length of the HKDF is reached")
+
+// Extract computes the pseudo-random key (PRK) from the secret and the
+// (optional) salt using HMAC with the given hash function:
+// PRK = HMAC-Hash(salt, secret). If the salt is empty, a string of
+// Size() zero bytes is used as salt.
+func Extract(hash func() hash.Hash, secret, salt []byte) []byte {
+	if len(salt) == 0 {
+		salt = make([]byte, hash().Size())
+	}
+	mac := hmac.New(hash, salt)
+	mac.Write(secret)
+	return mac.Sum(nil)
+}
+
+// Expand returns an io.Reader reading the output keying material derived
+// from the pseudo-random key (PRK) and the (optional) info using HMAC with
+// the given hash function. The PRK should be the result of Extract or a
+// uniformly random key of at least Size() bytes. The reader returns an error
+// after 255*Size() bytes.
+func Expand(hash func() hash.Hash, prk, info []byte) io.Reader {
+	return newReader(hmac.New(hash, prk), info)
+}
+
+// New returns an io.Reader reading the output keying material derived from
+// the secret, the (optional) salt and the (optional) info using HMAC with the
+// given hash function. New is equal to Expand(hash, Extract(hash, secret, salt), info).
+func New(hash func() hash.Hash, secret, salt, info []byte) io.Reader {
+	return Expand(hash, Extract(hash, secret, salt), info)
+}
+
+// ExtractBLAKE2b computes the pseudo-random key (PRK) of the BLAKE2b profile
+// from the secret and the (optional) salt: PRK = BLAKE2b-512(secret) keyed with
+// the salt and personalized with personal. The salt must not be longer than 64
+// bytes and personal must not be longer than 16 bytes - otherwise a non-nil
+// error is returned.
+func ExtractBLAKE2b(secret, salt, personal []byte) ([]byte, error) {
+	return blake2b.Sum(secret, blake2b.Size, &blake2b.Config{Key: salt, Personal: personal})
+}
+
+// ExpandBLAKE2b returns an io.Reader reading the output keying material
+// derived from the pseudo-random key (PRK) and the (optional) info using
+// BLAKE2b-512 keyed with the PRK and personalized with personal. The PRK
+// should be the result of ExtractBLAKE2b. The PRK must not be longer than
+// 64 bytes and personal must not be longer than 16 bytes - otherwise a
+// non-nil error is returned. The reader returns an error after 255*64 bytes.
+func ExpandBLAKE2b(prk, info, personal []byte) (io.Reader, error) {
+	h, err := blake2b.New(blake2b.Size, &blake2b.Config{Key: prk, Personal: personal})
+	if err != nil {
+		return nil, err
+	}
+	return newReader(h, info), nil
+}
+
+// NewBLAKE2b returns an io.Reader reading the output keying material derived
+// from the secret, the (optional) salt and the (optional) info using the BLAKE2b
+// profile. Different personalizations (e.g. the name of the application or
+// protocol) derive independent keys from the same secret. The salt must not be
+// longer than 64 bytes and personal must not be longer than 16 bytes - otherwise
+// a non-nil error is returned.
+func NewBLAKE2b(secret, salt, info, personal []byte) (io.Reader, error) {
+	prk, err := ExtractBLAKE2b(secret, salt, personal)
+	if err != nil {
+		return nil, err
+	}
+	return ExpandBLAKE2b(prk, info, personal)
+}
+
+// newReader returns the HKDF expand step using the
+// PRF - a hash.Hash keyed with the PRK.
+func newReader(prf hash.Hash, info []byte) io.Reader {
+	return &reader{
+		prf:  prf,
+		info: info,
+	}
+}
+
+// The HKDF expand step:
+// T(i) = PRF(T(i-1) || info || i) with T(0) = empty string
+type reader struct {
+	prf  hash.Hash
+	info []byte
+	ctr  byte   // the number of computed blocks
+	t    []byte // T(ctr)
+	buf  []byte // the unread part of T(ctr)
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n := len(p)
+	if remaining := len(r.buf) + (255-int(r.ctr))*r.prf.Size(); n > remaining {
+		return 0, errLimit
+	}
+
+	for len(p) > 0 {
+		if len(r.buf) == 0 {
+			r.ctr++
+			r.prf.Reset()
+			r.prf.Write(r.t)
+			r.prf.Write(r.info)
+			r.prf.Write([]byte{r.ctr})
+			r.t = r.prf.Sum(r.t[:0])
+			r.buf = r.t
+		}
+		k := copy(p, r.buf)
+		p = p[k:]
+		r.buf = r.buf[k:]
+	}
+	return n, nil
+}
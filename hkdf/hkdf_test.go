@@ -0,0 +1,216 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package hkdf
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"testing"
+
+	"github.com/enceve/crypto/blake2/blake2b"
+	"github.com/enceve/crypto/skein"
+)
+
+func fromHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// sequence returns the bytes from, from+1, ..., to
+func sequence(from, to int) string {
+	b := make([]byte, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		b = append(b, byte(i))
+	}
+	return hex.EncodeToString(b)
+}
+
+// Test vectors for HKDF-SHA256 from RFC 5869 Appendix A
+var vectors = []struct {
+	secret, salt, info string
+	prk, okm           string
+}{
+	{
+		secret: "0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b",
+		salt:   "000102030405060708090a0b0c",
+		info:   "f0f1f2f3f4f5f6f7f8f9",
+		prk:    "077709362c2e32df0ddc3f0dc47bba6390b6c73bb50f9c3122ec844ad7c2b3e5",
+		okm:    "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865",
+	},
+	{
+		secret: sequence(0x00, 0x4f),
+		salt:   sequence(0x60, 0xaf),
+		info:   sequence(0xb0, 0xff),
+		prk:    "06a6b88c5853361a06104c9ceb35b45cef760014904671014a193f40c15fc244",
+		okm: "b11e398dc80327a1c8e7f78c596a49344f012eda2d4efad8a050cc4c19afa97c" +
+			"59045a99cac7827271cb41c65e590e09da3275600c2f09b8367793a9aca3db71" +
+			"cc30c58179ec3e87c14c01d5c1f3434f1d87",
+	},
+	{
+		secret: "0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b",
+		salt:   "",
+		info:   "",
+		prk:    "19ef24a32c717b167f33a91d6f648bdf96596776afdb6377ac434c1c293ccb04",
+		okm:    "8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8",
+	},
+}
+
+func TestVectors(t *testing.T) {
+	for i, v := range vectors {
+		secret, salt, info := fromHex(v.secret), fromHex(v.salt), fromHex(v.info)
+		prk, expected := fromHex(v.prk), fromHex(v.okm)
+
+		if p := Extract(sha256.New, secret, salt); !bytes.Equal(p, prk) {
+			t.Fatalf("Test vector %d: PRK does not match:\nFound:    %x\nExpected: %x", i, p, prk)
+		}
+		okm := make([]byte, len(expected))
+		if _, err := io.ReadFull(New(sha256.New, secret, salt, info), okm); err != nil {
+			t.Fatalf("Test vector %d: Failed to read from HKDF: %s", i, err)
+		}
+		if !bytes.Equal(okm, expected) {
+			t.Fatalf("Test vector %d: OKM does not match:\nFound:    %x\nExpected: %x", i, okm, expected)
+		}
+
+		// read the OKM in small pieces
+		r := Expand(sha256.New, prk, info)
+		for j := 0; j < len(okm); j += 5 {
+			k := j + 5
+			if k > len(okm) {
+				k = len(okm)
+			}
+			if _, err := io.ReadFull(r, okm[j:k]); err != nil {
+				t.Fatalf("Test vector %d: Failed to read from HKDF: %s", i, err)
+			}
+		}
+		if !bytes.Equal(okm, expected) {
+			t.Fatalf("Test vector %d: OKM (partial reads) does not match:\nFound:    %x\nExpected: %x", i, okm, expected)
+		}
+	}
+}
+
+var hashFunctions = map[string]func() hash.Hash{
+	"BLAKE2b": func() hash.Hash {
+		h, err := blake2b.New(64, nil)
+		if err != nil {
+			panic(err)
+		}
+		return h
+	},
+	"Skein512": func() hash.Hash { return skein.New(64, nil) },
+}
+
+func TestHashFunctions(t *testing.T) {
+	secret, salt, info := []byte("secret"), []byte("salt"), []byte("info")
+	for name, h := range hashFunctions {
+		prk := Extract(h, secret, salt)
+		mac := hmac.New(h, salt)
+		mac.Write(secret)
+		if expected := mac.Sum(nil); !bytes.Equal(prk, expected) {
+			t.Fatalf("%s: PRK does not match:\nFound:    %x\nExpected: %x", name, prk, expected)
+		}
+
+		// T(i) = HMAC(PRK, T(i-1) || info || i)
+		mac = hmac.New(h, prk)
+		var expected, t0 []byte
+		for i := 1; i <= 3; i++ {
+			mac.Reset()
+			mac.Write(t0)
+			mac.Write(info)
+			mac.Write([]byte{byte(i)})
+			t0 = mac.Sum(nil)
+			expected = append(expected, t0...)
+		}
+		expected = expected[:2*mac.Size()+1]
+
+		okm := make([]byte, len(expected))
+		if _, err := io.ReadFull(New(h, secret, salt, info), okm); err != nil {
+			t.Fatalf("%s: Failed to read from HKDF: %s", name, err)
+		}
+		if !bytes.Equal(okm, expected) {
+			t.Fatalf("%s: OKM does not match:\nFound:    %x\nExpected: %x", name, okm, expected)
+		}
+	}
+}
+
+func TestBLAKE2b(t *testing.T) {
+	secret, salt, info := []byte("secret"), []byte("salt"), []byte("info")
+	personal := []byte("enceve/crypto")
+
+	prk, err := ExtractBLAKE2b(secret, salt, personal)
+	if err != nil {
+		t.Fatalf("ExtractBLAKE2b failed: %s", err)
+	}
+	expPRK, err := blake2b.Sum(secret, 64, &blake2b.Config{Key: salt, Personal: personal})
+	if err != nil {
+		t.Fatalf("Failed to compute BLAKE2b checksum: %s", err)
+	}
+	if !bytes.Equal(prk, expPRK) {
+		t.Fatalf("PRK does not match:\nFound:    %x\nExpected: %x", prk, expPRK)
+	}
+
+	t1, _ := blake2b.Sum(append(info, 1), 64, &blake2b.Config{Key: prk, Personal: personal})
+	t2, _ := blake2b.Sum(append(append(t1, info...), 2), 64, &blake2b.Config{Key: prk, Personal: personal})
+	expected := append(t1, t2[:16]...)
+
+	r, err := NewBLAKE2b(secret, salt, info, personal)
+	if err != nil {
+		t.Fatalf("NewBLAKE2b failed: %s", err)
+	}
+	okm := make([]byte, len(expected))
+	if _, err = io.ReadFull(r, okm); err != nil {
+		t.Fatalf("Failed to read from HKDF: %s", err)
+	}
+	if !bytes.Equal(okm, expected) {
+		t.Fatalf("OKM does not match:\nFound:    %x\nExpected: %x", okm, expected)
+	}
+
+	// a different personalization must derive a different key
+	r, err = NewBLAKE2b(secret, salt, info, []byte("other app"))
+	if err != nil {
+		t.Fatalf("NewBLAKE2b failed: %s", err)
+	}
+	other := make([]byte, len(expected))
+	if _, err = io.ReadFull(r, other); err != nil {
+		t.Fatalf("Failed to read from HKDF: %s", err)
+	}
+	if bytes.Equal(okm, other) {
+		t.Fatal("Different personalizations derived the same key")
+	}
+
+	if _, err = NewBLAKE2b(secret, make([]byte, 65), info, personal); err == nil {
+		t.Fatal("NewBLAKE2b accepted a salt of 65 bytes")
+	}
+	if _, err = NewBLAKE2b(secret, salt, info, make([]byte, 17)); err == nil {
+		t.Fatal("NewBLAKE2b accepted a personalization of 17 bytes")
+	}
+	if _, err = ExpandBLAKE2b(make([]byte, 65), info, personal); err == nil {
+		t.Fatal("ExpandBLAKE2b accepted a PRK of 65 bytes")
+	}
+}
+
+func TestLimit(t *testing.T) {
+	r := New(sha256.New, []byte("secret"), nil, nil)
+	buf := make([]byte, 255*sha256.Size)
+	if _, err := io.ReadFull(r, buf[:100]); err != nil {
+		t.Fatalf("Failed to read from HKDF: %s", err)
+	}
+	if _, err := io.ReadFull(r, buf[100:]); err != nil {
+		t.Fatalf("Failed to read 255*Size() bytes from HKDF: %s", err)
+	}
+	if _, err := r.Read(buf[:1]); err == nil {
+		t.Fatal("HKDF returned more than 255*Size() bytes")
+	}
+
+	r = New(sha256.New, []byte("secret"), nil, nil)
+	if n, err := r.Read(make([]byte, 255*sha256.Size+1)); err == nil || n != 0 {
+		t.Fatalf("HKDF returned %d > 255*Size() bytes", n)
+	}
+}
@@ -0,0 +1,178 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// Package kbkdf implements the key-based key derivation functions
+// specified in NIST SP 800-108 in counter, feedback and pipeline mode.
+// The KBKDF derives keying material from a key-derivation key using a
+// PRF - e.g. CMac, HMAC or a keyed BLAKE2b or Skein hash function.
+// The PRF is a hash.Hash, which is already keyed with the key-derivation
+// key - for instance:
+//
+//	cmac.New(block)
+//	hmac.New(sha256.New, key)
+//	blake2b.New(32, &blake2b.Config{Key: key})
+//	skein.New(64, &skein.Config{Key: key})
+package kbkdf
+
+import (
+	"errors"
+	"hash"
+)
+
+// The positions of the counter in the input of the PRF.
+const (
+	// BeforeFixed places the counter after the iteration variable (in
+	// feedback and pipeline mode) and before the fixed input data.
+	// This is the default of NIST SP 800-108.
+	BeforeFixed = iota
+	// AfterFixed places the counter after the fixed input data.
+	AfterFixed
+	// MiddleFixed places the counter within the fixed input data
+	// after Offset bytes.
+	MiddleFixed
+	// BeforeIteration places the counter before the iteration variable
+	// (in feedback and pipeline mode). In counter mode BeforeIteration
+	// is equal to BeforeFixed.
+	BeforeIteration
+)
+
+// Counter specifies the encoding and the position of the counter.
+// The zero value is not valid in counter mode - the Size must be set.
+type Counter struct {
+	// Size is the size of the (big endian) counter in bytes. It must
+	// be between 1 and 4 - or 0 for no counter in feedback and pipeline
+	// mode.
+	Size int
+	// Position is the position of the counter: BeforeFixed,
+	// AfterFixed, MiddleFixed or BeforeIteration.
+	Position int
+	// Offset is the number of fixed input bytes before
+	// the counter, if the Position is MiddleFixed.
+	Offset int
+}
+
+var errTooLong = errors.New("the output length exceeds the max. value of the counter")
+
+// FixedInput returns the fixed input data specified in NIST SP 800-108:
+// label || 0x00 || context || [L]_32 - where L is lengthBits, the length
+// of the derived keying material in bits (not bytes), encoded as 32 bit
+// big endian integer.
+func FixedInput(label, context []byte, lengthBits int) []byte {
+	fixed := make([]byte, 0, len(label)+1+len(context)+4)
+	fixed = append(fixed, label...)
+	fixed = append(fixed, 0)
+	fixed = append(fixed, context...)
+	return append(fixed, byte(lengthBits>>24), byte(lengthBits>>16), byte(lengthBits>>8), byte(lengthBits))
+}
+
+// CounterMode derives len(out) bytes in counter mode using the PRF and the
+// fixed input data: K(i) = PRF([i] || fixedInput) (for BeforeFixed).
+// A non-nil error is returned, if the counter is not valid or the counter
+// cannot encode the number of required PRF calls.
+func CounterMode(out []byte, prf hash.Hash, fixedInput []byte, ctr Counter) error {
+	if ctr.Size == 0 {
+		return errors.New("the counter size must not be 0 in counter mode")
+	}
+	if err := check(out, prf, fixedInput, ctr); err != nil {
+		return err
+	}
+	for i := uint32(1); len(out) > 0; i++ {
+		prf.Reset()
+		write(prf, nil, fixedInput, ctr, i)
+		out = out[copy(out, prf.Sum(nil)):]
+	}
+	return nil
+}
+
+// FeedbackMode derives len(out) bytes in feedback mode using the PRF, the
+// iv and the fixed input data: K(i) = PRF(K(i-1) || [i] || fixedInput) (for
+// BeforeFixed), where K(0) is the iv. The iv may be empty. A non-nil error
+// is returned, if the counter is not valid or the counter cannot encode the
+// number of required PRF calls.
+func FeedbackMode(out []byte, prf hash.Hash, iv, fixedInput []byte, ctr Counter) error {
+	if err := check(out, prf, fixedInput, ctr); err != nil {
+		return err
+	}
+	k := iv
+	for i := uint32(1); len(out) > 0; i++ {
+		prf.Reset()
+		write(prf, k, fixedInput, ctr, i)
+		k = prf.Sum(nil)
+		out = out[copy(out, k):]
+	}
+	return nil
+}
+
+// PipelineMode derives len(out) bytes in double-pipeline mode using the PRF
+// and the fixed input data: A(i) = PRF(A(i-1)) with A(0) = fixedInput and
+// K(i) = PRF(A(i) || [i] || fixedInput) (for BeforeFixed). A non-nil error
+// is returned, if the counter is not valid or the counter cannot encode the
+// number of required PRF calls.
+func PipelineMode(out []byte, prf hash.Hash, fixedInput []byte, ctr Counter) error {
+	if err := check(out, prf, fixedInput, ctr); err != nil {
+		return err
+	}
+	a := fixedInput
+	for i := uint32(1); len(out) > 0; i++ {
+		prf.Reset()
+		prf.Write(a)
+		a = prf.Sum(nil)
+
+		prf.Reset()
+		write(prf, a, fixedInput, ctr, i)
+		out = out[copy(out, prf.Sum(nil)):]
+	}
+	return nil
+}
+
+// check returns a non-nil error if the counter is not valid or the
+// counter is too small to encode the number of PRF calls.
+func check(out []byte, prf hash.Hash, fixedInput []byte, ctr Counter) error {
+	if ctr.Size < 0 || ctr.Size > 4 {
+		return errors.New("the counter size must be between 0 and 4")
+	}
+	switch ctr.Position {
+	default:
+		return errors.New("invalid counter position")
+	case BeforeFixed, AfterFixed, BeforeIteration:
+	case MiddleFixed:
+		if ctr.Offset < 0 || ctr.Offset > len(fixedInput) {
+			return errors.New("the counter offset must be within the fixed input data")
+		}
+	}
+
+	n := (uint64(len(out)) + uint64(prf.Size()) - 1) / uint64(prf.Size())
+	if n > 1<<32-1 || (ctr.Size > 0 && n > 1<<uint(8*ctr.Size)-1) {
+		return errTooLong
+	}
+	return nil
+}
+
+// write writes the PRF input - the iteration variable x, the
+// fixed input data and the counter i - to the PRF.
+func write(prf hash.Hash, x, fixedInput []byte, ctr Counter, i uint32) {
+	var c []byte
+	if ctr.Size > 0 {
+		c = []byte{byte(i >> 24), byte(i >> 16), byte(i >> 8), byte(i)}[4-ctr.Size:]
+	}
+
+	switch ctr.Position {
+	case BeforeIteration:
+		prf.Write(c)
+		prf.Write(x)
+		prf.Write(fixedInput)
+	case BeforeFixed:
+		prf.Write(x)
+		prf.Write(c)
+		prf.Write(fixedInput)
+	case AfterFixed:
+		prf.Write(x)
+		prf.Write(fixedInput)
+		prf.Write(c)
+	case MiddleFixed:
+		prf.Write(x)
+		prf.Write(fixedInput[:ctr.Offset])
+		prf.Write(c)
+		prf.Write(fixedInput[ctr.Offset:])
+	}
+}
@@ -0,0 +1,317 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package kbkdf
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"testing"
+
+	"github.com/enceve/crypto/blake2/blake2b"
+	"github.com/enceve/crypto/cmac"
+	"github.com/enceve/crypto/skein"
+)
+
+func fromHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// newPRF returns the PRF (CMac-AES or HMAC-SHA256) keyed with the key.
+func newPRF(t *testing.T, name string, key []byte) hash.Hash {
+	if name == "HMAC-SHA256" {
+		return hmac.New(sha256.New, key)
+	}
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("Failed to create AES instance: %s", err)
+	}
+	prf, err := cmac.New(c)
+	if err != nil {
+		t.Fatalf("Failed to create CMac instance: %s", err)
+	}
+	return prf
+}
+
+// Test vectors for KBKDF in counter mode from the NIST CAVP (KDFCTR_gen.txt)
+// https://csrc.nist.gov/projects/cryptographic-algorithm-validation-program/key-derivation
+var counterVectors = []struct {
+	prf, key, fixedInput string
+	ctr                  Counter
+	expected             string
+}{
+	{
+		prf:        "CMac-AES",
+		key:        "dff1e50ac0b69dc40f1051d46c2b069c",
+		fixedInput: "c16e6e02c5a3dcc8d78b9ac1306877761310455b4e41469951d9e6c2245a064b33fd8c3b01203a7824485bf0a64060c4648b707d2607935699316ea5",
+		ctr:        Counter{Size: 1, Position: BeforeFixed},
+		expected:   "8be8f0869b3c0ba97b71863d1b9f7813",
+	},
+	{
+		prf:        "CMac-AES",
+		key:        "30ec5f6fa1def33cff008178c4454211",
+		fixedInput: "c95e7b1d4f2570259abfc05bb00730f0284c3bb9a61d07259848a1cb57c81d8a6c3382c500bf801dfc8f70726b082cf4c3fa34386c1e7bf0e5471438",
+		ctr:        Counter{Size: 2, Position: BeforeFixed},
+		expected:   "00018fff9574994f5c4457f461c7a67e",
+	},
+	{
+		prf:        "CMac-AES",
+		key:        "ca1cf43e5ccd512cc719a2f9de41734c",
+		fixedInput: "e3884ac963196f02ddd09fc04c20c88b60faa775b5ef6feb1faf8c5e098b5210e2b4e45d62cc0bf907fd68022ee7b15631b5c8daf903d99642c5b831",
+		ctr:        Counter{Size: 3, Position: BeforeFixed},
+		expected:   "1cb2b12326cc5ec1eba248167f0efd58",
+	},
+	{
+		prf:        "CMac-AES",
+		key:        "c10b152e8c97b77e18704e0f0bd38305",
+		fixedInput: "98cd4cbbbebe15d17dc86e6dbad800a2dcbd64f7c7ad0e78e9cf94ffdba89d03e97eadf6c4f7b806caf52aa38f09d0eb71d71f497bcc6906b48d36c4",
+		ctr:        Counter{Size: 4, Position: BeforeFixed},
+		expected:   "26faf61908ad9ee881b8305c221db53f",
+	},
+	{
+		prf:        "CMac-AES",
+		key:        "e61a51e1633e7d0de704dcebbd8f962f",
+		fixedInput: "5eef88f8cb188e63e08e23c957ee424a3345da88400c567548b57693931a847501f8e1bce1c37a09ef8c6e2ad553dd0f603b52cc6d4e4cbb76eb6c8f",
+		ctr:        Counter{Size: 1, Position: AfterFixed},
+		expected:   "63a5647d0fe69d21fc420b1a8ce34cc1",
+	},
+	{
+		prf:        "HMAC-SHA256",
+		key:        "dd1d91b7d90b2bd3138533ce92b272fbf8a369316aefe242e659cc0ae238afe0",
+		fixedInput: "01322b96b30acd197979444e468e1c5c6859bf1b1cf951b7e725303e237e46b864a145fab25e517b08f8683d0315bb2911d80a0e8aba17f3b413faac",
+		ctr:        Counter{Size: 4, Position: BeforeFixed},
+		expected:   "10621342bfb0fd40046c0e29f2cfdbf0",
+	},
+}
+
+func TestCounterVectors(t *testing.T) {
+	for i, v := range counterVectors {
+		prf := newPRF(t, v.prf, fromHex(v.key))
+		expected := fromHex(v.expected)
+		out := make([]byte, len(expected))
+		if err := CounterMode(out, prf, fromHex(v.fixedInput), v.ctr); err != nil {
+			t.Fatalf("Test vector %d: CounterMode failed: %s", i, err)
+		}
+		if !bytes.Equal(out, expected) {
+			t.Fatalf("Test vector %d: Derived key does not match:\nFound:    %x\nExpected: %x", i, out, expected)
+		}
+	}
+}
+
+// Multi-block test vectors for the counter and feedback mode generated
+// with the KBKDF of OpenSSL 3.0 (openssl kdf ... KBKDF - a 32 bit counter
+// before the fixed input) and pyca/cryptography 45 (KBKDFCMAC, KBKDFHMAC).
+// The fixed input of the OpenSSL vectors is FixedInput("label", "context", 320).
+var multiBlockVectors = []struct {
+	prf, key, iv, fixedInput string
+	feedback                 bool
+	ctr                      Counter
+	expected                 string
+}{
+	{
+		prf:        "CMac-AES",
+		key:        "000102030405060708090a0b0c0d0e0f",
+		fixedInput: "6c6162656c00636f6e7465787400000140",
+		ctr:        Counter{Size: 4, Position: BeforeFixed},
+		expected:   "3fc9b552ad320ef843abf45fe0209ce553353235b587ffa35dfd387b410da1c1a60066f8b9f805ce",
+	},
+	{
+		prf:        "CMac-AES",
+		key:        "000102030405060708090a0b0c0d0e0f",
+		iv:         "a0a1a2a3a4a5a6a7a8a9aaabacadaeaf",
+		fixedInput: "6c6162656c00636f6e7465787400000140",
+		feedback:   true,
+		ctr:        Counter{Size: 4, Position: BeforeFixed},
+		expected:   "8381b67f594ed99eedb622189d53d72d5db063e9209711c0dbdbe48cf058ed6e1f6f1a70399f019a",
+	},
+	{
+		prf:        "CMac-AES",
+		key:        "000102030405060708090a0b0c0d0e0f",
+		fixedInput: "0104070a0d101316191c1f2225282b2e3134373a",
+		ctr:        Counter{Size: 2, Position: MiddleFixed, Offset: 5},
+		expected: "3ba3e027cf70ec6681ecf3b3731fdc6eddba716f5a34d55b533e8d87b9ef6fe7" +
+			"444eab5f6853549beaabc25453fb6d18",
+	},
+	{
+		prf:        "HMAC-SHA256",
+		key:        "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		fixedInput: "0104070a0d101316191c1f2225282b2e3134373a",
+		ctr:        Counter{Size: 3, Position: AfterFixed},
+		expected: "fb868cdea690b85e9715cd180eef3d8d239326ebcebcb4303c1ca6cfd815c000" +
+			"cf8fc53f5c21c835f0162310d0b467049cb49b20200b3b91a6b930f83a5f83e8" +
+			"39da0558c9c9f2956d50e5412a5626fd",
+	},
+}
+
+func TestMultiBlockVectors(t *testing.T) {
+	for i, v := range multiBlockVectors {
+		prf := newPRF(t, v.prf, fromHex(v.key))
+		expected := fromHex(v.expected)
+		out := make([]byte, len(expected))
+
+		var err error
+		if v.feedback {
+			err = FeedbackMode(out, prf, fromHex(v.iv), fromHex(v.fixedInput), v.ctr)
+		} else {
+			err = CounterMode(out, prf, fromHex(v.fixedInput), v.ctr)
+		}
+		if err != nil {
+			t.Fatalf("Test vector %d: Key derivation failed: %s", i, err)
+		}
+		if !bytes.Equal(out, expected) {
+			t.Fatalf("Test vector %d: Derived key does not match:\nFound:    %x\nExpected: %x", i, out, expected)
+		}
+	}
+}
+
+// prfs returns keyed PRFs of this library and the standard library.
+func prfs(t *testing.T, key []byte) map[string]hash.Hash {
+	c, err := aes.NewCipher(key[:16])
+	if err != nil {
+		t.Fatalf("Failed to create AES instance: %s", err)
+	}
+	cmacPRF, err := cmac.New(c)
+	if err != nil {
+		t.Fatalf("Failed to create CMac instance: %s", err)
+	}
+	blake2bPRF, err := blake2b.New(32, &blake2b.Config{Key: key})
+	if err != nil {
+		t.Fatalf("Failed to create BLAKE2b instance: %s", err)
+	}
+	return map[string]hash.Hash{
+		"CMac-AES":    cmacPRF,
+		"HMAC-SHA256": hmac.New(sha256.New, key),
+		"BLAKE2b":     blake2bPRF,
+		"Skein512":    skein.New(64, &skein.Config{Key: key}),
+	}
+}
+
+// prf computes the PRF of the concatenation of the data.
+func prf(h hash.Hash, data ...[]byte) []byte {
+	h.Reset()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+func TestModes(t *testing.T) {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	fixed := FixedInput([]byte("label"), []byte("context"), 100*8)
+
+	for name, h := range prfs(t, key) {
+		n := 100
+		out, expected := make([]byte, n), make([]byte, 0, n+h.Size())
+
+		// counter mode with a 16 bit counter after the fixed input
+		for i := 1; len(expected) < n; i++ {
+			expected = append(expected, prf(h, fixed, []byte{0, byte(i)})...)
+		}
+		if err := CounterMode(out, h, fixed, Counter{Size: 2, Position: AfterFixed}); err != nil {
+			t.Fatalf("%s: CounterMode failed: %s", name, err)
+		}
+		if !bytes.Equal(out, expected[:n]) {
+			t.Fatalf("%s: CounterMode does not match:\nFound:    %x\nExpected: %x", name, out, expected[:n])
+		}
+
+		// counter mode with a 8 bit counter after 3 bytes of the fixed input
+		expected = expected[:0]
+		for i := 1; len(expected) < n; i++ {
+			expected = append(expected, prf(h, fixed[:3], []byte{byte(i)}, fixed[3:])...)
+		}
+		if err := CounterMode(out, h, fixed, Counter{Size: 1, Position: MiddleFixed, Offset: 3}); err != nil {
+			t.Fatalf("%s: CounterMode failed: %s", name, err)
+		}
+		if !bytes.Equal(out, expected[:n]) {
+			t.Fatalf("%s: CounterMode (middle) does not match:\nFound:    %x\nExpected: %x", name, out, expected[:n])
+		}
+
+		// feedback mode with a 32 bit counter: K(i) = PRF(K(i-1) || [i] || fixed)
+		iv := []byte("initialization vector")
+		expected = expected[:0]
+		for i, k := 1, iv; len(expected) < n; i++ {
+			k = prf(h, k, []byte{0, 0, 0, byte(i)}, fixed)
+			expected = append(expected, k...)
+		}
+		if err := FeedbackMode(out, h, iv, fixed, Counter{Size: 4}); err != nil {
+			t.Fatalf("%s: FeedbackMode failed: %s", name, err)
+		}
+		if !bytes.Equal(out, expected[:n]) {
+			t.Fatalf("%s: FeedbackMode does not match:\nFound:    %x\nExpected: %x", name, out, expected[:n])
+		}
+
+		// feedback mode without counter: K(i) = PRF(K(i-1) || fixed)
+		expected = expected[:0]
+		for k := iv; len(expected) < n; {
+			k = prf(h, k, fixed)
+			expected = append(expected, k...)
+		}
+		if err := FeedbackMode(out, h, iv, fixed, Counter{}); err != nil {
+			t.Fatalf("%s: FeedbackMode failed: %s", name, err)
+		}
+		if !bytes.Equal(out, expected[:n]) {
+			t.Fatalf("%s: FeedbackMode (no counter) does not match:\nFound:    %x\nExpected: %x", name, out, expected[:n])
+		}
+
+		// pipeline mode with a 24 bit counter before the iteration variable
+		expected = expected[:0]
+		for i, a := 1, fixed; len(expected) < n; i++ {
+			a = prf(h, a)
+			expected = append(expected, prf(h, []byte{0, 0, byte(i)}, a, fixed)...)
+		}
+		if err := PipelineMode(out, h, fixed, Counter{Size: 3, Position: BeforeIteration}); err != nil {
+			t.Fatalf("%s: PipelineMode failed: %s", name, err)
+		}
+		if !bytes.Equal(out, expected[:n]) {
+			t.Fatalf("%s: PipelineMode does not match:\nFound:    %x\nExpected: %x", name, out, expected[:n])
+		}
+	}
+}
+
+func TestFixedInput(t *testing.T) {
+	fixed := FixedInput([]byte("label"), []byte("ctx"), 256)
+	expected := append([]byte("label\x00ctx"), 0, 0, 1, 0)
+	if !bytes.Equal(fixed, expected) {
+		t.Fatalf("FixedInput does not match:\nFound:    %x\nExpected: %x", fixed, expected)
+	}
+}
+
+func TestInvalidCounter(t *testing.T) {
+	h := hmac.New(sha256.New, make([]byte, 32))
+	fixed := FixedInput(nil, nil, 0)
+
+	if err := CounterMode(make([]byte, 32), h, fixed, Counter{}); err == nil {
+		t.Fatal("CounterMode accepted a counter of size 0")
+	}
+	if err := CounterMode(make([]byte, 32), h, fixed, Counter{Size: 5}); err == nil {
+		t.Fatal("CounterMode accepted a counter of size 5")
+	}
+	if err := CounterMode(make([]byte, 32), h, fixed, Counter{Size: 1, Position: 42}); err == nil {
+		t.Fatal("CounterMode accepted an invalid counter position")
+	}
+	if err := CounterMode(make([]byte, 32), h, fixed, Counter{Size: 1, Position: MiddleFixed, Offset: len(fixed) + 1}); err == nil {
+		t.Fatal("CounterMode accepted an invalid counter offset")
+	}
+
+	// a 8 bit counter can encode 255 PRF calls
+	if err := CounterMode(make([]byte, 255*h.Size()), h, fixed, Counter{Size: 1}); err != nil {
+		t.Fatalf("CounterMode rejected 255 PRF calls: %s", err)
+	}
+	if err := CounterMode(make([]byte, 255*h.Size()+1), h, fixed, Counter{Size: 1}); err == nil {
+		t.Fatal("CounterMode accepted 256 PRF calls with a 8 bit counter")
+	}
+	if err := PipelineMode(make([]byte, 255*h.Size()+1), h, fixed, Counter{Size: 1}); err == nil {
+		t.Fatal("PipelineMode accepted 256 PRF calls with a 8 bit counter")
+	}
+}
@@ -73,7 +73,7 @@ func (p *Hash) Write(msg []byte) (int, error) {
 
 	if p.off > 0 {
 		dif := TagSize - p.off
-		if n > dif {
+		if n >= dif {
 			p.off += copy(p.buf[p.off:], msg[:dif])
 			msg = msg[dif:]
 			core(p.buf[:], msgBlock, &(p.h), &(p.r))
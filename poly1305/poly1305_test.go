@@ -50,6 +50,26 @@ func TestWrite(t *testing.T) {
 	}
 }
 
+func TestWriteFullBlock(t *testing.T) {
+	var key [32]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	msg := make([]byte, 2*TagSize)
+
+	// complete a partial block with the second write
+	h := New(&key)
+	h.Write(msg[:TagSize/2])
+	h.Write(msg[TagSize/2 : TagSize])
+
+	var tag, sum [TagSize]byte
+	h.Sum(&sum)
+	Sum(&tag, msg[:TagSize], &key)
+	if tag != sum {
+		t.Fatalf("Sum differ from poly1305.Sum\n Sum: %s \n poly1305.Sum %s", hex.EncodeToString(sum[:]), hex.EncodeToString(tag[:]))
+	}
+}
+
 func TestSum(t *testing.T) {
 	var key [32]byte
 	for i := range key {
@@ -193,3 +193,81 @@ func TestVectors(t *testing.T) {
 		}
 	}
 }
+
+// Skein-1024-KDF test vectors. Regenerate them from the skein
+// directory with "python3 testdata/skein.py kdf 1024".
+var kdfVectors = []struct {
+	key, keyID string
+	length     int
+	out        string
+}{
+	{
+		key: "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F" +
+			"202122232425262728292A2B2C2D2E2F303132333435363738393A3B3C3D3E3F" +
+			"404142434445464748494A4B4C4D4E4F505152535455565758595A5B5C5D5E5F" +
+			"606162636465666768696A6B6C6D6E6F707172737475767778797A7B7C7D7E7F",
+		keyID:  "",
+		length: 128,
+		out: "0B7B48E22EA380F0D375193BF67FAE342AAD8566CFC34BF6BAC40C499E982EFF" +
+			"D90A238801A59FD9AFC9B1B9C81D0C296671CD1458962C03321494D6A79AE5B9" +
+			"6B66AC3706A5562A33B89DDB17B7ECF0BB165E5242F173ECC7B15A8D98ABD701" +
+			"B5F71E5AB1DE18C51C23533FD2E7EB54802095829DD59180F4C84D72599F7858",
+	},
+	{
+		key: "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F" +
+			"202122232425262728292A2B2C2D2E2F303132333435363738393A3B3C3D3E3F" +
+			"404142434445464748494A4B4C4D4E4F505152535455565758595A5B5C5D5E5F" +
+			"606162636465666768696A6B6C6D6E6F707172737475767778797A7B7C7D7E7F",
+		keyID:  "536B65696E2D31303234206B65792064657269766174696F6E",
+		length: 64,
+		out: "330463482398A782B25E7DCD1E91717109B9E0D066B0ED701A2B0C04794B6980" +
+			"A942814C395AD6C2A8AA360F568BE9351904D36288BB48BA52668177F9ED7014",
+	},
+	{
+		key: "A0A1A2A3A4A5A6A7A8A9AAABACADAEAFB0B1B2B3B4B5B6B7B8B9BABBBCBDBEBF" +
+			"C0C1C2C3C4C5C6C7C8C9CACBCCCDCECFD0D1D2D3D4D5D6D7D8D9DADBDCDDDEDF",
+		keyID:  "536B65696E2D31303234206B65792064657269766174696F6E",
+		length: 129,
+		out: "CB3655CCE666BDBC7147185AEEBB8D2D37FF750EDD3F29C104B345DFD0B0FC8B" +
+			"877C762A4EBF836886B7A896E69F80ED927AAB6D50D7BD68C06AD07F1BCE906F" +
+			"AAB40F86581B12D7DE1FE35688C78F0A46CB965433B26C064C3990FF873B0DA0" +
+			"4CA09C6A76FA267412EC472E20F04D63BC5E862FAA4A69BA9E1A377CC4A60CAE" +
+			"61",
+	},
+	{
+		key: "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F" +
+			"202122232425262728292A2B2C2D2E2F303132333435363738393A3B3C3D3E3F" +
+			"404142434445464748494A4B4C4D4E4F505152535455565758595A5B5C5D5E5F" +
+			"606162636465666768696A6B6C6D6E6F707172737475767778797A7B7C7D7E7F",
+		keyID: "00070E151C232A31383F464D545B626970777E858C939AA1A8AFB6BDC4CBD2D9" +
+			"E0E7EEF5FC030A11181F262D343B424950575E656C737A81888F969DA4ABB2B9" +
+			"C0C7CED5DCE3EAF1F8FF060D141B222930373E454C535A61686F767D848B9299" +
+			"A0A7AEB5BCC3CAD1D8DFE6EDF4FB020910171E252C333A41484F565D646B7279" +
+			"80",
+		length: 389,
+		out: "C8FA74853FC4F6806716044E8A4FF13CEAFEA011B24C730FFF04943FB4164FC5" +
+			"B648555310F93720CF20D4DBEE035F454931E13AFAE20350E28A5AE01344A414" +
+			"AC3D0D422D2C826B02E5BE0F3E931CD18AE33712B262506D7064B588862853EF" +
+			"E0F80B322B9FF98EFB643579EA7D6ACBB3A694F7B2151C00820D90E8A843B516" +
+			"ACB983E82144701BB317591ECC24075A464F8E88F44DBC41C44673A82388CC36" +
+			"849C9709807E8416DF5807FF23ED17989D05CFA39C7769FF543C35BE19758776" +
+			"B4B93B21C52ED04690DCB7F1D59A6FDC1D756673AE0FA0565C50E7B509CF45B6" +
+			"68D4BCCACDB0453E0415F4CF73CD446A599A74A1F4DD11B44A87E822CF9604C4" +
+			"F5CA5251BE8F7E7C409D326069474C782847023AF53A10C21ADEF77E9339FC79" +
+			"030B648BEF4F6A90209744F44664952422AD1A2B10B2FF887CEC73217A91BD09" +
+			"494E90BF26AF3D2DA26C4BFEAB1522A78A9E40F19ED62E5CD489ACFCAF9B10CE" +
+			"5A5CB85D1DE79EA423ABCD876F0F310D97A96A045A04AECD45932A81335F3F9E" +
+			"ED0A53034D",
+	},
+}
+
+func TestDeriveKey(t *testing.T) {
+	for i, v := range kdfVectors {
+		key, keyID, ref := fromHex(v.key), fromHex(v.keyID), fromHex(v.out)
+
+		out := DeriveKey(key, keyID, v.length)
+		if !bytes.Equal(out, ref) {
+			t.Fatalf("Test vector %d : Derived key does not match:\nFound:      %s\nExpected: %s", i, hex.EncodeToString(out), hex.EncodeToString(ref))
+		}
+	}
+}
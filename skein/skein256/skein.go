@@ -82,6 +82,14 @@ func Sum(msg []byte, hashsize int, conf *skein.Config) []byte {
 	return s.Sum(nil)
 }
 
+// DeriveKey derives a key of the given length from the master key and the
+// key id using Skein256 as key derivation function (Skein-KDF). The key id
+// identifies the derived key (e.g. the context of the key) and is optional.
+// The length must be > 0.
+func DeriveKey(masterKey, keyID []byte, length int) []byte {
+	return Sum(nil, length, &skein.Config{Key: masterKey, KeyID: keyID})
+}
+
 // New512 returns a hash.Hash computing the Skein256 512 bit checksum.
 // The key is optional and turns the hash into a MAC.
 func New512(key []byte) hash.Hash {
@@ -178,3 +178,52 @@ func TestVectors(t *testing.T) {
 		}
 	}
 }
+
+// Skein-256-KDF test vectors. Regenerate them from the skein
+// directory with "python3 testdata/skein.py kdf 256".
+var kdfVectors = []struct {
+	key, keyID string
+	length     int
+	out        string
+}{
+	{
+		key:    "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		keyID:  "",
+		length: 32,
+		out:    "9CC5AC7188672877C7226D08CDEF7C2D20DDA430A175C3C5AE9483991F25549C",
+	},
+	{
+		key:    "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		keyID:  "536B65696E2D323536206B65792064657269766174696F6E",
+		length: 16,
+		out:    "FA04D1D9C186E4F695C95C1E2293AAD5",
+	},
+	{
+		key:    "A0A1A2A3A4A5A6A7A8A9AAABACADAEAF",
+		keyID:  "536B65696E2D323536206B65792064657269766174696F6E",
+		length: 33,
+		out: "777C1794E89893581BCD3C67A18B93298BA6113D4CBCB9792BF8C5B1BE890FC3" +
+			"D5",
+	},
+	{
+		key: "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		keyID: "00070E151C232A31383F464D545B626970777E858C939AA1A8AFB6BDC4CBD2D9" +
+			"E0",
+		length: 101,
+		out: "855E3AF49F3553BCB609AA1869256253FC0A35DAD19372AE4082D782D9AAE400" +
+			"DCB292E7B1967AFFE073A44707B4470F7E9FC45AE1D9BF174CDB52AF4A4B431C" +
+			"A5E6056870EE62879E4B116766F957079176221D65C8583B0B70B49CCDDD412E" +
+			"45EDA36731",
+	},
+}
+
+func TestDeriveKey(t *testing.T) {
+	for i, v := range kdfVectors {
+		key, keyID, ref := fromHex(v.key), fromHex(v.keyID), fromHex(v.out)
+
+		out := DeriveKey(key, keyID, v.length)
+		if !bytes.Equal(out, ref) {
+			t.Fatalf("Test vector %d : Derived key does not match:\nFound:      %s\nExpected: %s", i, hex.EncodeToString(out), hex.EncodeToString(ref))
+		}
+	}
+}
@@ -0,0 +1,165 @@
+#!/usr/bin/env python3
+# Use of this source code is governed by a license
+# that can be found in the LICENSE file
+
+# A plain implementation of Threefish and Skein following the Skein 1.3
+# paper. It is used to generate the test vectors for which no official KATs
+# exist (Skein-KDF, ΘCB3-Threefish, Threefish-Skein). Before printing
+# anything it checks itself against vectors of the reference code.
+#
+# Usage: python3 skein.py kdf <256|512|1024>
+
+import sys
+
+M64 = (1 << 64) - 1
+C240 = 0x1BD11BDAA9FC1A22
+
+ROTATIONS = {
+    4: [[14, 16], [52, 57], [23, 40], [5, 37], [25, 33], [46, 12], [58, 22], [32, 32]],
+    8: [[46, 36, 19, 37], [33, 27, 14, 42], [17, 49, 36, 39], [44, 9, 54, 56],
+        [39, 30, 34, 24], [13, 50, 10, 17], [25, 29, 39, 43], [8, 35, 56, 22]],
+    16: [[24, 13, 8, 47, 8, 17, 22, 37], [38, 19, 10, 55, 49, 18, 23, 52],
+         [33, 4, 51, 13, 34, 41, 59, 17], [5, 20, 48, 41, 47, 28, 16, 25],
+         [41, 9, 37, 31, 12, 47, 44, 30], [16, 34, 56, 51, 4, 53, 42, 41],
+         [31, 44, 47, 46, 19, 42, 44, 25], [9, 48, 35, 52, 23, 31, 37, 20]],
+}
+PERMUTATION = {
+    4: [0, 3, 2, 1],
+    8: [2, 1, 4, 7, 6, 5, 0, 3],
+    16: [0, 9, 2, 13, 6, 11, 4, 15, 10, 7, 12, 3, 14, 5, 8, 1],
+}
+ROUNDS = {4: 72, 8: 72, 16: 80}
+
+# Skein 1.3 - Table 6
+T_KEY, T_CFG, T_PRS, T_PK, T_KDF, T_NON, T_MSG, T_OUT = 0, 4, 8, 12, 16, 20, 48, 63
+
+
+def words(b):
+    return [int.from_bytes(b[i:i + 8], 'little') for i in range(0, len(b), 8)]
+
+
+def wbytes(w):
+    return b''.join(x.to_bytes(8, 'little') for x in w)
+
+
+def threefish(key, tweak, block):
+    """Encrypts block with Threefish - all arguments are byte strings."""
+    k, t, v = words(key), words(tweak), words(block)
+    nw = len(k)
+    k.append(C240)
+    for x in k[:nw]:
+        k[nw] ^= x
+    t.append(t[0] ^ t[1])
+
+    def subkey(s):
+        sk = [k[(s + i) % (nw + 1)] for i in range(nw)]
+        sk[nw - 3] += t[s % 3]
+        sk[nw - 2] += t[(s + 1) % 3]
+        sk[nw - 1] += s
+        return [x & M64 for x in sk]
+
+    for d in range(ROUNDS[nw]):
+        if d % 4 == 0:
+            v = [(a + b) & M64 for a, b in zip(v, subkey(d // 4))]
+        f = []
+        for j in range(nw // 2):
+            r = ROTATIONS[nw][d % 8][j]
+            y0 = (v[2 * j] + v[2 * j + 1]) & M64
+            y1 = ((v[2 * j + 1] << r | v[2 * j + 1] >> (64 - r)) & M64) ^ y0
+            f += [y0, y1]
+        v = [f[p] for p in PERMUTATION[nw]]
+    return wbytes([(a + b) & M64 for a, b in zip(v, subkey(ROUNDS[nw] // 4))])
+
+
+def ubi(g, msg, typ):
+    bs = len(g)
+    blocks = [msg[i:i + bs] for i in range(0, len(msg), bs)] or [b'']
+    pos = 0
+    for i, m in enumerate(blocks):
+        pos += len(m)
+        m += bytes(bs - len(m))
+        tweak = pos | typ << 120
+        if i == 0:
+            tweak |= 1 << 126
+        if i == len(blocks) - 1:
+            tweak |= 1 << 127
+        c = threefish(g, tweak.to_bytes(16, 'little'), m)
+        g = bytes(a ^ b for a, b in zip(c, m))
+    return g
+
+
+def skein(blocksize, outlen, msg, key=b'', personal=b'', publickey=b'', keyid=b'', nonce=b''):
+    """Computes Skein-<8*blocksize>-<8*outlen> of msg."""
+    g = bytes(blocksize)
+    if key:
+        g = ubi(g, key, T_KEY)
+    cfg = b'SHA3' + (1).to_bytes(2, 'little') + bytes(2) + (8 * outlen).to_bytes(8, 'little')
+    g = ubi(g, cfg + bytes(16), T_CFG)
+    for typ, v in ((T_PRS, personal), (T_PK, publickey), (T_KDF, keyid), (T_NON, nonce)):
+        if v:
+            g = ubi(g, v, typ)
+    g = ubi(g, msg, T_MSG)
+    out = b''
+    for i in range((outlen + blocksize - 1) // blocksize):
+        out += ubi(g, i.to_bytes(8, 'little'), T_OUT)
+    return out[:outlen]
+
+
+def selftest():
+    h = bytes.fromhex
+    # skein_golden_kat.txt
+    assert skein(32, 32, b'') == h(
+        'C8877087DA56E072870DAA843F176E9453115929094C3A40C463A196C29BF7BA')
+    assert skein(64, 64, b'') == h(
+        'BC5B4C50925519C290CC634277AE3D6257212395CBA733BBAD37A4AF0FA06AF4'
+        '1FCA7903D06564FEA7A2D3730DBDB80C1F85562DFCC070334EA4D1D9E72CBA7A')
+    assert skein(128, 128, b'') == h(
+        '0FFF9563BB3279289227AC77D319B6FFF8D7E9F09DA1247B72A0A265CD6D2A62'
+        '645AD547ED8193DB48CFF847C06494A03F55666D3B47EB4C20456C9373C86297'
+        'D630D5578EBD34CB40991578F9F52B18003EFA35D3DA6553FF35DB91B81AB890'
+        'BEC1B189B7F52CB2A783EBB7D823D725B0B4A71F6824E88F68F982EEFC6D19C6')
+    assert skein(64, 64, h('D3090C72167517F7C7AD82A70C2FD3F6'), key=h(
+        'CB41F1706CDE09651203C2D0EFBADDF847A0D315CB2E53FF8BAC41DA0002672E'
+        '920244C66E02D5F0DAD3E94C42BB65F0D14157DECF4105EF5609D5B0984457C1')) == h(
+        '478D7B6C0CC6E35D9EBBDEDF39128E5A36585DB6222891692D1747D401DE34CE'
+        '3DB6FCBAB6C968B7F2620F4A844A2903B547775579993736D2493A75FF6752A1')
+    # Threefish-256 and Threefish-512 (see ../threefish/vectors_test.go)
+    assert threefish(bytes(32), bytes(16), bytes(32)) == h(
+        '84da2a1f8beaee947066ae3e3103f1ad536db1f4a1192495116b9f3ce6133fd8')
+    assert threefish(bytes(64), bytes(16), bytes(64)) == h(
+        'b1a2bbc6ef6025bc40eb3822161f36e375d1bb0aee3186fbd19e47c5d479947b'
+        '7bc2f8586e35f0cff7e7f03084b0b7b1f1ab3961a580a3e97eb41ea14a6d7bbe')
+
+
+def gohex(b, indent=3):
+    s = b.hex().upper()
+    if len(s) <= 64:
+        return '"%s"' % s
+    lines = ['"%s"' % s[i:i + 64] for i in range(0, len(s), 64)]
+    return (' +\n' + '\t' * indent).join(lines)
+
+
+def kdf(bits):
+    bs = bits // 8
+    label = b'Skein-%d key derivation' % bits
+    cases = (
+        (bytes(range(bs)), b'', bs),
+        (bytes(range(bs)), label, bs // 2),
+        (bytes(range(0xA0, 0xA0 + bs // 2)), label, bs + 1),
+        (bytes(range(bs)), bytes(7 * i & 0xFF for i in range(bs + 1)), 3 * bs + 5),
+    )
+    for key, keyid, n in cases:
+        print('\t{')
+        print('\t\tkey:    %s,' % gohex(key))
+        print('\t\tkeyID:  %s,' % gohex(keyid))
+        print('\t\tlength: %d,' % n)
+        print('\t\tout: %s,' % gohex(skein(bs, n, b'', key=key, keyid=keyid)))
+        print('\t},')
+
+
+selftest()
+
+if __name__ == '__main__':
+    if len(sys.argv) != 3 or sys.argv[1] != 'kdf' or sys.argv[2] not in ('256', '512', '1024'):
+        sys.exit('usage: python3 skein.py kdf <256|512|1024>')
+    kdf(int(sys.argv[2]))
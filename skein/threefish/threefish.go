@@ -36,6 +36,7 @@ const (
 //		- Threefish-256  - if len(key) = 32
 //		- Threefish-512  - if len(key) = 64
 // 		- Threefish-1024 - if len(key) = 128
+// The returned cipher.Block also implements TweakableBlock.
 func NewCipher(tweak *[TweakSize]byte, key []byte) (cipher.Block, error) {
 	switch k := len(key); k {
 	default:
@@ -49,6 +50,46 @@ func NewCipher(tweak *[TweakSize]byte, key []byte) (cipher.Block, error) {
 	}
 }
 
+// TweakableBlock is a cipher.Block which accepts a tweak for every
+// encryption and decryption. The key schedule is computed only once,
+// so changing the tweak is cheap. The Encrypt and Decrypt methods use
+// the tweak fixed at construction.
+type TweakableBlock interface {
+	cipher.Block
+
+	// EncryptTweak encrypts the first block in src into dst
+	// using the given tweak. Dst and src may point at the same memory.
+	EncryptTweak(dst, src []byte, tweak *[TweakSize]byte)
+
+	// DecryptTweak decrypts the first block in src into dst
+	// using the given tweak. Dst and src may point at the same memory.
+	DecryptTweak(dst, src []byte, tweak *[TweakSize]byte)
+}
+
+// NewTweakableCipher returns a TweakableBlock implementing the Threefish cipher.
+// The length of the key must be 32, 64 or 128 byte.
+// The tweak is used by the Encrypt and Decrypt methods and may be nil,
+// in which case the all-zero tweak is used.
+// The returned cipher implements:
+//		- Threefish-256  - if len(key) = 32
+//		- Threefish-512  - if len(key) = 64
+// 		- Threefish-1024 - if len(key) = 128
+func NewTweakableCipher(tweak *[TweakSize]byte, key []byte) (TweakableBlock, error) {
+	if tweak == nil {
+		tweak = new([TweakSize]byte)
+	}
+	switch k := len(key); k {
+	default:
+		return nil, crypto.KeySizeError(k)
+	case BlockSize256:
+		return newCipher256(tweak, key), nil
+	case BlockSize512:
+		return newCipher512(tweak, key), nil
+	case BlockSize1024:
+		return newCipher1024(tweak, key), nil
+	}
+}
+
 // Increment the tweak by the ctr argument.
 // Skein can consume messages up to 2^96 -1 bytes.
 func IncrementTweak(tweak *[3]uint64, ctr uint64) {
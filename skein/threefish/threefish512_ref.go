@@ -23,16 +23,34 @@ func (t *threefish512) Decrypt(dst, src []byte) {
 	block512ToBytes(dst, &block)
 }
 
-func newCipher512(tweak *[TweakSize]byte, key []byte) *threefish512 {
-	c := new(threefish512)
+func (t *threefish512) EncryptTweak(dst, src []byte, tweak *[TweakSize]byte) {
+	var block [8]uint64
+	var tw [3]uint64
+
+	bytesToBlock512(&block, src)
+	bytesToTweak(&tw, tweak)
+
+	Encrypt512(&block, &(t.keys), &tw)
+
+	block512ToBytes(dst, &block)
+}
 
-	c.tweak[0] = uint64(tweak[0]) | uint64(tweak[1])<<8 | uint64(tweak[2])<<16 | uint64(tweak[3])<<24 |
-		uint64(tweak[4])<<32 | uint64(tweak[5])<<40 | uint64(tweak[6])<<48 | uint64(tweak[7])<<56
+func (t *threefish512) DecryptTweak(dst, src []byte, tweak *[TweakSize]byte) {
+	var block [8]uint64
+	var tw [3]uint64
+
+	bytesToBlock512(&block, src)
+	bytesToTweak(&tw, tweak)
 
-	c.tweak[1] = uint64(tweak[8]) | uint64(tweak[9])<<8 | uint64(tweak[10])<<16 | uint64(tweak[11])<<24 |
-		uint64(tweak[12])<<32 | uint64(tweak[13])<<40 | uint64(tweak[14])<<48 | uint64(tweak[15])<<56
+	Decrypt512(&block, &(t.keys), &tw)
+
+	block512ToBytes(dst, &block)
+}
+
+func newCipher512(tweak *[TweakSize]byte, key []byte) *threefish512 {
+	c := new(threefish512)
 
-	c.tweak[2] = c.tweak[0] ^ c.tweak[1]
+	bytesToTweak(&(c.tweak), tweak)
 
 	for i := range c.keys[:8] {
 		j := i * 8
@@ -92,3 +92,11 @@ func block1024ToBytes(dst []byte, block *[16]uint64) {
 	dstPtr[14] = block[14]
 	dstPtr[15] = block[15]
 }
+
+func bytesToTweak(tweak *[3]uint64, src *[TweakSize]byte) {
+	srcPtr := (*[2]uint64)(unsafe.Pointer(&src[0]))
+
+	tweak[0] = srcPtr[0]
+	tweak[1] = srcPtr[1]
+	tweak[2] = tweak[0] ^ tweak[1]
+}
@@ -70,3 +70,12 @@ func block1024ToBytes(dst []byte, block *[16]uint64) {
 		dst[j+7] = byte(v >> 56)
 	}
 }
+
+func bytesToTweak(tweak *[3]uint64, src *[TweakSize]byte) {
+	for i := range tweak[:2] {
+		j := i * 8
+		tweak[i] = uint64(src[j]) | uint64(src[j+1])<<8 | uint64(src[j+2])<<16 | uint64(src[j+3])<<24 |
+			uint64(src[j+4])<<32 | uint64(src[j+5])<<40 | uint64(src[j+6])<<48 | uint64(src[j+7])<<56
+	}
+	tweak[2] = tweak[0] ^ tweak[1]
+}
@@ -3,7 +3,10 @@
 
 package threefish
 
-import "testing"
+import (
+	"bytes"
+	"testing"
+)
 
 // The UBI256, UBI512 and UBI1024 functions are tested within
 // the skein packages (skein, skein256 and skein1024)
@@ -39,6 +42,54 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func testTweakableBlock(t *testing.T, blocksize int) {
+	var tweak, zero [TweakSize]byte
+	for i := range tweak {
+		tweak[i] = byte(i)
+	}
+	key := make([]byte, blocksize)
+	for i := range key {
+		key[i] = byte(255 - i)
+	}
+
+	c0, err := NewCipher(&tweak, key)
+	if err != nil {
+		t.Fatalf("Failed to create Threefish-%d instance: %s", blocksize*8, err)
+	}
+	c1, err := NewTweakableCipher(nil, key)
+	if err != nil {
+		t.Fatalf("Failed to create Threefish-%d instance: %s", blocksize*8, err)
+	}
+	if _, ok := c0.(TweakableBlock); !ok {
+		t.Fatalf("Threefish-%d does not implement TweakableBlock", blocksize*8)
+	}
+
+	buf0, buf1 := make([]byte, blocksize), make([]byte, blocksize)
+	c0.Encrypt(buf0, buf0)
+	c1.EncryptTweak(buf1, buf1, &tweak)
+	if !bytes.Equal(buf0, buf1) {
+		t.Fatalf("Threefish-%d: EncryptTweak differs from Encrypt", blocksize*8)
+	}
+
+	c1.Encrypt(buf0, buf1)
+	c1.EncryptTweak(buf1, buf1, &zero)
+	if !bytes.Equal(buf0, buf1) {
+		t.Fatalf("Threefish-%d: Encrypt does not use the zero tweak", blocksize*8)
+	}
+
+	c1.DecryptTweak(buf1, buf1, &zero)
+	c1.DecryptTweak(buf1, buf1, &tweak)
+	if !bytes.Equal(buf1, make([]byte, blocksize)) {
+		t.Fatalf("Threefish-%d: DecryptTweak is not the inverse of EncryptTweak", blocksize*8)
+	}
+}
+
+func TestTweakableBlock(t *testing.T) {
+	testTweakableBlock(t, BlockSize256)
+	testTweakableBlock(t, BlockSize512)
+	testTweakableBlock(t, BlockSize1024)
+}
+
 func TestIncrementTweak(t *testing.T) {
 	var tweak [3]uint64
 
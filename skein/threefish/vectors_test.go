@@ -182,3 +182,33 @@ func TestVectros1024(t *testing.T) {
 		}
 	}
 }
+
+func TestTweakableVectors(t *testing.T) {
+	vectors := append(append(testVectors256, testVectors512...), testVectors1024...)
+	for i, v := range vectors {
+		key := fromHex(v.key)
+		tweak := fromHex(v.tweak)
+		plaintext := fromHex(v.plaintext)
+		ciphertext := fromHex(v.ciphertext)
+
+		var Tweak [TweakSize]byte
+		copy(Tweak[:], tweak)
+
+		c, err := NewTweakableCipher(nil, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dst := make([]byte, len(plaintext))
+
+		c.EncryptTweak(dst, plaintext, &Tweak)
+		if !bytes.Equal(ciphertext, dst) {
+			t.Fatalf("Test vector %d : Encryption failed\nFound:      %s \nExpected: %s", i, hex.EncodeToString(dst), hex.EncodeToString(ciphertext))
+		}
+
+		c.DecryptTweak(dst, dst, &Tweak)
+		if !bytes.Equal(plaintext, dst) {
+			t.Fatalf("Test vector %d : Decryption failed\nFound:     %s \nExpected: %s", i, hex.EncodeToString(dst), hex.EncodeToString(plaintext))
+		}
+	}
+}
@@ -163,3 +163,62 @@ func TestVectors(t *testing.T) {
 		}
 	}
 }
+
+// Skein-512-KDF test vectors (a key, a KeyID and an empty message - see
+// Skein 1.3, Section 4.11). Generated by "python3 testdata/skein.py kdf 512",
+// which verifies itself against the reference code KATs first.
+var kdfVectors = []struct {
+	key, keyID string
+	length     int
+	out        string
+}{
+	{
+		key: "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F" +
+			"202122232425262728292A2B2C2D2E2F303132333435363738393A3B3C3D3E3F",
+		keyID:  "",
+		length: 64,
+		out: "54F7518545762B1003D548DFDBC81725894B30DA23BD8C3DC045641EF87361A6" +
+			"585B7EC242A339167F846445F2B682E4BE7D216FC12D24735C535C703E45FBAF",
+	},
+	{
+		key: "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F" +
+			"202122232425262728292A2B2C2D2E2F303132333435363738393A3B3C3D3E3F",
+		keyID:  "536B65696E2D353132206B65792064657269766174696F6E",
+		length: 32,
+		out:    "6B1527BF53AB693BED788C563956BD45EC3247337AF1627A7603DB306784FBE3",
+	},
+	{
+		key:    "A0A1A2A3A4A5A6A7A8A9AAABACADAEAFB0B1B2B3B4B5B6B7B8B9BABBBCBDBEBF",
+		keyID:  "536B65696E2D353132206B65792064657269766174696F6E",
+		length: 65,
+		out: "EB96EB10E2D45314D1566FE639752EA0104587CF6695A308C33A588121CD8A63" +
+			"62FB62B337D748CF5C1A3664D049913E01FFEE0D9F599AFF3C14A0ED79617103" +
+			"69",
+	},
+	{
+		key: "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F" +
+			"202122232425262728292A2B2C2D2E2F303132333435363738393A3B3C3D3E3F",
+		keyID: "00070E151C232A31383F464D545B626970777E858C939AA1A8AFB6BDC4CBD2D9" +
+			"E0E7EEF5FC030A11181F262D343B424950575E656C737A81888F969DA4ABB2B9" +
+			"C0",
+		length: 197,
+		out: "9AA563921F33F7714AE1A0A67A752582124A0FF4A3AB094F0646B8AA4AF08219" +
+			"985F1AD4C5A0E886BFCB6FAB0CC3548259840EC9742FF10BA50EA4A11F3E77C3" +
+			"1E292863E39619E6486AF2EF6A9B5C137BB166F0B46B8CC04F916B764CC60BFD" +
+			"25DACB1E7F461EB7328DEF613EAADEFA93389B585C81BAC2D473E172EC1AAACC" +
+			"B86FFDD22AE7A0C1313401A10AC585E4E5F5FE374BA3CEFF195779FA4A07B634" +
+			"070F2834BF2693E134DCB7C6730FF27A4769493EFF51BAF7AB084DC52F2D2E29" +
+			"873B2EABEE",
+	},
+}
+
+func TestDeriveKey(t *testing.T) {
+	for i, v := range kdfVectors {
+		key, keyID, ref := fromHex(v.key), fromHex(v.keyID), fromHex(v.out)
+
+		out := DeriveKey(key, keyID, v.length)
+		if !bytes.Equal(out, ref) {
+			t.Fatalf("Test vector %d : Derived key does not match:\nFound:      %s\nExpected: %s", i, hex.EncodeToString(out), hex.EncodeToString(ref))
+		}
+	}
+}